@@ -75,7 +75,7 @@ func runEnqueueExample(cmd *cobra.Command, args []string) error {
 
 	// Initialize job repository and service
 	jobRepo := jobctrl.NewPostgresJobRepository(db)
-	jobService := jobctrl.NewJobService(publisher, jobRepo, logger, nil)
+	jobService := jobctrl.NewJobService(publisher, jobRepo, logger, nil, nil)
 
 	// Create test payload
 	payload := jobctrl.TestPayload{
@@ -19,6 +19,9 @@ import (
 	"github.com/weaviate/weaviate-go-client/v4/weaviate"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
 	"github.com/weaviate/weaviate/entities/models"
+	"golang.org/x/sync/errgroup"
+
+	"raggo/src/core/queryexpansion"
 )
 
 // evaluateCmd represents the evaluate command
@@ -44,6 +47,8 @@ func init() {
 	evaluateCmd.Flags().BoolP("contextual", "c", false, "Add contextual information before storing in database")
 	evaluateCmd.Flags().StringP("model", "m", "llama3.2:3b", "LLM model to use for generating context")
 	evaluateCmd.Flags().BoolP("bm25", "b", false, "Use BM25 scoring in addition to vector search")
+	evaluateCmd.Flags().StringP("expansion", "x", queryexpansion.ModeNone, "Query expansion mode: "+strings.Join(queryexpansion.Modes, ", "))
+	evaluateCmd.Flags().String("expansion-model", "", "LLM model writing query expansions (default: the --model)")
 }
 
 func Evaluate(cmd *cobra.Command, args []string) {
@@ -56,6 +61,15 @@ func Evaluate(cmd *cobra.Command, args []string) {
 	useContextual, _ := cmd.Flags().GetBool("contextual")
 	model, _ := cmd.Flags().GetString("model")
 	useBM25, _ := cmd.Flags().GetBool("bm25")
+	expansion, _ := cmd.Flags().GetString("expansion")
+	expansionModel, _ := cmd.Flags().GetString("expansion-model")
+	if expansionModel == "" {
+		expansionModel = model
+	}
+	if !queryexpansion.Valid(expansion) {
+		fmt.Printf("Unknown query expansion mode %q, use one of %s\n", expansion, strings.Join(queryexpansion.Modes, ", "))
+		return
+	}
 
 	fmt.Printf("Starting evaluation with:\n")
 	fmt.Printf("- Input file: %s\n", inputPath)
@@ -64,6 +78,7 @@ func Evaluate(cmd *cobra.Command, args []string) {
 	fmt.Printf("- Using contextual information: %v\n", useContextual)
 	fmt.Printf("- LLM model: %s\n", model)
 	fmt.Printf("- Using BM25 scoring: %v\n", useBM25)
+	fmt.Printf("- Query expansion: %s (model: %s)\n", expansion, expansionModel)
 
 	// Generate names with timestamp
 	timestamp := time.Now().Unix()
@@ -273,6 +288,8 @@ func Evaluate(cmd *cobra.Command, args []string) {
 	var totalSemanticCount float64
 	var totalElasticCount float64
 	var totalResult int
+	var totalRetrievalTime time.Duration
+	generator := evaluateGenerator{model: expansionModel}
 
 	for scanner.Scan() {
 		evalBar.Add(1)
@@ -284,7 +301,9 @@ func Evaluate(cmd *cobra.Command, args []string) {
 		}
 
 		// Call RetrievalFunction with query
-		retrievedChunks, semanticCount, elasticCount, err := RetrievalFunction(ctx, client, evalRaw.Query, k, className, useBM25, indexName)
+		start := time.Now()
+		retrievedChunks, semanticCount, elasticCount, err := RetrievalFunction(ctx, client, evalRaw.Query, k, className, useBM25, indexName, expansion, generator)
+		totalRetrievalTime += time.Since(start)
 		if err != nil {
 			fmt.Printf("Failed to retrieve chunks for query: %v\n", err)
 			continue
@@ -320,8 +339,9 @@ func Evaluate(cmd *cobra.Command, args []string) {
 
 	if processedEvals > 0 {
 		averageScore := totalScore / float64(processedEvals)
-		fmt.Printf("\nEvaluation Results (k=%d):\n", k)
+		fmt.Printf("\nEvaluation Results (k=%d, expansion=%s):\n", k, expansion)
 		fmt.Printf("Total evaluations: %d\n", processedEvals)
+		fmt.Printf("Average retrieval time: %s\n", totalRetrievalTime/time.Duration(processedEvals))
 		fmt.Printf("Average score: %.2f\n", averageScore)
 		fmt.Printf("Pass@%d: %.2f%%\n", k, averageScore*100)
 		if useBM25 {
@@ -722,6 +742,36 @@ func searchWeaviate(ctx context.Context, client *weaviate.Client, query string,
 	return chunks, nil
 }
 
+// searchWeaviateQueries searches the variants of a query in parallel and fuses their rankings with reciprocal rank fusion
+func searchWeaviateQueries(ctx context.Context, client *weaviate.Client, queries []string, k int, className string) ([]ChunkScore, error) {
+	if len(queries) == 1 {
+		return searchWeaviate(ctx, client, queries[0], k, className)
+	}
+
+	rankings := make([][]ChunkScore, len(queries))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, query := range queries {
+		g.Go(func() error {
+			ranking, err := searchWeaviate(gctx, client, query, k, className)
+			if err != nil {
+				return err
+			}
+			rankings[i] = ranking
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	fused := queryexpansion.ReciprocalRankFusion(rankings, func(chunk ChunkScore) string { return chunk.ChunkID }, nil)
+	chunks := make([]ChunkScore, 0, len(fused))
+	for _, f := range fused {
+		chunks = append(chunks, f.Item)
+	}
+	return chunks, nil
+}
+
 func mergeResults(weaviateResults, elasticResults []ChunkScore) []ChunkScore {
 	// Create a map to store the highest score for each chunk
 	chunkScores := make(map[string]ChunkScore)
@@ -759,16 +809,20 @@ func mergeResults(weaviateResults, elasticResults []ChunkScore) []ChunkScore {
 	return merged
 }
 
-func RetrievalFunction(ctx context.Context, client *weaviate.Client, query string, k int, className string, useBM25 bool, indexName string) ([]RetrievalChunk, float64, float64, error) {
-	// Get results from Weaviate
-	weaviateResults, err := searchWeaviate(ctx, client, query, k, className)
+func RetrievalFunction(ctx context.Context, client *weaviate.Client, query string, k int, className string, useBM25 bool, indexName string, expansion string, generator queryexpansion.Generator) ([]RetrievalChunk, float64, float64, error) {
+	// Get results from Weaviate for the query as transformed by the expansion mode
+	queries, err := queryexpansion.Expand(ctx, generator, expansion, query)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to expand query: %v", err)
+	}
+	weaviateResults, err := searchWeaviateQueries(ctx, client, queries, k, className)
 	if err != nil {
 		return nil, 0, 0, fmt.Errorf("failed to search Weaviate: %v", err)
 	}
 
 	var finalResults []ChunkScore
 	if useBM25 {
-		// Get results from Elasticsearch
+		// Get results from Elasticsearch, which matches the keywords of the original query
 		elasticResults, err := searchElasticsearch(ctx, query, k, indexName)
 		if err != nil {
 			return nil, 0, 0, fmt.Errorf("failed to search Elasticsearch: %v", err)
@@ -848,6 +902,29 @@ Answer only with the succinct context and nothing else.
 `
 )
 
+// evaluateGenerator writes query expansions with the Ollama client of the evaluation
+type evaluateGenerator struct {
+	model string
+}
+
+func (g evaluateGenerator) Reasoning(ctx context.Context, system string, prompt string) (string, error) {
+	req := api.GenerateRequest{
+		Model:  g.model,
+		System: system,
+		Prompt: prompt,
+	}
+
+	var response strings.Builder
+	err := ollamaClient.Generate(ctx, &req, func(resp api.GenerateResponse) error {
+		response.WriteString(resp.Response)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate: %v", err)
+	}
+	return response.String(), nil
+}
+
 func situateContext(ctx context.Context, doc, chunk string, generator string) string {
 	if generator == "" {
 		generator = "llama3.2:3b"
@@ -24,13 +24,25 @@ import (
 	weaviateClient "github.com/weaviate/weaviate-go-client/v4/weaviate"
 
 	httpHdlr "raggo/handler/http"
+	"raggo/src/core/conversation"
 	"raggo/src/core/knowledgebase"
+	"raggo/src/core/resourcedeletion"
+	"raggo/src/core/translationflow"
+	"raggo/src/core/translationmemory"
+	"raggo/src/core/translationreview"
 	"raggo/src/infrastructure/integrations/ollama"
 	jobctrl "raggo/src/infrastructure/job"
 	"raggo/src/storage/minioctrl"
 	"raggo/src/storage/postgres/chunkctrl"
+	"raggo/src/storage/postgres/conversationctrl"
+	"raggo/src/storage/postgres/glossaryctrl"
 	pgKnowledgeBase "raggo/src/storage/postgres/knowledgebasectrl"
 	"raggo/src/storage/postgres/resourcectrl"
+	"raggo/src/storage/postgres/styleguidectrl"
+	"raggo/src/storage/postgres/translatedchunkctrl"
+	"raggo/src/storage/postgres/translatedresourcectrl"
+	pgTranslationMemory "raggo/src/storage/postgres/translationmemoryctrl"
+	"raggo/src/storage/postgres/translationprofilectrl"
 	"raggo/src/storage/weaviate"
 )
 
@@ -77,7 +89,7 @@ func RunServer(cmd *cobra.Command, args []string) {
 
 	// Initialize job repository and service
 	jobRepo := jobctrl.NewPostgresJobRepository(db)
-	jobService := jobctrl.NewJobService(publisher, jobRepo, logger, nil)
+	jobService := jobctrl.NewJobService(publisher, jobRepo, logger, nil, nil)
 
 	// Initialize services
 	resourceService, err := resourcectrl.NewResourceService(db)
@@ -90,6 +102,16 @@ func RunServer(cmd *cobra.Command, args []string) {
 		log.Fatalf("Failed to create resource service: %v", err)
 	}
 
+	translatedResourceService, err := translatedresourcectrl.NewTranslatedResourceService(db)
+	if err != nil {
+		log.Fatalf("Failed to create translated resource service: %v", err)
+	}
+
+	translatedChunkService, err := translatedchunkctrl.NewTranslatedChunkService(db)
+	if err != nil {
+		log.Fatalf("Failed to create translated chunk service: %v", err)
+	}
+
 	// Initialize MinIO service
 	minioService, err := minioctrl.NewMinioService(
 		viper.GetString("minio.endpoint"),
@@ -115,69 +137,259 @@ func RunServer(cmd *cobra.Command, args []string) {
 	// Setup gin router
 	r := gin.Default()
 
+	// Initialize Ollama client. Generations are streamed for as long as the model writes, so the client has
+	// no total timeout; requests are bounded by their context, e.g. the reasoning timeout of a translation
+	// or the request of the client
+	oc := ollama.NewClient(viper.GetString("ollama.url"), &http.Client{})
+
+	// Initialize Weaviate SDK
+	wc := weaviateClient.New(weaviateClient.Config{
+		Host:   viper.GetString("weaviate.url"),
+		Scheme: "http",
+	})
+	wsdk := weaviate.NewSDK(wc)
+
+	// Initialize knowledge base service
+	knowledgeBaseRepo := pgKnowledgeBase.NewRepository(db)
+	knowledgeBaseService, err := knowledgebase.NewService(
+		knowledgeBaseRepo,
+		wsdk,
+		oc,
+		minioService,
+		resourceService,
+		chunkService,
+		translatedResourceService,
+		translatedChunkService,
+	)
+	if err != nil {
+		log.Fatalf("Failed to create knowledge base service: %v", err)
+	}
+
+	// Initialize resource deletion service
+	resourceDeletionService := resourcedeletion.NewService(
+		minioService,
+		resourceService,
+		chunkService,
+		translatedResourceService,
+		translatedChunkService,
+		knowledgeBaseService,
+	)
+
 	// Initialize conversion handler
 	conversionHandler, err := httpHdlr.NewConversionHandler(
 		minioService,
 		viper.GetString("minio.pdf_bucket"),
 		viper.GetString("minio.chunk_bucket"),
+		viper.GetString("minio.image_bucket"),
+		viper.GetString("minio.document_bucket"),
 		viper.GetString("minio.domain"),
 		viper.GetString("unstructured.url"),
 		resourceService,
 		chunkService,
+		oc,
+		knowledgeBaseService,
+		resourceDeletionService,
 	)
 	if err != nil {
 		log.Fatalf("Failed to initialize conversion handler: %v", err)
 	}
 
 	// Initialize translation handler
-	translationHandler, err := httpHdlr.NewTranslationHandler(jobService)
+	translationProfileService, err := translationprofilectrl.NewTranslationProfileService(db)
+	if err != nil {
+		log.Fatalf("Failed to create translation profile service: %v", err)
+	}
+	translationHandler, err := httpHdlr.NewTranslationHandler(jobService, translationProfileService)
 	if err != nil {
 		log.Fatalf("Failed to create MinIO service: %v", err)
 	}
+	translationProfileHandler, err := httpHdlr.NewTranslationProfileHandler(translationProfileService)
+	if err != nil {
+		log.Fatalf("Failed to initialize translation profile handler: %v", err)
+	}
 
-	// Initialize Ollama client
-	oc := ollama.NewClient(viper.GetString("ollama.url"), &http.Client{
-		Timeout: 30 * time.Second,
-	})
+	// Initialize resource handler
+	resourceHandler, err := httpHdlr.NewResourceHandler(
+		minioService,
+		viper.GetString("minio.domain"),
+		resourceService,
+		chunkService,
+		resourceDeletionService,
+		jobService,
+		translatedResourceService,
+		translatedChunkService,
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize resource handler: %v", err)
+	}
 
-	// Initialize Weaviate SDK
-	wc := weaviateClient.New(weaviateClient.Config{
-		Host:   viper.GetString("weaviate.url"),
-		Scheme: "http",
-	})
-	wsdk := weaviate.NewSDK(wc)
+	// Initialize glossary handler
+	glossaryService, err := glossaryctrl.NewGlossaryService(db)
+	if err != nil {
+		log.Fatalf("Failed to create glossary service: %v", err)
+	}
+	glossaryHandler, err := httpHdlr.NewGlossaryHandler(glossaryService)
+	if err != nil {
+		log.Fatalf("Failed to initialize glossary handler: %v", err)
+	}
 
-	// Initialize knowledge base service and handler
-	knowledgeBaseRepo := pgKnowledgeBase.NewRepository(db)
-	knowledgeBaseService, err := knowledgebase.NewService(
-		knowledgeBaseRepo,
-		wsdk,
+	// Initialize style guide handler
+	styleGuideService, err := styleguidectrl.NewStyleGuideService(db)
+	if err != nil {
+		log.Fatalf("Failed to create style guide service: %v", err)
+	}
+	styleGuideHandler, err := httpHdlr.NewStyleGuideHandler(styleGuideService)
+	if err != nil {
+		log.Fatalf("Failed to initialize style guide handler: %v", err)
+	}
+
+	// Initialize text translation handler, translating with the template overrides of the workers
+	var templateOverrides map[string]string
+	if templateDir := viper.GetString("translation.template_dir"); templateDir != "" {
+		templateOverrides, err = translationflow.LoadTemplateDir(templateDir)
+		if err != nil {
+			log.Fatalf("Failed to load translation templates: %v", err)
+		}
+	}
+	textTranslationHandler, err := httpHdlr.NewTextTranslationHandler(
 		oc,
+		glossaryService,
+		styleGuideService,
+		translationProfileService,
+		templateOverrides,
+		translationflow.NewLimiter(viper.GetInt("ollama.max_concurrency"), viper.GetFloat64("ollama.requests_per_second")),
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize text translation handler: %v", err)
+	}
+
+	// Initialize translation memory handler
+	translationMemory, err := translationmemory.NewService(pgTranslationMemory.NewRepository(db))
+	if err != nil {
+		log.Fatalf("Failed to create translation memory: %v", err)
+	}
+	translationMemoryHandler, err := httpHdlr.NewTranslationMemoryHandler(translationMemory)
+	if err != nil {
+		log.Fatalf("Failed to initialize translation memory handler: %v", err)
+	}
+
+	// Initialize translated chunk handler for quality and human review
+	translatedChunkHandler, err := httpHdlr.NewTranslatedChunkHandler(
 		minioService,
-		resourceService,
 		chunkService,
+		translatedResourceService,
+		translatedChunkService,
+		translationreview.NewService(minioService, chunkService, translatedChunkService, translationMemory),
 	)
 	if err != nil {
-		log.Fatalf("Failed to create knowledge base service: %v", err)
+		log.Fatalf("Failed to initialize translated chunk handler: %v", err)
+	}
+
+	// Initialize job handler
+	jobHandler, err := httpHdlr.NewJobHandler(jobService)
+	if err != nil {
+		log.Fatalf("Failed to initialize job handler: %v", err)
 	}
-	knowledgeBaseHandler, err := httpHdlr.NewKnowledgeBaseHandler(knowledgeBaseService)
+
+	// Initialize knowledge base handler
+	knowledgeBaseHandler, err := httpHdlr.NewKnowledgeBaseHandler(
+		knowledgeBaseService,
+		knowledgebase.QueryOptions{
+			TranslationModel: viper.GetString("knowledge_base.query_translation_model"),
+			Expansion:        viper.GetString("knowledge_base.query_expansion"),
+			ExpansionModel:   viper.GetString("knowledge_base.query_expansion_model"),
+			Context:          viper.GetString("knowledge_base.context_expansion"),
+			Neighbors:        viper.GetInt("knowledge_base.context_neighbors"),
+		},
+		viper.GetString("knowledge_base.answer_model"),
+		viper.GetInt("knowledge_base.answer_token_budget"),
+	)
 	if err != nil {
 		log.Fatalf("Failed to initialize knowledge base handler: %v", err)
 	}
 
+	// Initialize conversation handler for multi-turn questions over knowledge bases
+	conversationService, err := conversationctrl.NewConversationService(db)
+	if err != nil {
+		log.Fatalf("Failed to create conversation service: %v", err)
+	}
+	conversations, err := conversation.NewService(knowledgeBaseService, conversationService, oc)
+	if err != nil {
+		log.Fatalf("Failed to create conversations: %v", err)
+	}
+	conversationHandler, err := httpHdlr.NewConversationHandler(
+		conversations,
+		conversationService,
+		knowledgeBaseHandler,
+		viper.GetString("knowledge_base.query_rewrite_model"),
+		viper.GetInt("knowledge_base.conversation_history_turns"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize conversation handler: %v", err)
+	}
+
 	// Register routes
 	r.GET("/pdfs", pdfHandler.List)
 	r.POST("/pdfs", pdfHandler.Upload)
+	r.PUT("/resources/:id", pdfHandler.UploadVersion)
+	r.GET("/resources/:id/versions", pdfHandler.ListVersions)
+	r.DELETE("/resources/:id", resourceHandler.Delete)
+	r.GET("/resources/:id/text", resourceHandler.GetText)
+	r.GET("/resources/:id/chunks", resourceHandler.ListChunks)
+	r.GET("/resources/:id/translations", resourceHandler.ListTranslations)
+	r.GET("/translations/:id/download", resourceHandler.DownloadTranslation)
+	r.GET("/translations/:id/chunks", translatedChunkHandler.ListChunks)
+	r.GET("/translations/:id/chunks/:chunkId", translatedChunkHandler.GetChunk)
+	r.PUT("/translations/:id/chunks/:chunkId", translatedChunkHandler.Revise)
+	r.POST("/translations/:id/chunks/:chunkId/approve", translatedChunkHandler.Approve)
+	r.POST("/translations/:id/chunks/:chunkId/reject", translatedChunkHandler.Reject)
+	r.PUT("/translations/:id/chunks/:chunkId/flag", translatedChunkHandler.Flag)
+	r.POST("/translations/:id/regenerate", translatedChunkHandler.Regenerate)
 	r.POST("/conversion", conversionHandler.Convert)
 	r.POST("/translation", translationHandler.Translate)
+	r.GET("/jobs/:jobId", jobHandler.GetJob)
+	r.POST("/jobs/:jobId/retry-failed", jobHandler.RetryFailedChunks)
 
 	// Knowledge base routes
 	r.GET("/api/v1/knowledge-bases", knowledgeBaseHandler.ListKnowledgeBases)
 	r.GET("/api/v1/knowledge-bases/:id/resources", knowledgeBaseHandler.ListKnowledgeBaseResources)
+	r.POST("/api/v1/knowledge-bases/query", knowledgeBaseHandler.QueryKnowledgeBases)
 	r.POST("/api/v1/knowledge-bases/:id/query", knowledgeBaseHandler.QueryKnowledgeBase)
+	r.POST("/api/v1/knowledge-bases/:id/answer", knowledgeBaseHandler.AnswerKnowledgeBase)
 	r.POST("/api/v1/knowledge-bases/:id/resources", knowledgeBaseHandler.AddResourceToKnowledgeBase)
 	r.POST("/api/v1/knowledge-bases/:id/reset-weaviate", knowledgeBaseHandler.ResetWeaviateContent)
 
+	// Conversation routes
+	r.POST("/api/v1/knowledge-bases/:id/conversations", conversationHandler.Create)
+	r.GET("/api/v1/knowledge-bases/:id/conversations", conversationHandler.List)
+	r.GET("/api/v1/conversations/:id", conversationHandler.Get)
+	r.DELETE("/api/v1/conversations/:id", conversationHandler.Delete)
+	r.POST("/api/v1/conversations/:id/messages", conversationHandler.Ask)
+
+	// Ad-hoc translation streamed as server-sent events
+	r.POST("/api/v1/translate", textTranslationHandler.Translate)
+
+	// Glossary routes
+	r.GET("/api/v1/glossaries", glossaryHandler.ListGlossaries)
+	r.POST("/api/v1/glossaries", glossaryHandler.CreateGlossary)
+	r.GET("/api/v1/glossaries/:id", glossaryHandler.GetGlossary)
+	r.DELETE("/api/v1/glossaries/:id", glossaryHandler.DeleteGlossary)
+	r.POST("/api/v1/glossaries/:id/terms", glossaryHandler.AddTerm)
+	r.DELETE("/api/v1/glossaries/:id/terms/:termId", glossaryHandler.DeleteTerm)
+
+	// Style guide routes
+	r.GET("/api/v1/style-guides", styleGuideHandler.ListStyleGuides)
+	r.PUT("/api/v1/style-guides", styleGuideHandler.SaveStyleGuide)
+	r.DELETE("/api/v1/style-guides/:id", styleGuideHandler.DeleteStyleGuide)
+
+	// Translation profile routes
+	r.GET("/api/v1/translation-profiles", translationProfileHandler.ListProfiles)
+	r.PUT("/api/v1/translation-profiles", translationProfileHandler.SaveProfile)
+	r.DELETE("/api/v1/translation-profiles/:id", translationProfileHandler.DeleteProfile)
+
+	// Translation memory routes
+	r.GET("/api/v1/translation-memory/export", translationMemoryHandler.Export)
+
 	// Create HTTP server
 	srv := &http.Server{
 		Addr:    ":" + viper.GetString("server.port"),
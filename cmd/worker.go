@@ -20,14 +20,25 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	weaviateClient "github.com/weaviate/weaviate-go-client/v4/weaviate"
 
+	"raggo/src/core/knowledgebase"
+	"raggo/src/core/resourcedeletion"
+	"raggo/src/core/translationflow"
+	"raggo/src/core/translationmemory"
 	"raggo/src/infrastructure/integrations/ollama"
 	jobctrl "raggo/src/infrastructure/job"
 	"raggo/src/storage/minioctrl"
 	"raggo/src/storage/postgres/chunkctrl"
+	"raggo/src/storage/postgres/glossaryctrl"
+	pgKnowledgeBase "raggo/src/storage/postgres/knowledgebasectrl"
 	"raggo/src/storage/postgres/resourcectrl"
+	"raggo/src/storage/postgres/styleguidectrl"
 	"raggo/src/storage/postgres/translatedchunkctrl"
 	"raggo/src/storage/postgres/translatedresourcectrl"
+	pgTranslationMemory "raggo/src/storage/postgres/translationmemoryctrl"
+	"raggo/src/storage/postgres/translationprofilectrl"
+	"raggo/src/storage/weaviate"
 )
 
 var workerCmd = &cobra.Command{
@@ -143,6 +154,39 @@ func runWorker(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to initialize translated chunk service: %v", err)
 	}
 
+	// Initialize GlossaryService
+	glossaryService, err := glossaryctrl.NewGlossaryService(db)
+	if err != nil {
+		return fmt.Errorf("failed to initialize glossary service: %v", err)
+	}
+
+	// Initialize translation memory
+	translationMemory, err := translationmemory.NewService(pgTranslationMemory.NewRepository(db))
+	if err != nil {
+		return fmt.Errorf("failed to initialize translation memory: %v", err)
+	}
+
+	// Initialize StyleGuideService
+	styleGuideService, err := styleguidectrl.NewStyleGuideService(db)
+	if err != nil {
+		return fmt.Errorf("failed to initialize style guide service: %v", err)
+	}
+
+	// Initialize TranslationProfileService
+	translationProfileService, err := translationprofilectrl.NewTranslationProfileService(db)
+	if err != nil {
+		return fmt.Errorf("failed to initialize translation profile service: %v", err)
+	}
+
+	// Load template overrides, failing at startup instead of in the middle of a translation
+	var templateOverrides map[string]string
+	if templateDir := viper.GetString("translation.template_dir"); templateDir != "" {
+		templateOverrides, err = translationflow.LoadTemplateDir(templateDir)
+		if err != nil {
+			return fmt.Errorf("failed to load translation templates: %v", err)
+		}
+	}
+
 	// Initialize TranslationTask
 	translationTask := jobctrl.NewTranslationTask(
 		resourceService,
@@ -151,11 +195,55 @@ func runWorker(cmd *cobra.Command, args []string) error {
 		translatedChunkService,
 		minioService,
 		ollamaClient,
+		glossaryService,
+		translationMemory,
+		styleGuideService,
+		translationProfileService,
+		templateOverrides,
+		translationflow.NewLimiter(viper.GetInt("ollama.max_concurrency"), viper.GetFloat64("ollama.requests_per_second")),
+		viper.GetInt("translation.chunk_concurrency"),
+		jobctrl.QualityEstimationConfig{
+			Enabled:        viper.GetBool("translation.quality_estimation"),
+			Threshold:      viper.GetFloat64("translation.quality_threshold"),
+			EmbeddingModel: viper.GetString("translation.quality_embedding_model"),
+		},
+	)
+
+	// Initialize Weaviate SDK
+	wc := weaviateClient.New(weaviateClient.Config{
+		Host:   viper.GetString("weaviate.url"),
+		Scheme: "http",
+	})
+	wsdk := weaviate.NewSDK(wc)
+
+	// Initialize KnowledgeBaseService
+	knowledgeBaseService, err := knowledgebase.NewService(
+		pgKnowledgeBase.NewRepository(db),
+		wsdk,
+		ollamaClient,
+		minioService,
+		resourceService,
+		chunkService,
+		translatedResourceService,
+		translatedChunkService,
 	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize knowledge base service: %v", err)
+	}
+
+	// Initialize ResourceDeletionTask
+	resourceDeletionTask := jobctrl.NewResourceDeletionTask(resourcedeletion.NewService(
+		minioService,
+		resourceService,
+		chunkService,
+		translatedResourceService,
+		translatedChunkService,
+		knowledgeBaseService,
+	))
 
 	// Initialize job repository and service
 	jobRepo := jobctrl.NewPostgresJobRepository(db)
-	jobService := jobctrl.NewJobService(amqpPublisher, jobRepo, logger, translationTask)
+	jobService := jobctrl.NewJobService(amqpPublisher, jobRepo, logger, translationTask, resourceDeletionTask)
 
 	// Add handler for processing jobs
 	router.AddNoPublisherHandler(
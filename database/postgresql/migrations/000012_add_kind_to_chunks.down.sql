@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_chunks_kind;
+ALTER TABLE chunks DROP COLUMN table_markdown_minio_url;
+ALTER TABLE chunks DROP COLUMN table_html_minio_url;
+ALTER TABLE chunks DROP COLUMN kind;
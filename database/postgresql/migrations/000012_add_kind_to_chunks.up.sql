@@ -0,0 +1,6 @@
+ALTER TABLE chunks
+ADD COLUMN kind VARCHAR(20) NOT NULL DEFAULT 'text',
+ADD COLUMN table_html_minio_url TEXT,
+ADD COLUMN table_markdown_minio_url TEXT;
+
+CREATE INDEX idx_chunks_kind ON chunks(kind);
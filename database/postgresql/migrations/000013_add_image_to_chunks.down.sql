@@ -0,0 +1 @@
+ALTER TABLE chunks DROP COLUMN image_minio_url;
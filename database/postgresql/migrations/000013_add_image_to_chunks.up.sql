@@ -0,0 +1,2 @@
+ALTER TABLE chunks
+ADD COLUMN image_minio_url TEXT;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_knowledge_base_resources_content_hash;
+ALTER TABLE knowledge_base_resources DROP COLUMN content_hash;
+DROP INDEX IF EXISTS idx_chunks_content_hash;
+ALTER TABLE chunks DROP COLUMN content_hash;
+DROP INDEX IF EXISTS idx_resources_content_hash;
+ALTER TABLE resources DROP COLUMN content_hash;
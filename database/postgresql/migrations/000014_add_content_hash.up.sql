@@ -0,0 +1,14 @@
+ALTER TABLE resources
+ADD COLUMN content_hash VARCHAR(64);
+
+CREATE INDEX idx_resources_content_hash ON resources(content_hash);
+
+ALTER TABLE chunks
+ADD COLUMN content_hash VARCHAR(64);
+
+CREATE INDEX idx_chunks_content_hash ON chunks(content_hash);
+
+ALTER TABLE knowledge_base_resources
+ADD COLUMN content_hash VARCHAR(64);
+
+CREATE INDEX idx_knowledge_base_resources_content_hash ON knowledge_base_resources(knowledge_base_id, content_hash);
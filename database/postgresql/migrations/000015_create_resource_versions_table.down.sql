@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS resource_versions;
+ALTER TABLE resources DROP COLUMN version;
@@ -0,0 +1,21 @@
+ALTER TABLE resources
+ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS resource_versions (
+    id BIGINT PRIMARY KEY,
+    resource_id BIGINT NOT NULL,
+    version INTEGER NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    minio_url TEXT NOT NULL,
+    content_hash VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (resource_id) REFERENCES resources(id),
+    UNIQUE (resource_id, version)
+);
+
+CREATE INDEX idx_resource_versions_resource_id ON resource_versions(resource_id);
+
+-- Existing resources become their own first version
+INSERT INTO resource_versions (id, resource_id, version, filename, minio_url, content_hash, created_at, updated_at)
+SELECT id, id, 1, filename, minio_url, content_hash, created_at, updated_at FROM resources;
@@ -0,0 +1,3 @@
+ALTER TABLE resources
+DROP COLUMN IF EXISTS text_minio_url,
+DROP COLUMN IF EXISTS markdown_minio_url;
@@ -0,0 +1,3 @@
+ALTER TABLE resources
+ADD COLUMN text_minio_url TEXT,
+ADD COLUMN markdown_minio_url TEXT;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS glossary_terms;
+DROP TABLE IF EXISTS glossaries;
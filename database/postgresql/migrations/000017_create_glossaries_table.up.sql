@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS glossaries (
+    id BIGINT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    source_language VARCHAR(50) NOT NULL,
+    target_language VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS glossary_terms (
+    id BIGINT PRIMARY KEY,
+    glossary_id BIGINT NOT NULL,
+    source_term VARCHAR(255) NOT NULL,
+    target_term VARCHAR(255),
+    do_not_translate BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (glossary_id) REFERENCES glossaries(id) ON DELETE CASCADE,
+    UNIQUE (glossary_id, source_term)
+);
+
+CREATE INDEX idx_glossary_terms_glossary_id ON glossary_terms(glossary_id);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS translation_memory (
+    id BIGINT PRIMARY KEY,
+    source_hash CHAR(64) NOT NULL,
+    source_language VARCHAR(50) NOT NULL,
+    target_language VARCHAR(50) NOT NULL,
+    country VARCHAR(50) NOT NULL,
+    model VARCHAR(255) NOT NULL,
+    source_text TEXT NOT NULL,
+    target_text TEXT NOT NULL,
+    source_length INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (source_hash, source_language, target_language, country, model)
+);
+
+CREATE INDEX idx_translation_memory_candidates ON translation_memory(source_language, target_language, country, source_length);
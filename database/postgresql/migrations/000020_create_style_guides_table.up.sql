@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS style_guides (
+    id BIGINT PRIMARY KEY,
+    target_language VARCHAR(50) NOT NULL,
+    country VARCHAR(50) NOT NULL DEFAULT '',
+    formality VARCHAR(20) NOT NULL DEFAULT '',
+    tone TEXT NOT NULL DEFAULT '',
+    instructions TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (target_language, country)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS translation_profiles;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_translated_resources_variant;
+ALTER TABLE translated_resources DROP COLUMN model;
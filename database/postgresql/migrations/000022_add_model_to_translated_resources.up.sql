@@ -0,0 +1,4 @@
+ALTER TABLE translated_resources ADD COLUMN model VARCHAR(255) NOT NULL DEFAULT '';
+UPDATE translated_resources SET country = '' WHERE country IS NULL;
+
+CREATE UNIQUE INDEX idx_translated_resources_variant ON translated_resources(original_resource_id, target_language, country, model);
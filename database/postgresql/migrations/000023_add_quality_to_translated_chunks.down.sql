@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_translated_chunks_flagged;
+ALTER TABLE translated_chunks
+    DROP COLUMN quality_score,
+    DROP COLUMN quality_accuracy,
+    DROP COLUMN quality_fluency,
+    DROP COLUMN quality_terminology,
+    DROP COLUMN back_translation_similarity,
+    DROP COLUMN quality_comment,
+    DROP COLUMN flagged_for_review;
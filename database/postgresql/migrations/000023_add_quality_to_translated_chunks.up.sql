@@ -0,0 +1,10 @@
+ALTER TABLE translated_chunks
+    ADD COLUMN quality_score DOUBLE PRECISION,
+    ADD COLUMN quality_accuracy SMALLINT,
+    ADD COLUMN quality_fluency SMALLINT,
+    ADD COLUMN quality_terminology SMALLINT,
+    ADD COLUMN back_translation_similarity DOUBLE PRECISION,
+    ADD COLUMN quality_comment TEXT NOT NULL DEFAULT '',
+    ADD COLUMN flagged_for_review BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_translated_chunks_flagged ON translated_chunks(translated_resource_id) WHERE flagged_for_review;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_translated_chunks_review_status;
+ALTER TABLE translated_chunks
+    DROP COLUMN review_status,
+    DROP COLUMN revision_minio_url,
+    DROP COLUMN revised_by,
+    DROP COLUMN revised_at,
+    DROP COLUMN reviewed_by,
+    DROP COLUMN reviewed_at,
+    DROP COLUMN review_comment;
@@ -0,0 +1,10 @@
+ALTER TABLE translated_chunks
+    ADD COLUMN review_status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    ADD COLUMN revision_minio_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN revised_by VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN revised_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN reviewed_by VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN reviewed_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN review_comment TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_translated_chunks_review_status ON translated_chunks(translated_resource_id, review_status);
@@ -0,0 +1,3 @@
+ALTER TABLE chunks
+    DROP COLUMN language,
+    DROP COLUMN language_confidence;
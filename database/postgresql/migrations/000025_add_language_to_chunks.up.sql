@@ -0,0 +1,3 @@
+ALTER TABLE chunks
+    ADD COLUMN language VARCHAR(16) NOT NULL DEFAULT '',
+    ADD COLUMN language_confidence DOUBLE PRECISION;
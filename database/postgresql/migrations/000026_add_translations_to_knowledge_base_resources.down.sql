@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_knowledge_base_resources_language;
+DROP INDEX IF EXISTS idx_knowledge_base_resources_translated_chunk_id;
+ALTER TABLE knowledge_base_resources
+    DROP COLUMN translated_chunk_id,
+    DROP COLUMN language;
@@ -0,0 +1,6 @@
+ALTER TABLE knowledge_base_resources
+    ADD COLUMN translated_chunk_id BIGINT REFERENCES translated_chunks(id) ON DELETE CASCADE,
+    ADD COLUMN language VARCHAR(16) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_knowledge_base_resources_translated_chunk_id ON knowledge_base_resources(knowledge_base_id, translated_chunk_id);
+CREATE INDEX idx_knowledge_base_resources_language ON knowledge_base_resources(knowledge_base_id, language);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS conversations (
+    id BIGINT PRIMARY KEY,
+    knowledge_base_id BIGINT NOT NULL,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (knowledge_base_id) REFERENCES knowledge_bases(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_conversations_knowledge_base_id ON conversations(knowledge_base_id, updated_at);
+
+CREATE TABLE IF NOT EXISTS conversation_messages (
+    id BIGINT PRIMARY KEY,
+    conversation_id BIGINT NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    content TEXT NOT NULL,
+    standalone_query TEXT NOT NULL DEFAULT '',
+    citations JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_conversation_messages_conversation_id ON conversation_messages(conversation_id, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE chunks
+    DROP COLUMN page_number;
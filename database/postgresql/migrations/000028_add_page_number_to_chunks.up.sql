@@ -0,0 +1,2 @@
+ALTER TABLE chunks
+    ADD COLUMN page_number INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,14 @@
+ALTER TABLE translation_memory DROP CONSTRAINT IF EXISTS translation_memory_key;
+
+-- Entries that only differ by glossary or style guide cannot be told apart any more
+DELETE FROM translation_memory WHERE glossary <> '' OR style_guide <> '';
+UPDATE translation_memory SET model = model || '@' || pipeline WHERE pipeline <> '';
+
+ALTER TABLE translation_memory
+    DROP COLUMN pipeline,
+    DROP COLUMN glossary,
+    DROP COLUMN style_guide;
+
+ALTER TABLE translation_memory
+    ADD CONSTRAINT translation_memory_source_hash_source_language_target_language_country_model_key
+    UNIQUE (source_hash, source_language, target_language, country, model);
@@ -0,0 +1,23 @@
+ALTER TABLE translation_memory
+    ADD COLUMN pipeline VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN glossary VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN style_guide VARCHAR(64) NOT NULL DEFAULT '';
+
+-- Translations of other pipelines were kept apart by appending the pipeline signature to the model
+UPDATE translation_memory
+SET pipeline = split_part(model, '@', 2), model = split_part(model, '@', 1)
+WHERE model LIKE '%@%';
+
+DO $$
+DECLARE
+    constraint_name TEXT;
+BEGIN
+    SELECT conname INTO constraint_name FROM pg_constraint
+    WHERE conrelid = 'translation_memory'::regclass AND contype = 'u';
+    IF constraint_name IS NOT NULL THEN
+        EXECUTE format('ALTER TABLE translation_memory DROP CONSTRAINT %I', constraint_name);
+    END IF;
+END $$;
+
+ALTER TABLE translation_memory ADD CONSTRAINT translation_memory_key
+    UNIQUE (source_hash, source_language, target_language, country, model, pipeline, glossary, style_guide);
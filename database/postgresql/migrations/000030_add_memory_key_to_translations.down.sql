@@ -0,0 +1,6 @@
+ALTER TABLE translated_chunks
+    DROP COLUMN source_language;
+ALTER TABLE translated_resources
+    DROP COLUMN pipeline_signature,
+    DROP COLUMN glossary_signature,
+    DROP COLUMN style_guide_signature;
@@ -0,0 +1,6 @@
+ALTER TABLE translated_resources
+    ADD COLUMN pipeline_signature VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN glossary_signature VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN style_guide_signature VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE translated_chunks
+    ADD COLUMN source_language VARCHAR(50) NOT NULL DEFAULT '';
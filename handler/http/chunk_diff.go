@@ -0,0 +1,76 @@
+package http
+
+import (
+	"raggo/src/storage/postgres/chunkctrl"
+)
+
+// chunkDiff matches the chunks produced by a new conversion against the chunks of the previous one by content hash.
+// Previous chunks that are matched are kept (and possibly moved), the rest are removed once the conversion finishes.
+type chunkDiff struct {
+	pool    map[string][]chunkctrl.Chunk // unmatched previous chunks by kind + content hash
+	taken   map[string]bool              // MinIO URLs of every previous chunk
+	kept    []chunkctrl.Chunk
+	added   []chunkctrl.Chunk
+	skipped map[string]bool // kinds that were not produced by this conversion and must be left untouched
+}
+
+func newChunkDiff(previous []chunkctrl.Chunk) *chunkDiff {
+	d := &chunkDiff{
+		pool:    make(map[string][]chunkctrl.Chunk),
+		taken:   make(map[string]bool),
+		skipped: make(map[string]bool),
+	}
+	for _, chunk := range previous {
+		d.taken[chunk.MinioURL] = true
+		// Chunks converted before content hashes existed can't be matched and are replaced
+		if chunk.ContentHash == "" {
+			d.pool[chunk.Kind+":"] = append(d.pool[chunk.Kind+":"], chunk)
+			continue
+		}
+		key := chunk.Kind + ":" + chunk.ContentHash
+		d.pool[key] = append(d.pool[key], chunk)
+	}
+	return d
+}
+
+// match returns a previous chunk with the same kind and content, or nil if the content is new
+func (d *chunkDiff) match(kind, contentHash string) *chunkctrl.Chunk {
+	key := kind + ":" + contentHash
+	candidates := d.pool[key]
+	if contentHash == "" || len(candidates) == 0 {
+		return nil
+	}
+
+	chunk := candidates[0]
+	d.pool[key] = candidates[1:]
+	d.kept = append(d.kept, chunk)
+	return &chunk
+}
+
+func (d *chunkDiff) add(chunk chunkctrl.Chunk) {
+	d.added = append(d.added, chunk)
+}
+
+// skipKind keeps every previous chunk of the kind, used when the conversion did not produce that kind at all
+func (d *chunkDiff) skipKind(kind string) {
+	d.skipped[kind] = true
+}
+
+// isTaken reports whether a MinIO URL is still used by a previous chunk
+func (d *chunkDiff) isTaken(minioURL string) bool {
+	return d.taken[minioURL]
+}
+
+// removed returns the previous chunks that have no counterpart in the new conversion
+func (d *chunkDiff) removed() []chunkctrl.Chunk {
+	var removed []chunkctrl.Chunk
+	for _, chunks := range d.pool {
+		for _, chunk := range chunks {
+			if d.skipped[chunk.Kind] {
+				continue
+			}
+			removed = append(removed, chunk)
+		}
+	}
+	return removed
+}
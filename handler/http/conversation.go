@@ -0,0 +1,224 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"raggo/src/core/conversation"
+	"raggo/src/core/knowledgebase"
+	"raggo/src/storage/postgres/conversationctrl"
+)
+
+// eventRewrite carries the standalone query a question of a conversation is searched with
+const eventRewrite = "rewrite"
+
+type ConversationHandler struct {
+	service             *conversation.Service
+	conversationService *conversationctrl.ConversationService
+	knowledgeBase       *KnowledgeBaseHandler // answers questions with the options of the knowledge base routes
+	rewriteModel        string                // default model rewriting follow-up questions
+	historyTurns        int                   // earlier messages a question is rewritten and answered with
+}
+
+func NewConversationHandler(
+	service *conversation.Service,
+	conversationService *conversationctrl.ConversationService,
+	knowledgeBase *KnowledgeBaseHandler,
+	rewriteModel string,
+	historyTurns int,
+) (*ConversationHandler, error) {
+	return &ConversationHandler{
+		service:             service,
+		conversationService: conversationService,
+		knowledgeBase:       knowledgeBase,
+		rewriteModel:        rewriteModel,
+		historyTurns:        historyTurns,
+	}, nil
+}
+
+// Create handles POST /api/v1/knowledge-bases/:id/conversations
+func (h *ConversationHandler) Create(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	// The title is optional; without one the conversation is named after its first question
+	var req struct {
+		Title string `json:"title" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	kb, err := h.knowledgeBase.service.GetKnowledgeBase(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if kb == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
+		return
+	}
+
+	conversation, err := h.conversationService.Create(ctx, id, req.Title)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, conversation)
+}
+
+// List handles GET /api/v1/knowledge-bases/:id/conversations
+func (h *ConversationHandler) List(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	conversations, err := h.conversationService.List(c.Request.Context(), id, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":  conversations,
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// Get handles GET /api/v1/conversations/:id. It returns the conversation with its messages and the sources
+// cited anywhere in it.
+func (h *ConversationHandler) Get(c *gin.Context) {
+	conv, ok := h.conversation(c)
+	if !ok {
+		return
+	}
+
+	messages, err := h.conversationService.ListMessages(c.Request.Context(), conv.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation": conv,
+		"messages":     messages,
+		"sources":      conversation.Sources(messages),
+	})
+}
+
+// Delete handles DELETE /api/v1/conversations/:id
+func (h *ConversationHandler) Delete(c *gin.Context) {
+	conv, ok := h.conversation(c)
+	if !ok {
+		return
+	}
+
+	if err := h.conversationService.Delete(c.Request.Context(), conv.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Ask handles POST /api/v1/conversations/:id/messages. The answer is streamed like an answer of the knowledge base,
+// preceded by a rewrite event with the standalone query the question is searched with; the final event also carries
+// the ID of the stored answer.
+func (h *ConversationHandler) Ask(c *gin.Context) {
+	conv, ok := h.conversation(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Question    string `json:"question" binding:"required"`
+		Model       string `json:"model"`
+		TokenBudget int    `json:"token_budget"`
+		retrievalRequest
+		// RewriteModel overrides the configured model rewriting follow-up questions; "none" searches them as they are
+		RewriteModel string `json:"rewrite_model"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	answerOpts, ok := h.knowledgeBase.answerOptions(c, req.Model, req.TokenBudget, req.retrievalRequest)
+	if !ok {
+		return
+	}
+
+	opts := conversation.AskOptions{
+		Answer:       answerOpts,
+		RewriteModel: h.rewriteModel,
+		HistoryTurns: h.historyTurns,
+	}
+	switch req.RewriteModel {
+	case "":
+	case "none":
+		opts.RewriteModel = ""
+	default:
+		opts.RewriteModel = req.RewriteModel
+	}
+
+	streamEvents(c, func(send func(name string, data interface{})) {
+		opts.OnRewrite = func(query string) {
+			send(eventRewrite, gin.H{"query": query})
+		}
+		opts.Answer.OnSources = func(sources []knowledgebase.Source) {
+			send(eventSources, gin.H{"sources": sources})
+		}
+		opts.Answer.OnToken = func(token string) {
+			send(eventDelta, gin.H{"text": token})
+		}
+
+		reply, err := h.service.Ask(c.Request.Context(), conv, req.Question, opts)
+		if err != nil {
+			send(eventError, gin.H{"error": err.Error()})
+			return
+		}
+		send(eventFinal, gin.H{
+			"message_id": reply.Message.ID,
+			"answer":     reply.Answer.Answer,
+			"citations":  reply.Answer.Citations,
+		})
+	})
+}
+
+// conversation loads the conversation of the request.
+// It writes the error response itself and reports false if there is none.
+func (h *ConversationHandler) conversation(c *gin.Context) (*conversationctrl.Conversation, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return nil, false
+	}
+
+	conv, err := h.conversationService.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if conv == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		return nil, false
+	}
+	return conv, true
+}
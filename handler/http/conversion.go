@@ -10,7 +10,13 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"raggo/src/core/document"
+	"raggo/src/core/imagecaption"
+	"raggo/src/core/knowledgebase"
+	"raggo/src/core/resourcedeletion"
+	"raggo/src/infrastructure/integrations/ollama"
 	"raggo/src/infrastructure/integrations/unstructured"
+	"raggo/src/storage/contenthash"
 	"raggo/src/storage/minioctrl"
 	"raggo/src/storage/postgres/chunkctrl"
 	"raggo/src/storage/postgres/resourcectrl"
@@ -20,25 +26,37 @@ type ConversionHandler struct {
 	minioService        *minioctrl.MinioService
 	resourceBucket      string
 	chunkBucket         string
+	imageBucket         string
+	documentBucket      string
 	minioDomain         string
 	resourceService     *resourcectrl.ResourceService
 	chunkService        *chunkctrl.ChunkService
 	unstructuredService *unstructured.UnstructuredService
+	ollamaClient        *ollama.Client
+
+	knowledgeBaseService    *knowledgebase.Service
+	resourceDeletionService *resourcedeletion.Service
 }
 
 func NewConversionHandler(
 	minioService *minioctrl.MinioService,
 	resourceBucket string,
 	chunkBucket string,
+	imageBucket string,
+	documentBucket string,
 	minioDomain string,
 	unstructuredURL string,
 	resourceService *resourcectrl.ResourceService,
 	chunkService *chunkctrl.ChunkService,
+	ollamaClient *ollama.Client,
+	knowledgeBaseService *knowledgebase.Service,
+	resourceDeletionService *resourcedeletion.Service,
 ) (*ConversionHandler, error) {
-	// Ensure chunk bucket exists
-	err := minioService.EnsureBucketExists(context.Background(), chunkBucket)
-	if err != nil {
-		return nil, fmt.Errorf("failed to ensure bucket exists: %v", err)
+	// Ensure chunk, image and document buckets exist
+	for _, bucket := range []string{chunkBucket, imageBucket, documentBucket} {
+		if err := minioService.EnsureBucketExists(context.Background(), bucket); err != nil {
+			return nil, fmt.Errorf("failed to ensure bucket exists: %v", err)
+		}
 	}
 
 	unstructuredService := unstructured.NewUnstructuredService(unstructuredURL)
@@ -47,15 +65,23 @@ func NewConversionHandler(
 		minioService:        minioService,
 		resourceBucket:      resourceBucket,
 		chunkBucket:         chunkBucket,
+		imageBucket:         imageBucket,
+		documentBucket:      documentBucket,
 		minioDomain:         minioDomain,
 		resourceService:     resourceService,
 		chunkService:        chunkService,
 		unstructuredService: unstructuredService,
+		ollamaClient:        ollamaClient,
+
+		knowledgeBaseService:    knowledgeBaseService,
+		resourceDeletionService: resourceDeletionService,
 	}, nil
 }
 
 type ConversionRequest struct {
-	PDFID string `json:"pdfId"`
+	PDFID         string `json:"pdfId"`
+	ExtractImages bool   `json:"extractImages"`
+	CaptionModel  string `json:"captionModel"` // vision-capable model used to caption extracted images
 }
 
 func (h *ConversionHandler) Convert(c *gin.Context) {
@@ -84,34 +110,13 @@ func (h *ConversionHandler) Convert(c *gin.Context) {
 		return
 	}
 
-	// Check for existing chunks and delete them if found
+	// Load the chunks of the previous conversion to diff against
 	existingChunks, err := h.chunkService.GetByResourceID(c.Request.Context(), pdfID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing chunks"})
 		return
 	}
-
-	if len(existingChunks) > 0 {
-		// Delete chunks from MinIO
-		for _, chunk := range existingChunks {
-			parts := strings.Split(chunk.MinioURL, "/")
-			if len(parts) != 2 {
-				log.Printf("Invalid MinIO URL format for chunk: %s", chunk.MinioURL)
-				continue
-			}
-			err = h.minioService.DeleteObject(c.Request.Context(), parts[0], parts[1])
-			if err != nil {
-				log.Printf("Failed to delete chunk from MinIO: %v", err)
-			}
-		}
-
-		// Delete chunks from database
-		err = h.chunkService.DeleteByResourceID(c.Request.Context(), pdfID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete existing chunks"})
-			return
-		}
-	}
+	diff := newChunkDiff(existingChunks)
 
 	// Parse MinIO URL to get bucket and object name
 	parts := strings.Split(resource.MinioURL, "/")
@@ -136,48 +141,337 @@ func (h *ConversionHandler) Convert(c *gin.Context) {
 		return
 	}
 
-	// Store chunks
-	var chunks []chunkctrl.Chunk
+	// Extract the figures up front, so that they are ordered onto their pages between the text
+	extractImages := req.ExtractImages || req.CaptionModel != ""
+	var images []unstructured.UnstructuredElement
+	if extractImages {
+		images, err = h.unstructuredService.ExtractImages(resource.Filename, fileBytes)
+		if err != nil {
+			log.Printf("Failed to extract images: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to extract images"})
+			return
+		}
+	}
+	elementOrders, imageOrders := unstructured.ReadingOrder(elements, images)
+
+	// Store new chunks and keep the unchanged ones
 	for i, element := range elements {
-		if element.Text == "" {
+		if element.Text == "" && !element.IsTable() {
 			continue
 		}
 
-		// Generate chunk filename
 		chunkID := fmt.Sprintf("chunk_%d", i+1)
-		chunkName := fmt.Sprintf("%s_%s.txt", strings.TrimSuffix(filepath.Base(resource.Filename), filepath.Ext(resource.Filename)), chunkID)
-
-		// Upload chunk to MinIO
-		err = h.minioService.PutObject(
-			c.Request.Context(),
-			h.chunkBucket,
-			chunkName,
-			[]byte(element.Text),
-		)
+		order := elementOrders[i]
+
+		kind := chunkctrl.KindText
+		if element.IsTable() {
+			kind = chunkctrl.KindTable
+		}
+
+		if previous := diff.match(kind, contenthash.Sum([]byte(element.Text))); previous != nil {
+			if previous.ChunkID != chunkID || previous.Order != order {
+				if err := h.chunkService.UpdatePosition(c.Request.Context(), previous.ID, chunkID, order, element.Metadata.PageNumber); err != nil {
+					log.Printf("Failed to move chunk: %v", err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record chunk"})
+					return
+				}
+			}
+			continue
+		}
+
+		chunk, err := h.storeElementChunk(c.Request.Context(), resource, chunkID, element, order, diff)
 		if err != nil {
+			log.Printf("Failed to store chunk: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store chunk"})
 			return
 		}
+		diff.add(*chunk)
+	}
+
+	// Store the figures and caption them
+	if extractImages {
+		if err := h.storeImageChunks(c.Request.Context(), resource, images, imageOrders, elements, elementOrders, req.CaptionModel, diff); err != nil {
+			log.Printf("Failed to extract images: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to extract images"})
+			return
+		}
+	} else {
+		diff.skipKind(chunkctrl.KindImage)
+	}
+
+	// Drop the chunks that disappeared from the document and index the new ones
+	removed := diff.removed()
+	if err := h.resourceDeletionService.DeleteChunks(c.Request.Context(), resource.ID, removed); err != nil {
+		log.Printf("Failed to remove outdated chunks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove outdated chunks"})
+		return
+	}
+
+	if err := h.knowledgeBaseService.IndexResourceChunks(c.Request.Context(), resource.ID, diff.added); err != nil {
+		log.Printf("Failed to update knowledge bases: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update knowledge bases"})
+		return
+	}
+
+	// Merge the chunks into the full document
+	if err := h.storeDocument(c.Request.Context(), resource); err != nil {
+		log.Printf("Failed to store merged document: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store merged document"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"jobId":     fmt.Sprintf("conv_%d", resource.ID),
+		"status":    "completed",
+		"message":   fmt.Sprintf("Successfully converted PDF into %d chunks", len(diff.kept)+len(diff.added)),
+		"version":   resource.Version,
+		"added":     len(diff.added),
+		"removed":   len(removed),
+		"unchanged": len(diff.kept),
+	})
+}
+
+// storeDocument reassembles the chunks of the resource in document order and stores the full text
+// and Markdown in the document bucket. Documents of a previous version are removed.
+func (h *ConversionHandler) storeDocument(ctx context.Context, resource *resourcectrl.Resource) error {
+	chunks, err := h.chunkService.GetByResourceID(ctx, resource.ID)
+	if err != nil {
+		return err
+	}
+
+	parts := make([]document.Part, 0, len(chunks))
+	for _, chunk := range chunks {
+		part := document.Part{Kind: chunk.Kind}
+
+		bucket, objectName := h.minioService.GetBucketAndObjectFromURL(chunk.MinioURL)
+		content, err := h.minioService.GetObject(ctx, bucket, objectName)
+		if err != nil {
+			return fmt.Errorf("failed to read chunk %s: %v", chunk.ChunkID, err)
+		}
+		part.Content = string(content)
+
+		if chunk.IsTable() && chunk.TableMarkdownMinioURL != "" {
+			bucket, objectName := h.minioService.GetBucketAndObjectFromURL(chunk.TableMarkdownMinioURL)
+			markdown, err := h.minioService.GetObject(ctx, bucket, objectName)
+			if err != nil {
+				return fmt.Errorf("failed to read table markdown of chunk %s: %v", chunk.ChunkID, err)
+			}
+			part.TableMarkdown = string(markdown)
+		}
+		if chunk.IsImage() {
+			part.ImageURL = fmt.Sprintf("%s/%s", h.minioDomain, chunk.ImageMinioURL)
+		}
+
+		parts = append(parts, part)
+	}
 
-		// Create chunk record
-		chunk, err := h.chunkService.Create(
-			c.Request.Context(),
+	text, markdown := document.Assemble(parts)
+
+	baseName := fmt.Sprintf("%d_v%d", resource.ID, resource.Version)
+	textURL := fmt.Sprintf("%s/%s.txt", h.documentBucket, baseName)
+	markdownURL := fmt.Sprintf("%s/%s.md", h.documentBucket, baseName)
+	if err := h.minioService.PutObject(ctx, h.documentBucket, baseName+".txt", []byte(text)); err != nil {
+		return fmt.Errorf("failed to store document text: %v", err)
+	}
+	if err := h.minioService.PutObject(ctx, h.documentBucket, baseName+".md", []byte(markdown)); err != nil {
+		return fmt.Errorf("failed to store document markdown: %v", err)
+	}
+
+	if err := h.resourceService.SetDocumentURLs(ctx, resource.ID, textURL, markdownURL); err != nil {
+		return err
+	}
+
+	for _, previousURL := range []string{resource.TextMinioURL, resource.MarkdownMinioURL} {
+		if previousURL == "" || previousURL == textURL || previousURL == markdownURL {
+			continue
+		}
+		bucket, objectName := h.minioService.GetBucketAndObjectFromURL(previousURL)
+		if err := h.minioService.DeleteObject(ctx, bucket, objectName); err != nil {
+			log.Printf("Failed to delete previous document from MinIO: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// chunkBaseName returns an object name prefix for a new chunk that no previous chunk of the resource uses
+func (h *ConversionHandler) chunkBaseName(resource *resourcectrl.Resource, chunkID string, diff *chunkDiff) string {
+	baseName := fmt.Sprintf("%s_v%d_%s", strings.TrimSuffix(filepath.Base(resource.Filename), filepath.Ext(resource.Filename)), resource.Version, chunkID)
+	candidate := baseName
+	for i := 2; diff.isTaken(fmt.Sprintf("%s/%s.txt", h.chunkBucket, candidate)); i++ {
+		candidate = fmt.Sprintf("%s_%d", baseName, i)
+	}
+	return candidate
+}
+
+// storeElementChunk uploads a converted element and records it as a text or table chunk
+func (h *ConversionHandler) storeElementChunk(
+	ctx context.Context,
+	resource *resourcectrl.Resource,
+	chunkID string,
+	element unstructured.UnstructuredElement,
+	order int,
+	diff *chunkDiff,
+) (*chunkctrl.Chunk, error) {
+	baseName := h.chunkBaseName(resource, chunkID, diff)
+	chunkName := baseName + ".txt"
+
+	// Upload chunk to MinIO
+	if err := h.minioService.PutObject(ctx, h.chunkBucket, chunkName, []byte(element.Text)); err != nil {
+		return nil, fmt.Errorf("failed to store chunk: %v", err)
+	}
+
+	if element.IsTable() {
+		return h.storeTableChunk(ctx, resource.ID, chunkID, baseName, chunkName, element, order)
+	}
+
+	// Create chunk record
+	return h.chunkService.Create(
+		ctx,
+		resource.ID,
+		chunkID,
+		fmt.Sprintf("%s/%s", h.chunkBucket, chunkName),
+		contenthash.Sum([]byte(element.Text)),
+		order,
+		element.Metadata.PageNumber,
+	)
+}
+
+// storeTableChunk uploads the HTML and Markdown representations of a table element and records a table chunk.
+// The flattened text is expected to be uploaded already as chunkName.
+func (h *ConversionHandler) storeTableChunk(
+	ctx context.Context,
+	resourceID int64,
+	chunkID string,
+	baseName string,
+	chunkName string,
+	element unstructured.UnstructuredElement,
+	order int,
+) (*chunkctrl.Chunk, error) {
+	tableHTML := element.TableHTML()
+	markdown, err := unstructured.TableHTMLToMarkdown(tableHTML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert table to markdown: %v", err)
+	}
+
+	htmlName := baseName + ".html"
+	if err := h.minioService.PutObject(ctx, h.chunkBucket, htmlName, []byte(tableHTML)); err != nil {
+		return nil, fmt.Errorf("failed to store table html: %v", err)
+	}
+
+	markdownName := baseName + ".md"
+	if err := h.minioService.PutObject(ctx, h.chunkBucket, markdownName, []byte(markdown)); err != nil {
+		return nil, fmt.Errorf("failed to store table markdown: %v", err)
+	}
+
+	return h.chunkService.CreateTable(
+		ctx,
+		resourceID,
+		chunkID,
+		fmt.Sprintf("%s/%s", h.chunkBucket, chunkName),
+		contenthash.Sum([]byte(element.Text)),
+		fmt.Sprintf("%s/%s", h.chunkBucket, htmlName),
+		fmt.Sprintf("%s/%s", h.chunkBucket, markdownName),
+		order,
+		element.Metadata.PageNumber,
+	)
+}
+
+// surroundingTextLimit caps the text before and after a figure given to the caption model, in runes
+const surroundingTextLimit = 500
+
+// storeImageChunks stores the images extracted from the PDF in the image bucket and records one image chunk
+// per new figure at its position in reading order. When a caption model is given the caption becomes the chunk content,
+// captioned with the text before and after the figure, otherwise the text Unstructured found inside the image is used.
+// Figures identical to a previous conversion are kept without captioning them again.
+func (h *ConversionHandler) storeImageChunks(
+	ctx context.Context,
+	resource *resourcectrl.Resource,
+	images []unstructured.UnstructuredElement,
+	orders []int,
+	elements []unstructured.UnstructuredElement,
+	elementOrders []int,
+	captionModel string,
+	diff *chunkDiff,
+) error {
+	var captioner *imagecaption.Captioner
+	if captionModel != "" {
+		captioner = imagecaption.NewCaptioner(ollama.NewOllamaProvider(h.ollamaClient, captionModel))
+	}
+
+	for i, element := range images {
+		image, err := element.ImageBytes()
+		if err != nil {
+			log.Printf("Skipping image %d: %v", i+1, err)
+			continue
+		}
+
+		chunkID := fmt.Sprintf("image_%d", i+1)
+		order := orders[i]
+		imageHash := contenthash.Sum(image)
+
+		if previous := diff.match(chunkctrl.KindImage, imageHash); previous != nil {
+			if previous.ChunkID != chunkID || previous.Order != order {
+				if err := h.chunkService.UpdatePosition(ctx, previous.ID, chunkID, order, element.Metadata.PageNumber); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		baseName := h.chunkBaseName(resource, chunkID, diff)
+		imageName := baseName + imageExtension(element.Metadata.ImageMimeType)
+		if err := h.minioService.PutObject(ctx, h.imageBucket, imageName, image); err != nil {
+			return fmt.Errorf("failed to store image: %v", err)
+		}
+
+		caption := element.Text
+		if captioner != nil {
+			caption, err = captioner.Caption(ctx, image, imagecaption.CaptionData{
+				Filename:        resource.Filename,
+				PageNumber:      element.Metadata.PageNumber,
+				SurroundingText: unstructured.SurroundingText(elements, elementOrders, order, surroundingTextLimit),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		captionName := baseName + ".txt"
+		if err := h.minioService.PutObject(ctx, h.chunkBucket, captionName, []byte(caption)); err != nil {
+			return fmt.Errorf("failed to store image caption: %v", err)
+		}
+
+		chunk, err := h.chunkService.CreateImage(
+			ctx,
 			resource.ID,
 			chunkID,
-			fmt.Sprintf("%s/%s", h.chunkBucket, chunkName),
-			i+1, // Use the loop index + 1 as the order
+			fmt.Sprintf("%s/%s", h.chunkBucket, captionName),
+			imageHash,
+			fmt.Sprintf("%s/%s", h.imageBucket, imageName),
+			order,
+			element.Metadata.PageNumber,
 		)
 		if err != nil {
-			log.Printf("Failed to record chunk: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record chunk"})
-			return
+			return fmt.Errorf("failed to record image chunk: %v", err)
 		}
-		chunks = append(chunks, *chunk)
+		diff.add(*chunk)
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"jobId":   fmt.Sprintf("conv_%d", resource.ID),
-		"status":  "completed",
-		"message": fmt.Sprintf("Successfully converted PDF into %d chunks", len(chunks)),
-	})
+	return nil
+}
+
+func imageExtension(mimeType string) string {
+	switch mimeType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg", "image/jpg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	default:
+		return ".img"
+	}
 }
@@ -0,0 +1,169 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"raggo/src/storage/postgres/glossaryctrl"
+)
+
+type GlossaryHandler struct {
+	glossaryService *glossaryctrl.GlossaryService
+}
+
+func NewGlossaryHandler(glossaryService *glossaryctrl.GlossaryService) (*GlossaryHandler, error) {
+	return &GlossaryHandler{
+		glossaryService: glossaryService,
+	}, nil
+}
+
+// CreateGlossary handles POST /api/v1/glossaries
+func (h *GlossaryHandler) CreateGlossary(c *gin.Context) {
+	var req struct {
+		Name           string `json:"name" binding:"required"`
+		Description    string `json:"description"`
+		SourceLanguage string `json:"source_language" binding:"required"`
+		TargetLanguage string `json:"target_language" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	glossary, err := h.glossaryService.Create(c.Request.Context(), req.Name, req.Description, req.SourceLanguage, req.TargetLanguage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, glossary)
+}
+
+// ListGlossaries handles GET /api/v1/glossaries
+func (h *GlossaryHandler) ListGlossaries(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	glossaries, err := h.glossaryService.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":  glossaries,
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// GetGlossary handles GET /api/v1/glossaries/:id
+func (h *GlossaryHandler) GetGlossary(c *gin.Context) {
+	glossary, ok := h.findGlossary(c)
+	if !ok {
+		return
+	}
+
+	terms, err := h.glossaryService.ListTerms(c.Request.Context(), glossary.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"glossary": glossary,
+		"terms":    terms,
+	})
+}
+
+// DeleteGlossary handles DELETE /api/v1/glossaries/:id
+func (h *GlossaryHandler) DeleteGlossary(c *gin.Context) {
+	glossary, ok := h.findGlossary(c)
+	if !ok {
+		return
+	}
+
+	if err := h.glossaryService.Delete(c.Request.Context(), glossary.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AddTerm handles POST /api/v1/glossaries/:id/terms
+func (h *GlossaryHandler) AddTerm(c *gin.Context) {
+	glossary, ok := h.findGlossary(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		SourceTerm     string `json:"source_term" binding:"required"`
+		TargetTerm     string `json:"target_term"`
+		DoNotTranslate bool   `json:"do_not_translate"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.TargetTerm == "" && !req.DoNotTranslate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_term is required unless do_not_translate is set"})
+		return
+	}
+
+	term, err := h.glossaryService.AddTerm(c.Request.Context(), glossary.ID, req.SourceTerm, req.TargetTerm, req.DoNotTranslate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, term)
+}
+
+// DeleteTerm handles DELETE /api/v1/glossaries/:id/terms/:termId
+func (h *GlossaryHandler) DeleteTerm(c *gin.Context) {
+	glossary, ok := h.findGlossary(c)
+	if !ok {
+		return
+	}
+
+	termID, err := strconv.ParseInt(c.Param("termId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid term ID"})
+		return
+	}
+
+	if err := h.glossaryService.DeleteTerm(c.Request.Context(), glossary.ID, termID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// findGlossary loads the glossary of the :id path parameter.
+// On failure the error response is already written and ok is false.
+func (h *GlossaryHandler) findGlossary(c *gin.Context) (*glossaryctrl.Glossary, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid glossary ID"})
+		return nil, false
+	}
+
+	glossary, err := h.glossaryService.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if glossary == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Glossary not found"})
+		return nil, false
+	}
+
+	return glossary, true
+}
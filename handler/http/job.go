@@ -0,0 +1,71 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	jobctrl "raggo/src/infrastructure/job"
+)
+
+type JobHandler struct {
+	jobService *jobctrl.JobService
+}
+
+func NewJobHandler(jobService *jobctrl.JobService) (*JobHandler, error) {
+	return &JobHandler{
+		jobService: jobService,
+	}, nil
+}
+
+// GetJob returns the status and result of a background job
+func (h *JobHandler) GetJob(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := h.jobService.GetJob(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get job"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// RetryFailedChunks enqueues a translation job that translates only the chunks the given job failed on
+func (h *JobHandler) RetryFailedChunks(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := h.jobService.RetryFailedChunks(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, jobctrl.ErrNotRetryable) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry job"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"jobId":   strconv.Itoa(job.ID),
+		"status":  "accepted",
+		"message": "Retry of failed chunks created successfully",
+	})
+}
@@ -1,27 +1,61 @@
 package http
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"raggo/src/core/knowledgebase"
+	"raggo/src/core/queryexpansion"
 )
 
+// eventSources carries the sources of a streamed answer before its first delta
+const eventSources = "sources"
+
+// retrievalRequest holds the retrieval options every request searching a knowledge base may override
+type retrievalRequest struct {
+	// TranslationModel overrides the configured model translating the query; "none" searches without translation
+	TranslationModel string `json:"translation_model"`
+	// Expansion overrides the configured query expansion mode: none, hyde, multi_query or step_back
+	Expansion      string `json:"expansion"`
+	ExpansionModel string `json:"expansion_model"`
+	// Context overrides the configured context expansion mode: none, neighbors or page
+	Context   string `json:"context"`
+	Neighbors int    `json:"neighbors"`
+}
+
 type KnowledgeBaseHandler struct {
-	service *knowledgebase.Service
+	service           *knowledgebase.Service
+	queryDefaults     knowledgebase.QueryOptions // retrieval options of requests that do not override them
+	answerModel       string                     // default model answering questions
+	answerTokenBudget int                        // default estimated tokens of the prompt given to the answer model
 }
 
-func NewKnowledgeBaseHandler(service *knowledgebase.Service) (*KnowledgeBaseHandler, error) {
+func NewKnowledgeBaseHandler(service *knowledgebase.Service, queryDefaults knowledgebase.QueryOptions, answerModel string, answerTokenBudget int) (*KnowledgeBaseHandler, error) {
+	if !queryexpansion.Valid(queryDefaults.Expansion) {
+		return nil, fmt.Errorf("unknown query expansion mode: %s", queryDefaults.Expansion)
+	}
+	if !knowledgebase.ValidContext(queryDefaults.Context) {
+		return nil, fmt.Errorf("unknown context expansion mode: %s", queryDefaults.Context)
+	}
+
 	return &KnowledgeBaseHandler{
-		service: service,
+		service:           service,
+		queryDefaults:     queryDefaults,
+		answerModel:       answerModel,
+		answerTokenBudget: answerTokenBudget,
 	}, nil
 }
 
 // ListKnowledgeBases handles GET /api/v1/knowledge-bases
 func (h *KnowledgeBaseHandler) ListKnowledgeBases(c *gin.Context) {
-	offset, limit := getPaginationParams(c)
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 
 	bases, err := h.service.ListKnowledgeBases(c.Request.Context(), offset, limit)
 	if err != nil {
@@ -44,7 +78,10 @@ func (h *KnowledgeBaseHandler) ListKnowledgeBaseResources(c *gin.Context) {
 		return
 	}
 
-	offset, limit := getPaginationParams(c)
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 
 	resources, err := h.service.ListKnowledgeBaseResources(c.Request.Context(), id, offset, limit)
 	if err != nil {
@@ -69,13 +106,46 @@ func (h *KnowledgeBaseHandler) QueryKnowledgeBase(c *gin.Context) {
 
 	var req struct {
 		Query string `json:"query" binding:"required"`
+		retrievalRequest
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	opts, ok := h.queryOptions(c, req.retrievalRequest)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.QueryKnowledgeBase(c.Request.Context(), id, req.Query, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+// QueryKnowledgeBases handles POST /api/v1/knowledge-bases/query. It searches several knowledge bases at once and
+// returns one list ranked by normalized score, every result tagged with the knowledge base it was found in.
+func (h *KnowledgeBaseHandler) QueryKnowledgeBases(c *gin.Context) {
+	var req struct {
+		KnowledgeBaseIDs []int64 `json:"knowledge_base_ids" binding:"required,min=1"`
+		Query            string  `json:"query" binding:"required"`
+		retrievalRequest
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	result, err := h.service.QueryKnowledgeBase(c.Request.Context(), id, req.Query)
+	opts, ok := h.queryOptions(c, req.retrievalRequest)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.QueryKnowledgeBases(c.Request.Context(), req.KnowledgeBaseIDs, req.Query, opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -84,6 +154,115 @@ func (h *KnowledgeBaseHandler) QueryKnowledgeBase(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"result": result})
 }
 
+// AnswerKnowledgeBase handles POST /api/v1/knowledge-bases/:id/answer. The answer is streamed as server-sent events:
+// a sources event lists the numbered chunks given to the model, delta events carry the answer as it is generated
+// with inline citations such as [1], and a final event with the complete answer and its citations or an error event
+// ends the stream.
+func (h *KnowledgeBaseHandler) AnswerKnowledgeBase(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	var req struct {
+		Question string `json:"question" binding:"required"`
+		// Model and TokenBudget override the configured answer model and the estimated tokens of its prompt
+		Model       string `json:"model"`
+		TokenBudget int    `json:"token_budget"`
+		retrievalRequest
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	opts, ok := h.answerOptions(c, req.Model, req.TokenBudget, req.retrievalRequest)
+	if !ok {
+		return
+	}
+
+	streamEvents(c, func(send func(name string, data interface{})) {
+		opts.OnSources = func(sources []knowledgebase.Source) {
+			send(eventSources, gin.H{"sources": sources})
+		}
+		opts.OnToken = func(token string) {
+			send(eventDelta, gin.H{"text": token})
+		}
+
+		answer, err := h.service.Answer(c.Request.Context(), id, req.Question, opts)
+		if err != nil {
+			send(eventError, gin.H{"error": err.Error()})
+			return
+		}
+		send(eventFinal, gin.H{"answer": answer.Answer, "citations": answer.Citations})
+	})
+}
+
+// answerOptions applies the model, token budget and retrieval options of a request to the configured ones.
+// It writes the error response itself and reports false if they are invalid.
+func (h *KnowledgeBaseHandler) answerOptions(c *gin.Context, model string, tokenBudget int, retrieval retrievalRequest) (knowledgebase.AnswerOptions, bool) {
+	if tokenBudget < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token budget must not be negative"})
+		return knowledgebase.AnswerOptions{}, false
+	}
+	queryOpts, ok := h.queryOptions(c, retrieval)
+	if !ok {
+		return knowledgebase.AnswerOptions{}, false
+	}
+
+	opts := knowledgebase.AnswerOptions{
+		Model:       h.answerModel,
+		TokenBudget: h.answerTokenBudget,
+		Query:       queryOpts,
+	}
+	if model != "" {
+		opts.Model = model
+	}
+	if tokenBudget > 0 {
+		opts.TokenBudget = tokenBudget
+	}
+	return opts, true
+}
+
+// queryOptions applies the retrieval options of a request to the configured ones.
+// It writes the error response itself and reports false if they are invalid.
+func (h *KnowledgeBaseHandler) queryOptions(c *gin.Context, retrieval retrievalRequest) (knowledgebase.QueryOptions, bool) {
+	if !queryexpansion.Valid(retrieval.Expansion) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown query expansion mode, use one of " + strings.Join(queryexpansion.Modes, ", ")})
+		return knowledgebase.QueryOptions{}, false
+	}
+	if !knowledgebase.ValidContext(retrieval.Context) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown context expansion mode, use one of none, neighbors, page"})
+		return knowledgebase.QueryOptions{}, false
+	}
+	if retrieval.Neighbors < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Neighbors must not be negative"})
+		return knowledgebase.QueryOptions{}, false
+	}
+
+	opts := h.queryDefaults
+	switch retrieval.TranslationModel {
+	case "":
+	case "none":
+		opts.TranslationModel = ""
+	default:
+		opts.TranslationModel = retrieval.TranslationModel
+	}
+	if retrieval.Expansion != "" {
+		opts.Expansion = retrieval.Expansion
+	}
+	if retrieval.ExpansionModel != "" {
+		opts.ExpansionModel = retrieval.ExpansionModel
+	}
+	if retrieval.Context != "" {
+		opts.Context = retrieval.Context
+	}
+	if retrieval.Neighbors > 0 {
+		opts.Neighbors = retrieval.Neighbors
+	}
+	return opts, true
+}
+
 // AddResourceToKnowledgeBase handles POST /api/v1/knowledge-bases/:id/resources
 func (h *KnowledgeBaseHandler) AddResourceToKnowledgeBase(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
@@ -93,14 +272,19 @@ func (h *KnowledgeBaseHandler) AddResourceToKnowledgeBase(c *gin.Context) {
 	}
 
 	var req struct {
-		ResourceID int64 `json:"resource_id" binding:"required"`
+		ResourceID          int64  `json:"resource_id" binding:"required"`
+		TableSummaryModel   string `json:"table_summary_model"`
+		IncludeTranslations bool   `json:"include_translations"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	err = h.service.AddResourceToKnowledgeBase(c.Request.Context(), id, req.ResourceID)
+	err = h.service.AddResourceToKnowledgeBase(c.Request.Context(), id, req.ResourceID, knowledgebase.AddResourceOptions{
+		TableSummaryModel:   req.TableSummaryModel,
+		IncludeTranslations: req.IncludeTranslations,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -125,17 +309,3 @@ func (h *KnowledgeBaseHandler) ResetWeaviateContent(c *gin.Context) {
 
 	c.Status(http.StatusOK)
 }
-
-func getPaginationParams(c *gin.Context) (offset, limit int) {
-	offset, _ = strconv.Atoi(c.Query("offset"))
-	limit, _ = strconv.Atoi(c.Query("limit"))
-
-	if limit <= 0 {
-		limit = 10 // default limit
-	}
-	if offset < 0 {
-		offset = 0
-	}
-
-	return offset, limit
-}
@@ -0,0 +1,36 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100 // larger limits are lowered to this
+)
+
+// parsePagination reads the limit and offset query parameters of a list route, capping the limit at maxPageLimit.
+// It writes the error response itself and reports false if either is not a number, the limit is below 1
+// or the offset is negative.
+func parsePagination(c *gin.Context) (limit, offset int, ok bool) {
+	limit = defaultPageLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if _, err := fmt.Sscanf(limitParam, "%d", &limit); err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter, expected a number of at least 1"})
+			return 0, 0, false
+		}
+	}
+	limit = min(limit, maxPageLimit)
+
+	if offsetParam := c.Query("offset"); offsetParam != "" {
+		if _, err := fmt.Sscanf(offsetParam, "%d", &offset); err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter, expected a number of at least 0"})
+			return 0, 0, false
+		}
+	}
+
+	return limit, offset, true
+}
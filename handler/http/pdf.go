@@ -6,10 +6,12 @@ import (
 	"io"
 	"net/http"
 	"path/filepath"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"raggo/src/storage/contenthash"
 	"raggo/src/storage/minioctrl"
 	"raggo/src/storage/postgres/resourcectrl"
 )
@@ -37,22 +39,9 @@ func NewPDFHandler(minioService *minioctrl.MinioService, bucketName string, mini
 }
 
 func (h *PDFHandler) List(c *gin.Context) {
-	// Parse query parameters with defaults
-	limit := 10 // default limit
-	offset := 0 // default offset
-
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if _, err := fmt.Sscanf(limitParam, "%d", &limit); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
-			return
-		}
-	}
-
-	if offsetParam := c.Query("offset"); offsetParam != "" {
-		if _, err := fmt.Sscanf(offsetParam, "%d", &offset); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
-			return
-		}
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
 	}
 
 	// Get resources from service
@@ -72,45 +61,35 @@ func (h *PDFHandler) List(c *gin.Context) {
 }
 
 func (h *PDFHandler) Upload(c *gin.Context) {
-	// Get file from request
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+	filename, fileBytes, ok := readUploadedPDF(c)
+	if !ok {
 		return
 	}
-	defer file.Close()
 
-	// Validate file type
-	if filepath.Ext(header.Filename) != ".pdf" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Only PDF files are allowed"})
+	// Reject duplicates of an already uploaded file
+	contentHash := contenthash.Sum(fileBytes)
+	existing, err := h.resourceService.GetByContentHash(c.Request.Context(), contentHash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check for duplicate resource"})
 		return
 	}
-
-	// Generate unique file name
-	id := uuid.New().String()
-	objectName := fmt.Sprintf("%s.pdf", id)
-
-	// Read file into buffer
-	fileBytes, err := io.ReadAll(file)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "Resource with identical content already exists",
+			"id":       existing.ID,
+			"filename": existing.Filename,
+		})
 		return
 	}
 
-	// Upload to MinIO
-	err = h.minioService.PutObject(
-		context.Background(),
-		h.bucketName,
-		objectName,
-		fileBytes,
-	)
+	minioURL, err := h.storeFile(fileBytes)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file"})
 		return
 	}
 
 	// Create resource record
-	resource, err := h.resourceService.Create(c.Request.Context(), header.Filename, fmt.Sprintf("%s/%s", h.bucketName, objectName))
+	resource, err := h.resourceService.Create(c.Request.Context(), filename, minioURL, contentHash)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record resource"})
 		return
@@ -122,3 +101,146 @@ func (h *PDFHandler) Upload(c *gin.Context) {
 		"filename": resource.Filename,
 	})
 }
+
+// UploadVersion replaces the file of an existing resource with a new version.
+// The previous files are kept in MinIO so older versions stay listed; a following
+// conversion only re-processes the chunks whose content changed.
+func (h *PDFHandler) UploadVersion(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resource ID"})
+		return
+	}
+
+	resource, err := h.resourceService.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get resource"})
+		return
+	}
+	if resource == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+
+	filename, fileBytes, ok := readUploadedPDF(c)
+	if !ok {
+		return
+	}
+
+	contentHash := contenthash.Sum(fileBytes)
+	if contentHash == resource.ContentHash {
+		c.JSON(http.StatusOK, gin.H{
+			"id":        resource.ID,
+			"filename":  resource.Filename,
+			"version":   resource.Version,
+			"unchanged": true,
+		})
+		return
+	}
+
+	// The new content must not duplicate another resource
+	existing, err := h.resourceService.GetByContentHash(c.Request.Context(), contentHash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check for duplicate resource"})
+		return
+	}
+	if existing != nil && existing.ID != resource.ID {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "Resource with identical content already exists",
+			"id":       existing.ID,
+			"filename": existing.Filename,
+		})
+		return
+	}
+
+	minioURL, err := h.storeFile(fileBytes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file"})
+		return
+	}
+
+	resource, err = h.resourceService.AddVersion(c.Request.Context(), resource.ID, filename, minioURL, contentHash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record resource version"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":        resource.ID,
+		"filename":  resource.Filename,
+		"version":   resource.Version,
+		"unchanged": false,
+	})
+}
+
+// ListVersions returns every uploaded version of a resource, newest first
+func (h *PDFHandler) ListVersions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resource ID"})
+		return
+	}
+
+	resource, err := h.resourceService.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get resource"})
+		return
+	}
+	if resource == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+
+	versions, err := h.resourceService.ListVersions(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list resource versions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":       resource.ID,
+		"version":  resource.Version,
+		"versions": versions,
+	})
+}
+
+// readUploadedPDF reads the "file" form field and validates it is a PDF.
+// On failure the error response is already written and ok is false.
+func readUploadedPDF(c *gin.Context) (filename string, fileBytes []byte, ok bool) {
+	// Get file from request
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return "", nil, false
+	}
+	defer file.Close()
+
+	// Validate file type
+	if filepath.Ext(header.Filename) != ".pdf" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only PDF files are allowed"})
+		return "", nil, false
+	}
+
+	// Read file into buffer
+	fileBytes, err = io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return "", nil, false
+	}
+
+	return header.Filename, fileBytes, true
+}
+
+// storeFile uploads the file under a unique name and returns its MinIO URL
+func (h *PDFHandler) storeFile(fileBytes []byte) (string, error) {
+	// Generate unique file name
+	id := uuid.New().String()
+	objectName := fmt.Sprintf("%s.pdf", id)
+
+	// Upload to MinIO
+	if err := h.minioService.PutObject(context.Background(), h.bucketName, objectName, fileBytes); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/%s", h.bucketName, objectName), nil
+}
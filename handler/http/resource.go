@@ -0,0 +1,449 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"raggo/src/core/resourcedeletion"
+	"raggo/src/core/translationexport"
+	jobctrl "raggo/src/infrastructure/job"
+	"raggo/src/storage/minioctrl"
+	"raggo/src/storage/postgres/chunkctrl"
+	"raggo/src/storage/postgres/resourcectrl"
+	"raggo/src/storage/postgres/translatedchunkctrl"
+	"raggo/src/storage/postgres/translatedresourcectrl"
+)
+
+type ResourceHandler struct {
+	minioService            *minioctrl.MinioService
+	minioDomain             string
+	resourceService         *resourcectrl.ResourceService
+	chunkService            *chunkctrl.ChunkService
+	resourceDeletionService *resourcedeletion.Service
+	jobService              *jobctrl.JobService
+	translatedResourceSvc   *translatedresourcectrl.TranslatedResourceService
+	translatedChunkSvc      *translatedchunkctrl.TranslatedChunkService
+}
+
+func NewResourceHandler(
+	minioService *minioctrl.MinioService,
+	minioDomain string,
+	resourceService *resourcectrl.ResourceService,
+	chunkService *chunkctrl.ChunkService,
+	resourceDeletionService *resourcedeletion.Service,
+	jobService *jobctrl.JobService,
+	translatedResourceSvc *translatedresourcectrl.TranslatedResourceService,
+	translatedChunkSvc *translatedchunkctrl.TranslatedChunkService,
+) (*ResourceHandler, error) {
+	return &ResourceHandler{
+		minioService:            minioService,
+		minioDomain:             minioDomain,
+		resourceService:         resourceService,
+		chunkService:            chunkService,
+		resourceDeletionService: resourceDeletionService,
+		jobService:              jobService,
+		translatedResourceSvc:   translatedResourceSvc,
+		translatedChunkSvc:      translatedChunkSvc,
+	}, nil
+}
+
+// ChunkContent is a chunk of a resource together with its content
+type ChunkContent struct {
+	ID            int64  `json:"id"`
+	ChunkID       string `json:"chunkId"`
+	Order         int    `json:"order"`
+	PageNumber    int    `json:"pageNumber,omitempty"`
+	Kind          string `json:"kind"`
+	Content       string `json:"content"`
+	TableHTML     string `json:"tableHtml,omitempty"`
+	TableMarkdown string `json:"tableMarkdown,omitempty"`
+	ImageURL      string `json:"imageUrl,omitempty"`
+	Language      string `json:"language,omitempty"` // detected by a translation with an automatic source language
+}
+
+// Translation is a translation variant of a resource, identified by target language, country and model
+type Translation struct {
+	ID               int64     `json:"id"`
+	Filename         string    `json:"filename"`
+	SourceLanguage   string    `json:"sourceLanguage"`
+	TargetLanguage   string    `json:"targetLanguage"`
+	Country          string    `json:"country"`
+	Model            string    `json:"model"`
+	TranslatedChunks int64     `json:"translatedChunks"`
+	TotalChunks      int       `json:"totalChunks"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// GetText returns the full document reassembled on conversion, as plain text or with format=markdown as Markdown
+func (h *ResourceHandler) GetText(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resource ID"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "text")
+	if format != "text" && format != "markdown" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format parameter, expected text or markdown"})
+		return
+	}
+
+	resource, err := h.resourceService.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get resource"})
+		return
+	}
+	if resource == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+
+	minioURL, contentType := resource.TextMinioURL, "text/plain; charset=utf-8"
+	if format == "markdown" {
+		minioURL, contentType = resource.MarkdownMinioURL, "text/markdown; charset=utf-8"
+	}
+	if minioURL == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource has not been converted yet"})
+		return
+	}
+
+	bucket, objectName := h.minioService.GetBucketAndObjectFromURL(minioURL)
+	content, err := h.minioService.GetObject(c.Request.Context(), bucket, objectName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read document"})
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, content)
+}
+
+// ListChunks returns a page of the chunks of a resource in document order with their content
+func (h *ResourceHandler) ListChunks(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resource ID"})
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	resource, err := h.resourceService.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get resource"})
+		return
+	}
+	if resource == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+
+	chunks, total, err := h.chunkService.ListByResourceID(c.Request.Context(), id, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list chunks"})
+		return
+	}
+
+	contents := make([]ChunkContent, 0, len(chunks))
+	for _, chunk := range chunks {
+		content, err := h.chunkContent(c.Request.Context(), chunk)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read chunk content"})
+			return
+		}
+		contents = append(contents, *content)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"chunks": contents,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"total":  total,
+		},
+	})
+}
+
+// ListTranslations returns every translation variant of the resource with its progress
+func (h *ResourceHandler) ListTranslations(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resource ID"})
+		return
+	}
+
+	resource, err := h.resourceService.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get resource"})
+		return
+	}
+	if resource == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+
+	translatedResources, err := h.translatedResourceSvc.GetByOriginalID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list translations"})
+		return
+	}
+
+	chunks, err := h.chunkService.GetByResourceID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get chunks"})
+		return
+	}
+
+	ids := make([]int64, len(translatedResources))
+	for i, tr := range translatedResources {
+		ids[i] = tr.ID
+	}
+	counts, err := h.translatedChunkSvc.CountByTranslatedResourceIDs(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count translated chunks"})
+		return
+	}
+
+	translations := make([]Translation, 0, len(translatedResources))
+	for _, tr := range translatedResources {
+		translations = append(translations, Translation{
+			ID:               tr.ID,
+			Filename:         tr.Filename,
+			SourceLanguage:   tr.SourceLanguage,
+			TargetLanguage:   tr.TargetLanguage,
+			Country:          tr.Country,
+			Model:            tr.Model,
+			TranslatedChunks: counts[tr.ID],
+			TotalChunks:      len(chunks),
+			CreatedAt:        tr.CreatedAt,
+			UpdatedAt:        tr.UpdatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"translations": translations,
+	})
+}
+
+// DownloadTranslation returns a translation variant as a file. The format query parameter selects plain text,
+// Markdown, bilingual Markdown or HTML with source and translation side by side, XLIFF 2.0 or TMX for CAT tools,
+// or SRT and WebVTT when the source is a subtitle file.
+func (h *ResourceHandler) DownloadTranslation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid translation ID"})
+		return
+	}
+
+	format := c.DefaultQuery("format", translationexport.FormatMarkdown)
+	if !translationexport.IsValidFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format parameter, expected text, markdown, bilingual-markdown, bilingual-html, xliff, tmx, srt or vtt"})
+		return
+	}
+
+	translatedResource, err := h.translatedResourceSvc.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get translation"})
+		return
+	}
+	if translatedResource == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Translation not found"})
+		return
+	}
+
+	doc, err := h.translationDocument(c.Request.Context(), translatedResource)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read translation"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := translationexport.Write(&buf, doc, format); err != nil {
+		if errors.Is(err, translationexport.ErrNotSubtitles) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Subtitle formats are only available for subtitle sources"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export translation"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", translationexport.Filename(doc, format)))
+	c.Data(http.StatusOK, translationexport.ContentType(format), buf.Bytes())
+}
+
+// translationDocument aligns the chunks of the source resource, in document order, with their translation
+func (h *ResourceHandler) translationDocument(ctx context.Context, tr *translatedresourcectrl.TranslatedResource) (*translationexport.Document, error) {
+	chunks, err := h.chunkService.GetByResourceID(ctx, tr.OriginalResourceID)
+	if err != nil {
+		return nil, err
+	}
+	translatedChunks, err := h.translatedChunkSvc.GetByTranslatedResourceID(ctx, tr.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Rejected translations count as untranslated, approved revisions replace the machine draft
+	translatedURLs := make(map[int64]string, len(translatedChunks))
+	for _, translatedChunk := range translatedChunks {
+		if translatedChunk.IsRejected() {
+			continue
+		}
+		translatedURLs[translatedChunk.OriginalChunkID] = translatedChunk.FinalMinioURL()
+	}
+
+	read := func(minioURL string) (string, error) {
+		bucket, objectName := h.minioService.GetBucketAndObjectFromURL(minioURL)
+		content, err := h.minioService.GetObject(ctx, bucket, objectName)
+		return string(content), err
+	}
+
+	doc := &translationexport.Document{
+		Name:           tr.Filename,
+		SourceLanguage: tr.SourceLanguage,
+		TargetLanguage: tr.TargetLanguage,
+		Country:        tr.Country,
+		Model:          tr.Model,
+		Segments:       make([]translationexport.Segment, 0, len(chunks)),
+	}
+	for _, chunk := range chunks {
+		source, err := read(chunk.MinioURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chunk %s: %v", chunk.ChunkID, err)
+		}
+		segment := translationexport.Segment{ID: chunk.ChunkID, Kind: chunk.Kind, Source: source}
+		if minioURL, ok := translatedURLs[chunk.ID]; ok {
+			if segment.Target, err = read(minioURL); err != nil {
+				return nil, fmt.Errorf("failed to read translated chunk %s: %v", chunk.ChunkID, err)
+			}
+		}
+		doc.Segments = append(doc.Segments, segment)
+	}
+	return doc, nil
+}
+
+// chunkContent loads the content of a chunk and the representations stored next to it from MinIO
+func (h *ResourceHandler) chunkContent(ctx context.Context, chunk chunkctrl.Chunk) (*ChunkContent, error) {
+	read := func(minioURL string) (string, error) {
+		if minioURL == "" {
+			return "", nil
+		}
+		bucket, objectName := h.minioService.GetBucketAndObjectFromURL(minioURL)
+		content, err := h.minioService.GetObject(ctx, bucket, objectName)
+		return string(content), err
+	}
+
+	content, err := read(chunk.MinioURL)
+	if err != nil {
+		return nil, err
+	}
+	tableHTML, err := read(chunk.TableHTMLMinioURL)
+	if err != nil {
+		return nil, err
+	}
+	tableMarkdown, err := read(chunk.TableMarkdownMinioURL)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ChunkContent{
+		ID:            chunk.ID,
+		ChunkID:       chunk.ChunkID,
+		Order:         chunk.Order,
+		PageNumber:    chunk.PageNumber,
+		Kind:          chunk.Kind,
+		Content:       content,
+		TableHTML:     tableHTML,
+		TableMarkdown: tableMarkdown,
+		Language:      chunk.Language,
+	}
+	if chunk.IsImage() {
+		result.ImageURL = fmt.Sprintf("%s/%s", h.minioDomain, chunk.ImageMinioURL)
+	}
+	return result, nil
+}
+
+// Delete removes a resource and everything derived from it.
+// With dryRun=true only the deletion plan is returned, with async=true the deletion runs as a background job.
+func (h *ResourceHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resource ID"})
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dryRun", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dryRun parameter"})
+		return
+	}
+	async, err := strconv.ParseBool(c.DefaultQuery("async", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid async parameter"})
+		return
+	}
+
+	plan, err := h.resourceDeletionService.Plan(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to plan resource deletion"})
+		return
+	}
+	if plan == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"dryRun": true,
+			"plan":   plan,
+		})
+		return
+	}
+
+	if async {
+		payloadBytes, err := json.Marshal(jobctrl.ResourceDeletionPayload{ResourceID: id})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal job payload"})
+			return
+		}
+
+		job, err := h.jobService.EnqueueJob(c.Request.Context(), jobctrl.TaskTypeResourceDeletion, payloadBytes)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enqueue resource deletion job"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"jobId":   strconv.Itoa(job.ID),
+			"status":  "accepted",
+			"message": "Resource deletion job created successfully",
+			"plan":    plan,
+		})
+		return
+	}
+
+	plan, err = h.resourceDeletionService.Delete(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete resource"})
+		return
+	}
+	if plan == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dryRun": false,
+		"plan":   plan,
+	})
+}
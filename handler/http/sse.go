@@ -0,0 +1,48 @@
+package http
+
+import (
+	"io"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Names of the server-sent events every stream shares
+const (
+	eventDelta = "delta" // a piece of the text being generated
+	eventFinal = "final" // the complete result, the last event
+	eventError = "error" // the request failed, the last event
+)
+
+type serverSentEvent struct {
+	name string
+	data interface{}
+}
+
+// streamEvents runs the producer in the background and writes the events it sends to the response as server-sent
+// events until it returns. Once the client is gone nobody receives the events and send returns right away.
+func streamEvents(c *gin.Context, produce func(send func(name string, data interface{}))) {
+	ctx := c.Request.Context()
+	events := make(chan serverSentEvent)
+	send := func(name string, data interface{}) {
+		select {
+		case events <- serverSentEvent{name: name, data: data}:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		defer close(events)
+		produce(send)
+	}()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		event, ok := <-events
+		if !ok {
+			return false
+		}
+		c.SSEvent(event.name, event.data)
+		return true
+	})
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"raggo/src/storage/postgres/styleguidectrl"
+)
+
+type StyleGuideHandler struct {
+	styleGuideService *styleguidectrl.StyleGuideService
+}
+
+func NewStyleGuideHandler(styleGuideService *styleguidectrl.StyleGuideService) (*StyleGuideHandler, error) {
+	return &StyleGuideHandler{
+		styleGuideService: styleGuideService,
+	}, nil
+}
+
+// SaveStyleGuide handles PUT /api/v1/style-guides and creates or replaces the style guide of a target locale.
+// Leaving country empty makes the style guide the default for every country of the target language.
+func (h *StyleGuideHandler) SaveStyleGuide(c *gin.Context) {
+	var req struct {
+		TargetLanguage string `json:"target_language" binding:"required"`
+		Country        string `json:"country"`
+		Formality      string `json:"formality" binding:"omitempty,oneof=formal informal"`
+		Tone           string `json:"tone"`
+		Instructions   string `json:"instructions"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	styleGuide, err := h.styleGuideService.Save(c.Request.Context(), req.TargetLanguage, req.Country, req.Formality, req.Tone, req.Instructions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, styleGuide)
+}
+
+// ListStyleGuides handles GET /api/v1/style-guides
+func (h *StyleGuideHandler) ListStyleGuides(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	styleGuides, err := h.styleGuideService.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":  styleGuides,
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// DeleteStyleGuide handles DELETE /api/v1/style-guides/:id
+func (h *StyleGuideHandler) DeleteStyleGuide(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid style guide ID"})
+		return
+	}
+
+	styleGuide, err := h.styleGuideService.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if styleGuide == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Style guide not found"})
+		return
+	}
+
+	if err := h.styleGuideService.Delete(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
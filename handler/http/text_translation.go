@@ -0,0 +1,216 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"raggo/src/core/languagedetect"
+	"raggo/src/core/translationflow"
+	"raggo/src/infrastructure/integrations/ollama"
+	"raggo/src/storage/postgres/glossaryctrl"
+	"raggo/src/storage/postgres/styleguidectrl"
+	"raggo/src/storage/postgres/translationprofilectrl"
+)
+
+// TextTranslationRequest is an ad-hoc translation of text that is not stored as a resource
+type TextTranslationRequest struct {
+	Text           string `json:"text" binding:"required"`
+	SourceLanguage string `json:"source_language" binding:"required"` // "auto" detects the language of the text
+	TargetLanguage string `json:"target_language" binding:"required"`
+	Country        string `json:"country"`
+	Model          string `json:"model" binding:"required"`
+	GlossaryID     int64  `json:"glossary_id"`
+	// Optional pipeline of the translation stages, given inline or by the name of a stored profile
+	Profile  string                          `json:"profile"`
+	Pipeline *translationflow.PipelineConfig `json:"pipeline"`
+}
+
+// stageEvents names the event that carries the complete answer of a stage
+var stageEvents = map[string]string{
+	translationflow.StageInitial:     "draft",
+	translationflow.StageReflection:  "reflection",
+	translationflow.StageImprovement: "improvement",
+}
+
+type TextTranslationHandler struct {
+	ollamaClient      *ollama.Client
+	glossaryService   *glossaryctrl.GlossaryService
+	styleGuideService *styleguidectrl.StyleGuideService
+	profileService    *translationprofilectrl.TranslationProfileService
+	templateOverrides map[string]string // overrides of every pipeline, e.g. loaded from a template directory
+	ollamaLimiter     *translationflow.Limiter
+}
+
+func NewTextTranslationHandler(
+	ollamaClient *ollama.Client,
+	glossaryService *glossaryctrl.GlossaryService,
+	styleGuideService *styleguidectrl.StyleGuideService,
+	profileService *translationprofilectrl.TranslationProfileService,
+	templateOverrides map[string]string,
+	ollamaLimiter *translationflow.Limiter,
+) (*TextTranslationHandler, error) {
+	return &TextTranslationHandler{
+		ollamaClient:      ollamaClient,
+		glossaryService:   glossaryService,
+		styleGuideService: styleGuideService,
+		profileService:    profileService,
+		templateOverrides: templateOverrides,
+		ollamaLimiter:     ollamaLimiter,
+	}, nil
+}
+
+// Translate handles POST /api/v1/translate. The translation is streamed as server-sent events: delta events carry
+// the answer of the running stage as it is generated, draft, reflection and improvement events the complete answer
+// of each stage, and a final or error event ends the stream.
+func (h *TextTranslationHandler) Translate(c *gin.Context) {
+	var req TextTranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+
+	sourceLanguage := req.SourceLanguage
+	if sourceLanguage == languagedetect.Auto {
+		sourceLanguage = languagedetect.Detect(req.Text).Language
+		if sourceLanguage == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Could not detect the source language"})
+			return
+		}
+	}
+
+	pipeline, ok := h.pipeline(c, req)
+	if !ok {
+		return
+	}
+	flowOptions, ok := h.flowOptions(c, req, pipeline)
+	if !ok {
+		return
+	}
+
+	streamEvents(c, func(send func(name string, data interface{})) {
+		// Text already in the target language is returned as it is
+		if languagedetect.Same(sourceLanguage, req.TargetLanguage) {
+			send(eventFinal, gin.H{"translation": req.Text, "source_language": sourceLanguage})
+			return
+		}
+
+		flowOptions = append(flowOptions, translationflow.WithEventHandler(func(event translationflow.Event) {
+			stage, ok := stageEvents[event.Stage]
+			if !ok {
+				return
+			}
+			if event.Done {
+				send(stage, gin.H{"part": event.Part, "text": event.Text})
+			} else {
+				send(eventDelta, gin.H{"stage": stage, "part": event.Part, "text": event.Delta})
+			}
+		}))
+
+		provider := ollama.NewOllamaProvider(h.ollamaClient, req.Model)
+		flow := translationflow.NewTranslationFlow(provider, flowOptions...)
+		translation, err := flow.Translate(ctx, req.Text, sourceLanguage, req.TargetLanguage, req.Country)
+		if err != nil {
+			send(eventError, gin.H{"error": err.Error()})
+			return
+		}
+		send(eventFinal, gin.H{"translation": translation, "source_language": sourceLanguage})
+	})
+}
+
+// pipeline returns the pipeline of the request, the pipeline of its profile or the default pipeline.
+// It writes the error response itself and reports false if there is no valid pipeline.
+func (h *TextTranslationHandler) pipeline(c *gin.Context, req TextTranslationRequest) (*translationflow.PipelineConfig, bool) {
+	if req.Pipeline != nil {
+		if err := req.Pipeline.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		return req.Pipeline, true
+	}
+	if req.Profile == "" {
+		return &translationflow.PipelineConfig{}, true
+	}
+
+	profile, err := h.profileService.GetByName(c.Request.Context(), req.Profile)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get translation profile"})
+		return nil, false
+	}
+	if profile == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Translation profile not found"})
+		return nil, false
+	}
+	return &profile.Pipeline, true
+}
+
+// flowOptions configures the translation flow like a translation job with the same glossary, style guide and pipeline.
+// It writes the error response itself and reports false if the flow cannot be configured.
+func (h *TextTranslationHandler) flowOptions(c *gin.Context, req TextTranslationRequest, pipeline *translationflow.PipelineConfig) ([]translationflow.Option, bool) {
+	ctx := c.Request.Context()
+
+	glossary, ok := h.glossary(c, req.GlossaryID)
+	if !ok {
+		return nil, false
+	}
+	styleGuide, err := h.styleGuideService.Load(ctx, req.TargetLanguage, req.Country)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load style guide"})
+		return nil, false
+	}
+
+	overrides := make(map[string]string, len(h.templateOverrides)+len(pipeline.Templates))
+	for name, text := range h.templateOverrides {
+		overrides[name] = text
+	}
+	for name, text := range pipeline.Templates {
+		overrides[name] = text
+	}
+	templates, err := translationflow.NewTemplates(overrides)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	flowOptions := []translationflow.Option{
+		translationflow.WithGlossary(glossary),
+		translationflow.WithStyleGuide(styleGuide),
+		translationflow.WithTemplates(templates),
+		translationflow.WithSkipReflection(pipeline.SkipReflection),
+		translationflow.WithLimiter(h.ollamaLimiter),
+	}
+	if pipeline.ReviewRounds > 0 {
+		flowOptions = append(flowOptions, translationflow.WithReviewRounds(pipeline.ReviewRounds))
+	}
+	for stage, model := range pipeline.Models {
+		flowOptions = append(flowOptions, translationflow.WithStageProvider(stage, ollama.NewOllamaProvider(h.ollamaClient, model)))
+	}
+	return flowOptions, true
+}
+
+// glossary loads the glossary of the request, nil if none is requested.
+// It writes the error response itself and reports false if the glossary cannot be loaded.
+func (h *TextTranslationHandler) glossary(c *gin.Context, glossaryID int64) (*translationflow.Glossary, bool) {
+	if glossaryID == 0 {
+		return nil, true
+	}
+
+	ctx := c.Request.Context()
+	glossary, err := h.glossaryService.GetByID(ctx, glossaryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get glossary"})
+		return nil, false
+	}
+	if glossary == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Glossary not found"})
+		return nil, false
+	}
+
+	loaded, err := h.glossaryService.Load(ctx, glossaryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load glossary"})
+		return nil, false
+	}
+	return loaded, true
+}
@@ -0,0 +1,354 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"raggo/src/core/translationreview"
+	"raggo/src/storage/minioctrl"
+	"raggo/src/storage/postgres/chunkctrl"
+	"raggo/src/storage/postgres/translatedchunkctrl"
+	"raggo/src/storage/postgres/translatedresourcectrl"
+)
+
+type TranslatedChunkHandler struct {
+	minioService          *minioctrl.MinioService
+	chunkService          *chunkctrl.ChunkService
+	translatedResourceSvc *translatedresourcectrl.TranslatedResourceService
+	translatedChunkSvc    *translatedchunkctrl.TranslatedChunkService
+	reviewService         *translationreview.Service
+}
+
+func NewTranslatedChunkHandler(
+	minioService *minioctrl.MinioService,
+	chunkService *chunkctrl.ChunkService,
+	translatedResourceSvc *translatedresourcectrl.TranslatedResourceService,
+	translatedChunkSvc *translatedchunkctrl.TranslatedChunkService,
+	reviewService *translationreview.Service,
+) (*TranslatedChunkHandler, error) {
+	return &TranslatedChunkHandler{
+		minioService:          minioService,
+		chunkService:          chunkService,
+		translatedResourceSvc: translatedResourceSvc,
+		translatedChunkSvc:    translatedChunkSvc,
+		reviewService:         reviewService,
+	}, nil
+}
+
+// TranslatedChunkContent is a translated chunk with its source, machine translation, human revision,
+// review and estimated quality
+type TranslatedChunkContent struct {
+	ID                        int64      `json:"id"`
+	ChunkID                   string     `json:"chunkId"`
+	OriginalChunkID           int64      `json:"originalChunkId"`
+	Order                     int        `json:"order"`
+	Source                    string     `json:"source"`
+	Translation               string     `json:"translation"` // machine draft
+	Revision                  string     `json:"revision,omitempty"`
+	RevisedBy                 string     `json:"revisedBy,omitempty"`
+	RevisedAt                 *time.Time `json:"revisedAt,omitempty"`
+	ReviewStatus              string     `json:"reviewStatus"`
+	ReviewedBy                string     `json:"reviewedBy,omitempty"`
+	ReviewedAt                *time.Time `json:"reviewedAt,omitempty"`
+	ReviewComment             string     `json:"reviewComment,omitempty"`
+	QualityScore              *float64   `json:"qualityScore,omitempty"`
+	QualityAccuracy           *int       `json:"qualityAccuracy,omitempty"`
+	QualityFluency            *int       `json:"qualityFluency,omitempty"`
+	QualityTerminology        *int       `json:"qualityTerminology,omitempty"`
+	BackTranslationSimilarity *float64   `json:"backTranslationSimilarity,omitempty"`
+	QualityComment            string     `json:"qualityComment,omitempty"`
+	FlaggedForReview          bool       `json:"flaggedForReview"`
+	UpdatedAt                 time.Time  `json:"updatedAt"`
+}
+
+type FlagRequest struct {
+	Flagged *bool `json:"flagged" binding:"required"`
+}
+
+type RevisionRequest struct {
+	Translation string `json:"translation" binding:"required"`
+	Author      string `json:"author" binding:"required"`
+}
+
+type ReviewRequest struct {
+	Reviewer string `json:"reviewer" binding:"required"`
+	Comment  string `json:"comment"`
+}
+
+// ListChunks returns a page of the translated chunks of a translation in document order with their quality and review.
+// With flagged=true only the chunks flagged for human review are returned, with status only those of the review status.
+func (h *TranslatedChunkHandler) ListChunks(c *gin.Context) {
+	translatedResource, ok := h.getTranslatedResource(c)
+	if !ok {
+		return
+	}
+
+	flaggedOnly, err := strconv.ParseBool(c.DefaultQuery("flagged", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flagged parameter"})
+		return
+	}
+	filter := translatedchunkctrl.ListFilter{FlaggedOnly: flaggedOnly, ReviewStatus: c.Query("status")}
+	switch filter.ReviewStatus {
+	case "", translatedchunkctrl.ReviewStatusPending, translatedchunkctrl.ReviewStatusApproved, translatedchunkctrl.ReviewStatusRejected:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status parameter, expected pending, approved or rejected"})
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	chunks, total, err := h.translatedChunkSvc.ListByTranslatedResourceID(c.Request.Context(), translatedResource.ID, filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list translated chunks"})
+		return
+	}
+
+	contents := make([]TranslatedChunkContent, 0, len(chunks))
+	for _, chunk := range chunks {
+		content, err := h.chunkContent(c.Request.Context(), chunk)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read translated chunk content"})
+			return
+		}
+		contents = append(contents, *content)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"chunks": contents,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"total":  total,
+		},
+	})
+}
+
+// GetChunk returns a translated chunk with its source, machine translation, revision and review
+func (h *TranslatedChunkHandler) GetChunk(c *gin.Context) {
+	_, chunk, ok := h.getTranslatedChunk(c)
+	if !ok {
+		return
+	}
+	h.respondChunk(c, chunk)
+}
+
+// Revise stores the revision of a translator next to the machine draft. The chunk waits for approval again.
+func (h *TranslatedChunkHandler) Revise(c *gin.Context) {
+	_, chunk, ok := h.getTranslatedChunk(c)
+	if !ok {
+		return
+	}
+
+	var req RevisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chunk, err := h.reviewService.Revise(c.Request.Context(), chunk, req.Translation, req.Author)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save revision"})
+		return
+	}
+	h.respondChunk(c, chunk)
+}
+
+// Approve accepts a translated chunk and regenerates the translated resource
+func (h *TranslatedChunkHandler) Approve(c *gin.Context) {
+	translatedResource, chunk, ok := h.getTranslatedChunk(c)
+	if !ok {
+		return
+	}
+
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chunk, err := h.reviewService.Approve(c.Request.Context(), translatedResource, chunk, req.Reviewer, req.Comment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve translated chunk"})
+		return
+	}
+	h.respondChunk(c, chunk)
+}
+
+// Reject discards a translated chunk, which is translated again by the next run of the translation job
+func (h *TranslatedChunkHandler) Reject(c *gin.Context) {
+	translatedResource, chunk, ok := h.getTranslatedChunk(c)
+	if !ok {
+		return
+	}
+
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chunk, err := h.reviewService.Reject(c.Request.Context(), translatedResource, chunk, req.Reviewer, req.Comment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject translated chunk"})
+		return
+	}
+	h.respondChunk(c, chunk)
+}
+
+// Regenerate rebuilds the translated resource from the reviewed chunks
+func (h *TranslatedChunkHandler) Regenerate(c *gin.Context) {
+	translatedResource, ok := h.getTranslatedResource(c)
+	if !ok {
+		return
+	}
+
+	if err := h.reviewService.Regenerate(c.Request.Context(), translatedResource); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate translation"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":      translatedResource.ID,
+		"message": "Translation regenerated successfully",
+	})
+}
+
+// Flag flags a translated chunk for human review or clears its flag
+func (h *TranslatedChunkHandler) Flag(c *gin.Context) {
+	_, chunk, ok := h.getTranslatedChunk(c)
+	if !ok {
+		return
+	}
+
+	var req FlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.translatedChunkSvc.SetFlagged(c.Request.Context(), chunk.ID, *req.Flagged); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update translated chunk"})
+		return
+	}
+	chunk.FlaggedForReview = *req.Flagged
+	h.respondChunk(c, chunk)
+}
+
+// respondChunk writes the translated chunk with its content
+func (h *TranslatedChunkHandler) respondChunk(c *gin.Context, chunk *translatedchunkctrl.TranslatedChunk) {
+	content, err := h.chunkContent(c.Request.Context(), *chunk)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read translated chunk content"})
+		return
+	}
+	c.JSON(http.StatusOK, content)
+}
+
+// getTranslatedResource returns the translation of the id path parameter or writes the error response
+func (h *TranslatedChunkHandler) getTranslatedResource(c *gin.Context) (*translatedresourcectrl.TranslatedResource, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid translation ID"})
+		return nil, false
+	}
+
+	translatedResource, err := h.translatedResourceSvc.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get translation"})
+		return nil, false
+	}
+	if translatedResource == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Translation not found"})
+		return nil, false
+	}
+	return translatedResource, true
+}
+
+// getTranslatedChunk returns the translation of the id path parameter and its translated chunk of the chunkId
+// path parameter, or writes the error response
+func (h *TranslatedChunkHandler) getTranslatedChunk(c *gin.Context) (*translatedresourcectrl.TranslatedResource, *translatedchunkctrl.TranslatedChunk, bool) {
+	translatedResource, ok := h.getTranslatedResource(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	chunkID, err := strconv.ParseInt(c.Param("chunkId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid translated chunk ID"})
+		return nil, nil, false
+	}
+
+	chunk, err := h.translatedChunkSvc.GetByID(c.Request.Context(), chunkID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get translated chunk"})
+		return nil, nil, false
+	}
+	if chunk == nil || chunk.TranslatedResourceID != translatedResource.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Translated chunk not found"})
+		return nil, nil, false
+	}
+	return translatedResource, chunk, true
+}
+
+// chunkContent loads the translation of a chunk, its revision and its source from MinIO
+func (h *TranslatedChunkHandler) chunkContent(ctx context.Context, chunk translatedchunkctrl.TranslatedChunk) (*TranslatedChunkContent, error) {
+	read := func(minioURL string) (string, error) {
+		bucket, objectName := h.minioService.GetBucketAndObjectFromURL(minioURL)
+		content, err := h.minioService.GetObject(ctx, bucket, objectName)
+		return string(content), err
+	}
+
+	original, err := h.chunkService.GetByID(ctx, chunk.OriginalChunkID)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil {
+		return nil, fmt.Errorf("source chunk %d not found", chunk.OriginalChunkID)
+	}
+	source, err := read(original.MinioURL)
+	if err != nil {
+		return nil, err
+	}
+	translation, err := read(chunk.MinioURL)
+	if err != nil {
+		return nil, err
+	}
+	var revision string
+	if chunk.RevisionMinioURL != "" {
+		if revision, err = read(chunk.RevisionMinioURL); err != nil {
+			return nil, err
+		}
+	}
+
+	return &TranslatedChunkContent{
+		ID:                        chunk.ID,
+		ChunkID:                   chunk.ChunkID,
+		OriginalChunkID:           chunk.OriginalChunkID,
+		Order:                     original.Order,
+		Source:                    source,
+		Translation:               translation,
+		Revision:                  revision,
+		RevisedBy:                 chunk.RevisedBy,
+		RevisedAt:                 chunk.RevisedAt,
+		ReviewStatus:              chunk.ReviewStatus,
+		ReviewedBy:                chunk.ReviewedBy,
+		ReviewedAt:                chunk.ReviewedAt,
+		ReviewComment:             chunk.ReviewComment,
+		QualityScore:              chunk.QualityScore,
+		QualityAccuracy:           chunk.QualityAccuracy,
+		QualityFluency:            chunk.QualityFluency,
+		QualityTerminology:        chunk.QualityTerminology,
+		BackTranslationSimilarity: chunk.BackTranslationSimilarity,
+		QualityComment:            chunk.QualityComment,
+		FlaggedForReview:          chunk.FlaggedForReview,
+		UpdatedAt:                 chunk.UpdatedAt,
+	}, nil
+}
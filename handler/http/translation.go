@@ -7,25 +7,37 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"raggo/src/core/translationflow"
 	jobctrl "raggo/src/infrastructure/job"
+	"raggo/src/storage/postgres/translationprofilectrl"
 )
 
 type TranslationRequest struct {
 	TextID         string `json:"textId" binding:"required"`
-	SourceLanguage string `json:"sourceLanguage" binding:"required"`
+	SourceLanguage string `json:"sourceLanguage" binding:"required"` // "auto" detects the language of every chunk
 	TargetLanguage string `json:"targetLanguage" binding:"required"`
 	Country        string `json:"country" binding:"required"`
 	ModelProvider  string `json:"modelProvider" binding:"required,oneof=ollama"`
 	Model          string `json:"model" binding:"required"`
+	GlossaryID     string `json:"glossaryId"` // optional glossary the translation has to follow
+	// Optional pipeline of the translation stages, given inline or by the name of a stored profile
+	Profile  string                          `json:"profile"`
+	Pipeline *translationflow.PipelineConfig `json:"pipeline"`
+	// Restart discards an earlier translation into the same language and country by the same model instead of resuming it
+	Restart bool `json:"restart"`
+	// What to do with chunks that cannot be translated: fail the job, skip them (default) or keep their source text
+	FailurePolicy string `json:"failurePolicy" binding:"omitempty,oneof=fail skip keep_source"`
 }
 
 type TranslationHandler struct {
-	jobService *jobctrl.JobService
+	jobService     *jobctrl.JobService
+	profileService *translationprofilectrl.TranslationProfileService
 }
 
-func NewTranslationHandler(jobService *jobctrl.JobService) (*TranslationHandler, error) {
+func NewTranslationHandler(jobService *jobctrl.JobService, profileService *translationprofilectrl.TranslationProfileService) (*TranslationHandler, error) {
 	return &TranslationHandler{
-		jobService: jobService,
+		jobService:     jobService,
+		profileService: profileService,
 	}, nil
 }
 
@@ -36,6 +48,23 @@ func (h *TranslationHandler) Translate(c *gin.Context) {
 		return
 	}
 
+	// Reject pipelines that cannot run before the job is queued
+	if err := req.Pipeline.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Pipeline == nil && req.Profile != "" {
+		profile, err := h.profileService.GetByName(c.Request.Context(), req.Profile)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get translation profile"})
+			return
+		}
+		if profile == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Translation profile not found"})
+			return
+		}
+	}
+
 	// Create translation job payload
 	payload := jobctrl.TranslationPayload{
 		SourceLanguage:   req.SourceLanguage,
@@ -44,6 +73,11 @@ func (h *TranslationHandler) Translate(c *gin.Context) {
 		TargetResourceID: req.TextID,
 		UseService:       req.ModelProvider,
 		UseModel:         req.Model,
+		GlossaryID:       req.GlossaryID,
+		Profile:          req.Profile,
+		Pipeline:         req.Pipeline,
+		Restart:          req.Restart,
+		FailurePolicy:    req.FailurePolicy,
 	}
 
 	// Marshal payload to JSON
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"raggo/src/core/translationmemory"
+)
+
+type TranslationMemoryHandler struct {
+	translationMemory *translationmemory.Service
+}
+
+func NewTranslationMemoryHandler(translationMemory *translationmemory.Service) (*TranslationMemoryHandler, error) {
+	return &TranslationMemoryHandler{
+		translationMemory: translationMemory,
+	}, nil
+}
+
+// Export handles GET /api/v1/translation-memory/export and returns the matching entries as a TMX document
+func (h *TranslationMemoryHandler) Export(c *gin.Context) {
+	key := translationmemory.Key{
+		SourceLanguage: c.Query("source_language"),
+		TargetLanguage: c.Query("target_language"),
+		Country:        c.Query("country"),
+		Model:          c.Query("model"),
+		Pipeline:       c.Query("pipeline"),
+		Glossary:       c.Query("glossary"),
+		StyleGuide:     c.Query("style_guide"),
+	}
+
+	entries, err := h.translationMemory.List(c.Request.Context(), key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	srcLang := key.SourceLanguage
+	if srcLang == "" {
+		srcLang = "*all*"
+	}
+
+	var buf bytes.Buffer
+	if err := translationmemory.WriteTMX(&buf, srcLang, entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write TMX document"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="translation-memory.tmx"`)
+	c.Data(http.StatusOK, "application/x-tmx+xml", buf.Bytes())
+}
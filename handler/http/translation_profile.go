@@ -0,0 +1,93 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"raggo/src/core/translationflow"
+	"raggo/src/storage/postgres/translationprofilectrl"
+)
+
+type TranslationProfileHandler struct {
+	profileService *translationprofilectrl.TranslationProfileService
+}
+
+func NewTranslationProfileHandler(profileService *translationprofilectrl.TranslationProfileService) (*TranslationProfileHandler, error) {
+	return &TranslationProfileHandler{
+		profileService: profileService,
+	}, nil
+}
+
+// SaveProfile handles PUT /api/v1/translation-profiles and creates or replaces the profile of the given name
+func (h *TranslationProfileHandler) SaveProfile(c *gin.Context) {
+	var req struct {
+		Name        string                         `json:"name" binding:"required"`
+		Description string                         `json:"description"`
+		Pipeline    translationflow.PipelineConfig `json:"pipeline"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if err := req.Pipeline.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.profileService.Save(c.Request.Context(), req.Name, req.Description, req.Pipeline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// ListProfiles handles GET /api/v1/translation-profiles
+func (h *TranslationProfileHandler) ListProfiles(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	profiles, err := h.profileService.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":          profiles,
+		"offset":         offset,
+		"limit":          limit,
+		"template_names": translationflow.TemplateNames(),
+	})
+}
+
+// DeleteProfile handles DELETE /api/v1/translation-profiles/:id
+func (h *TranslationProfileHandler) DeleteProfile(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid translation profile ID"})
+		return
+	}
+
+	profile, err := h.profileService.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if profile == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Translation profile not found"})
+		return
+	}
+
+	if err := h.profileService.Delete(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,177 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"raggo/src/core/knowledgebase"
+	"raggo/src/infrastructure/integrations/ollama"
+	"raggo/src/infrastructure/log"
+	"raggo/src/storage/postgres/conversationctrl"
+)
+
+// DefaultHistoryTurns is the number of earlier messages a follow-up question is rewritten and answered with
+const DefaultHistoryTurns = 6
+
+// Service answers questions in conversations with a knowledge base. Follow-up questions are rewritten into
+// standalone queries before retrieval, so that "what about the second one?" finds what it refers to.
+type Service struct {
+	knowledgeBaseService *knowledgebase.Service
+	conversationService  *conversationctrl.ConversationService
+	ollamaClient         *ollama.Client
+}
+
+func NewService(
+	knowledgeBaseService *knowledgebase.Service,
+	conversationService *conversationctrl.ConversationService,
+	ollamaClient *ollama.Client,
+) (*Service, error) {
+	return &Service{
+		knowledgeBaseService: knowledgeBaseService,
+		conversationService:  conversationService,
+		ollamaClient:         ollamaClient,
+	}, nil
+}
+
+// AskOptions controls how a question of a conversation is answered
+type AskOptions struct {
+	Answer       knowledgebase.AnswerOptions
+	RewriteModel string // model rewriting follow-up questions, empty to search them as they are
+	HistoryTurns int    // earlier messages taken into account, DefaultHistoryTurns if zero
+	// OnRewrite receives the query searched for the question before it is answered; optional
+	OnRewrite func(query string)
+}
+
+// Reply is the answer to a question of a conversation
+type Reply struct {
+	StandaloneQuery string                    `json:"standalone_query"`
+	Answer          *knowledgebase.Answer     `json:"answer"`
+	Message         *conversationctrl.Message `json:"message"`
+}
+
+// Ask answers a question in a conversation and adds the question and its answer to the history
+func (s *Service) Ask(ctx context.Context, conversation *conversationctrl.Conversation, question string, opts AskOptions) (*Reply, error) {
+	if opts.HistoryTurns <= 0 {
+		opts.HistoryTurns = DefaultHistoryTurns
+	}
+
+	messages, err := s.conversationService.ListMessages(ctx, conversation.ID)
+	if err != nil {
+		return nil, err
+	}
+	history := History(messages, opts.HistoryTurns)
+
+	query := question
+	if len(history) > 0 && opts.RewriteModel != "" {
+		rewritten, err := s.rewriteQuery(ctx, opts.RewriteModel, history, question)
+		if err != nil {
+			log.Error(err, "failed to rewrite question, searching it as it is", "conversation_id", conversation.ID)
+		} else {
+			query = rewritten
+		}
+	}
+	if opts.OnRewrite != nil {
+		opts.OnRewrite(query)
+	}
+
+	answerOpts := opts.Answer
+	answerOpts.RetrievalQuery = query
+	answerOpts.History = history
+	answer, err := s.knowledgeBaseService.Answer(ctx, conversation.KnowledgeBaseID, question, answerOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := s.conversationService.AddExchange(ctx, conversation, question, query, answer.Answer, answer.Citations)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reply{
+		StandaloneQuery: query,
+		Answer:          answer,
+		Message:         message,
+	}, nil
+}
+
+// rewriteQuery turns a follow-up question into a query that can be searched without the conversation
+func (s *Service) rewriteQuery(ctx context.Context, model string, history []knowledgebase.Turn, question string) (string, error) {
+	var sb strings.Builder
+	for _, turn := range history {
+		fmt.Fprintf(&sb, "%s: %s\n", turn.Role, turn.Content)
+	}
+	prompt := strings.NewReplacer("{history}", strings.TrimSuffix(sb.String(), "\n"), "{question}", question).Replace(REWRITE_QUERY_PROMPT)
+
+	provider := ollama.NewOllamaProvider(s.ollamaClient, model)
+	rewritten, err := provider.Reasoning(ctx, REWRITE_QUERY_SYSTEM_PROMPT, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to rewrite question: %v", err)
+	}
+
+	query := CleanQuery(rewritten)
+	if query == "" {
+		return question, nil
+	}
+	return query, nil
+}
+
+// CleanQuery strips what models tend to wrap a rewritten query in: a label, quotes and further lines
+func CleanQuery(rewritten string) string {
+	for _, line := range strings.Split(rewritten, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if i := strings.Index(line, ":"); i >= 0 && strings.Contains(strings.ToLower(line[:i]), "question") {
+			line = strings.TrimSpace(line[i+1:])
+		}
+		return strings.Trim(line, "\"'“”")
+	}
+	return ""
+}
+
+// History returns the last turns of a conversation, oldest first
+func History(messages []conversationctrl.Message, turns int) []knowledgebase.Turn {
+	if len(messages) > turns {
+		messages = messages[len(messages)-turns:]
+	}
+	history := make([]knowledgebase.Turn, 0, len(messages))
+	for _, message := range messages {
+		history = append(history, knowledgebase.Turn{Role: message.Role, Content: message.Content})
+	}
+	return history
+}
+
+// Sources returns the sources cited in a conversation, every chunk once in the order it was first cited
+// and numbered by that order
+func Sources(messages []conversationctrl.Message) []knowledgebase.Source {
+	seen := make(map[int64]bool)
+	sources := []knowledgebase.Source{}
+	for _, message := range messages {
+		for _, citation := range message.Citations {
+			if seen[citation.ChunkID] {
+				continue
+			}
+			seen[citation.ChunkID] = true
+			citation.Number = len(sources) + 1
+			sources = append(sources, citation)
+		}
+	}
+	return sources
+}
+
+const (
+	REWRITE_QUERY_SYSTEM_PROMPT = `You rewrite the last question of a conversation into a standalone search query.
+Resolve every pronoun and reference such as "it", "the second one" or "that" using the conversation,
+keep the language of the question and do not answer it.
+Return only the rewritten question. If it already stands on its own, return it unchanged.`
+
+	REWRITE_QUERY_PROMPT = `
+<conversation>
+{history}
+</conversation>
+
+Last question: {question}
+`
+)
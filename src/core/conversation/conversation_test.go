@@ -0,0 +1,102 @@
+package conversation_test
+
+import (
+	"reflect"
+	"testing"
+
+	"raggo/src/core/conversation"
+	"raggo/src/core/knowledgebase"
+	"raggo/src/storage/postgres/conversationctrl"
+)
+
+func TestHistory(t *testing.T) {
+	messages := []conversationctrl.Message{
+		{Role: knowledgebase.RoleUser, Content: "Which models are supported?"},
+		{Role: knowledgebase.RoleAssistant, Content: "Llama and Qwen [1]."},
+		{Role: knowledgebase.RoleUser, Content: "What about the second one?"},
+		{Role: knowledgebase.RoleAssistant, Content: "Qwen supports 29 languages [2]."},
+	}
+
+	tests := []struct {
+		name  string
+		turns int
+		want  []knowledgebase.Turn
+	}{
+		{
+			name:  "last turns only",
+			turns: 2,
+			want: []knowledgebase.Turn{
+				{Role: knowledgebase.RoleUser, Content: "What about the second one?"},
+				{Role: knowledgebase.RoleAssistant, Content: "Qwen supports 29 languages [2]."},
+			},
+		},
+		{
+			name:  "shorter conversation than the window",
+			turns: 6,
+			want: []knowledgebase.Turn{
+				{Role: knowledgebase.RoleUser, Content: "Which models are supported?"},
+				{Role: knowledgebase.RoleAssistant, Content: "Llama and Qwen [1]."},
+				{Role: knowledgebase.RoleUser, Content: "What about the second one?"},
+				{Role: knowledgebase.RoleAssistant, Content: "Qwen supports 29 languages [2]."},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := conversation.History(messages, tt.turns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("History() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got := conversation.History(nil, 6); len(got) != 0 {
+		t.Errorf("History() of an empty conversation = %+v, want none", got)
+	}
+}
+
+func TestSources(t *testing.T) {
+	messages := []conversationctrl.Message{
+		{Role: knowledgebase.RoleUser, Content: "first question"},
+		{Role: knowledgebase.RoleAssistant, Citations: []knowledgebase.Source{
+			{Number: 1, ChunkID: 11, ResourceID: 10},
+			{Number: 3, ChunkID: 13, ResourceID: 10},
+		}},
+		{Role: knowledgebase.RoleUser, Content: "follow-up"},
+		{Role: knowledgebase.RoleAssistant, Citations: []knowledgebase.Source{
+			{Number: 1, ChunkID: 13, ResourceID: 10},
+			{Number: 2, ChunkID: 21, ResourceID: 20},
+		}},
+	}
+
+	want := []knowledgebase.Source{
+		{Number: 1, ChunkID: 11, ResourceID: 10},
+		{Number: 2, ChunkID: 13, ResourceID: 10},
+		{Number: 3, ChunkID: 21, ResourceID: 20},
+	}
+	if got := conversation.Sources(messages); !reflect.DeepEqual(got, want) {
+		t.Errorf("Sources() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCleanQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		rewritten string
+		want      string
+	}{
+		{name: "plain", rewritten: "How many languages does Qwen support?", want: "How many languages does Qwen support?"},
+		{name: "quoted", rewritten: "\"How many languages does Qwen support?\"\n", want: "How many languages does Qwen support?"},
+		{name: "labelled", rewritten: "Standalone question: How many languages does Qwen support?", want: "How many languages does Qwen support?"},
+		{name: "explanation on further lines", rewritten: "\nHow many languages does Qwen support?\nI replaced \"the second one\" with Qwen.", want: "How many languages does Qwen support?"},
+		{name: "empty", rewritten: "  \n ", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := conversation.CleanQuery(tt.rewritten); got != tt.want {
+				t.Errorf("CleanQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+
+	"raggo/src/storage/postgres/chunkctrl"
+)
+
+// Part is one chunk of a converted resource with its content loaded
+type Part struct {
+	Kind          string
+	Content       string
+	TableMarkdown string // Markdown representation of a table chunk
+	ImageURL      string // browsable URL of the image of an image chunk
+}
+
+// Assemble merges the parts of a document, given in document order, into its full plain text and Markdown.
+// Tables keep their row/column structure in the Markdown output and images are embedded with their caption as alt text.
+func Assemble(parts []Part) (text string, markdown string) {
+	var textBlocks, markdownBlocks []string
+	for _, part := range parts {
+		content := strings.TrimSpace(part.Content)
+		if content != "" {
+			textBlocks = append(textBlocks, content)
+		}
+
+		switch {
+		case part.Kind == chunkctrl.KindTable && part.TableMarkdown != "":
+			markdownBlocks = append(markdownBlocks, strings.TrimSpace(part.TableMarkdown))
+		case part.Kind == chunkctrl.KindImage && part.ImageURL != "":
+			markdownBlocks = append(markdownBlocks, fmt.Sprintf("![%s](%s)", imageAltText(content), part.ImageURL))
+		case content != "":
+			markdownBlocks = append(markdownBlocks, content)
+		}
+	}
+
+	return strings.Join(textBlocks, "\n\n"), strings.Join(markdownBlocks, "\n\n")
+}
+
+// imageAltText flattens a caption so it can be used inside the brackets of a Markdown image
+func imageAltText(caption string) string {
+	alt := strings.Join(strings.Fields(caption), " ")
+	alt = strings.ReplaceAll(alt, "[", `\[`)
+	return strings.ReplaceAll(alt, "]", `\]`)
+}
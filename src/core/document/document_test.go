@@ -0,0 +1,29 @@
+package document_test
+
+import (
+	"testing"
+
+	"raggo/src/core/document"
+	"raggo/src/storage/postgres/chunkctrl"
+)
+
+func TestAssemble(t *testing.T) {
+	parts := []document.Part{
+		{Kind: chunkctrl.KindText, Content: "Introduction\n"},
+		{Kind: chunkctrl.KindTable, Content: "Model Voltage A1 12V", TableMarkdown: "| Model | Voltage |\n| --- | --- |\n| A1 | 12V |"},
+		{Kind: chunkctrl.KindText, Content: "   "},
+		{Kind: chunkctrl.KindImage, Content: "Wiring [diagram]\nof the board", ImageURL: "http://localhost:9000/images/a.png"},
+	}
+
+	text, markdown := document.Assemble(parts)
+
+	wantText := "Introduction\n\nModel Voltage A1 12V\n\nWiring [diagram]\nof the board"
+	if text != wantText {
+		t.Errorf("Assemble() text = %q, want %q", text, wantText)
+	}
+
+	wantMarkdown := "Introduction\n\n| Model | Voltage |\n| --- | --- |\n| A1 | 12V |\n\n![Wiring \\[diagram\\] of the board](http://localhost:9000/images/a.png)"
+	if markdown != wantMarkdown {
+		t.Errorf("Assemble() markdown = %q, want %q", markdown, wantMarkdown)
+	}
+}
@@ -0,0 +1,88 @@
+package imagecaption
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"raggo/src/infrastructure/log"
+)
+
+const (
+	DefaultCaptionTimeout = 60 * time.Second
+)
+
+// VisionProvider is implemented by vision-capable LLM providers
+type VisionProvider interface {
+	DescribeImage(ctx context.Context, prompt string, image []byte) (string, error)
+}
+
+// CaptionData holds the data needed for the caption prompt
+type CaptionData struct {
+	Filename        string
+	PageNumber      int
+	SurroundingText string
+}
+
+type Captioner struct {
+	visionProvider VisionProvider
+	timeout        time.Duration
+	promptTmpl     *template.Template
+}
+
+func NewCaptioner(visionProvider VisionProvider, opts ...Option) *Captioner {
+	c := &Captioner{
+		visionProvider: visionProvider,
+		timeout:        DefaultCaptionTimeout,
+		promptTmpl:     template.Must(template.New("caption").Parse(ImageCaptionPromptTmpl)),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+type Option func(c *Captioner)
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Captioner) {
+		c.timeout = timeout
+	}
+}
+
+// Caption generates a searchable description of an image extracted from a document
+func (c *Captioner) Caption(ctx context.Context, image []byte, data CaptionData) (string, error) {
+	var promptBuf bytes.Buffer
+	if err := c.promptTmpl.Execute(&promptBuf, data); err != nil {
+		return "", fmt.Errorf("failed to execute caption template: %w", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	caption, err := c.visionProvider.DescribeImage(timeoutCtx, promptBuf.String(), image)
+	if err != nil {
+		log.Error(err, "failed to caption image", "filename", data.Filename, "page", data.PageNumber)
+		return "", fmt.Errorf("failed to caption image: %w", err)
+	}
+
+	return strings.TrimSpace(caption), nil
+}
+
+const ImageCaptionPromptTmpl = `You are given a figure extracted from page {{.PageNumber}} of the document "{{.Filename}}".
+{{- if .SurroundingText}}
+
+Text around the figure in the document:
+<TEXT>
+{{.SurroundingText}}
+</TEXT>
+{{- end}}
+
+Describe the figure so it can be found by a search engine. Mention the kind of figure (diagram, chart, photo, screenshot, ...),
+the components, labels and values it shows, and what it explains.
+Answer only with the description and nothing else.`
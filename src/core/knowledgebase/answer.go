@@ -0,0 +1,239 @@
+package knowledgebase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"raggo/src/infrastructure/integrations/ollama"
+	"raggo/src/infrastructure/log"
+)
+
+// DefaultAnswerTokenBudget is the estimated number of tokens of the prompt given to the model by default
+const DefaultAnswerTokenBudget = 4096
+
+// Source is a retrieved chunk given to the model as context. The answer cites it by its number.
+type Source struct {
+	Number            int     `json:"number"`
+	ChunkID           int64   `json:"chunk_id"`
+	ResourceID        int64   `json:"resource_id"`
+	TranslatedChunkID int64   `json:"translated_chunk_id,omitempty"`
+	Language          string  `json:"language,omitempty"`
+	Score             float64 `json:"score"`
+	Content           string  `json:"content"`
+	ContextChunkIDs   []int64 `json:"context_chunk_ids,omitempty"` // the chunks making up the content after context expansion
+}
+
+// Answer is a generated answer with the sources it is based on
+type Answer struct {
+	Answer    string   `json:"answer"`
+	Sources   []Source `json:"sources"`   // the context given to the model
+	Citations []Source `json:"citations"` // the sources cited in the answer
+}
+
+// Roles of the turns of a conversation
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Turn is an earlier question or answer of the conversation a question is asked in
+type Turn struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// AnswerOptions controls how a question is answered
+type AnswerOptions struct {
+	Model string
+	// TokenBudget caps the tokens of the prompt: the retrieved context fills what the system prompt, the history
+	// and the question leave. Tokens are estimated with EstimateBertTokenCount, not the tokenizer of the model,
+	// so the budget should leave some room below the context size of the model. DefaultAnswerTokenBudget if zero.
+	TokenBudget int
+	Query       QueryOptions
+	// RetrievalQuery is searched instead of the question, e.g. a follow-up question rewritten to stand on its own
+	RetrievalQuery string
+	// History holds the earlier turns of the conversation, oldest first
+	History []Turn
+	// OnSources receives the sources before the answer is generated, OnToken every piece of the answer; both optional
+	OnSources func([]Source)
+	OnToken   func(string)
+}
+
+// citationPattern matches inline citations such as [1] or [2, 3]
+var citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// Answer retrieves the chunks relevant to the question, gives the closest ones that fit into what the rest of
+// the prompt leaves of the token budget to the model and generates an answer that cites them inline by their number. Without relevant chunks the model
+// is asked all the same, so that it says it cannot answer from the knowledge base.
+func (s *Service) Answer(ctx context.Context, knowledgeBaseID int64, question string, opts AnswerOptions) (*Answer, error) {
+	if opts.Model == "" {
+		return nil, fmt.Errorf("answer model is required")
+	}
+	if opts.TokenBudget <= 0 {
+		opts.TokenBudget = DefaultAnswerTokenBudget
+	}
+
+	query := question
+	if opts.RetrievalQuery != "" {
+		query = opts.RetrievalQuery
+	}
+	results, err := s.QueryKnowledgeBase(ctx, knowledgeBaseID, query, opts.Query)
+	if err != nil && !errors.Is(err, ErrNoRelevantContext) {
+		return nil, err
+	}
+	sources := SelectSources(results, SourceTokenBudget(opts.TokenBudget, opts.History, question))
+	if opts.OnSources != nil {
+		opts.OnSources(sources)
+	}
+
+	prompt := answerPrompt(opts.History, renderSources(sources), question)
+	onToken := opts.OnToken
+	if onToken == nil {
+		onToken = func(string) {}
+	}
+	provider := ollama.NewOllamaProvider(s.ollamaClient, opts.Model)
+	text, err := provider.ReasoningStream(ctx, ANSWER_SYSTEM_PROMPT, prompt, onToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate answer: %v", err)
+	}
+
+	answer := &Answer{
+		Answer:    strings.TrimSpace(text),
+		Sources:   sources,
+		Citations: CitedSources(text, sources),
+	}
+	log.Info("Answered question", "knowledge_base_id", knowledgeBaseID, "sources", len(sources), "citations", len(answer.Citations))
+	return answer, nil
+}
+
+// SourceTokenBudget returns the tokens left for the retrieved context once the system prompt, the history and
+// the question are given to the model, 0 if they take up the whole budget
+func SourceTokenBudget(tokenBudget int, history []Turn, question string) int {
+	used := EstimateBertTokenCount(ANSWER_SYSTEM_PROMPT) + EstimateBertTokenCount(answerPrompt(history, "", question))
+	return max(tokenBudget-used, 0)
+}
+
+// answerPrompt renders the answer prompt with the history, the rendered sources and the question
+func answerPrompt(history []Turn, sources, question string) string {
+	return strings.NewReplacer(
+		"{history}", renderHistory(history),
+		"{sources}", sources,
+		"{question}", question,
+	).Replace(ANSWER_PROMPT)
+}
+
+// SelectSources numbers the closest results that fit into the token budget. Results that do not fit are skipped
+// in favour of shorter ones further down; the closest result is cut to the budget if it does not fit on its own.
+func SelectSources(results []QueryResult, tokenBudget int) []Source {
+	sources := []Source{}
+	remaining := tokenBudget
+	for i, result := range results {
+		content := result.Content
+		tokens := EstimateBertTokenCount(content)
+		if tokens > remaining {
+			if i > 0 {
+				continue
+			}
+			content = truncateToTokens(content, remaining)
+			tokens = EstimateBertTokenCount(content)
+		}
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		remaining -= tokens
+
+		sources = append(sources, Source{
+			Number:            len(sources) + 1,
+			ChunkID:           result.ChunkID,
+			ResourceID:        result.ResourceID,
+			TranslatedChunkID: result.TranslatedChunkID,
+			Language:          result.Language,
+			Score:             result.Score,
+			Content:           content,
+			ContextChunkIDs:   result.ContextChunkIDs,
+		})
+	}
+	return sources
+}
+
+// truncateToTokens keeps the leading words of the text that fit into the token budget
+func truncateToTokens(text string, tokenBudget int) string {
+	var sb strings.Builder
+	tokens := 2 // [CLS] and [SEP], as counted by EstimateBertTokenCount
+	for _, word := range strings.Fields(text) {
+		tokens += estimateWordTokens(word)
+		if tokens > tokenBudget {
+			break
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(word)
+	}
+	return sb.String()
+}
+
+// CitedSources returns the sources the answer cites, in the order of their numbers. Citations of numbers
+// that are not among the sources are ignored.
+func CitedSources(answer string, sources []Source) []Source {
+	cited := make(map[int]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		for _, number := range strings.Split(match[1], ",") {
+			if n, err := strconv.Atoi(strings.TrimSpace(number)); err == nil {
+				cited[n] = true
+			}
+		}
+	}
+
+	citations := []Source{}
+	for _, source := range sources {
+		if cited[source.Number] {
+			citations = append(citations, source)
+		}
+	}
+	return citations
+}
+
+// renderSources formats the sources for the answer prompt
+func renderSources(sources []Source) string {
+	var sb strings.Builder
+	for _, source := range sources {
+		fmt.Fprintf(&sb, "<source number=\"%d\" chunk_id=\"%d\" resource_id=\"%d\">\n%s\n</source>\n",
+			source.Number, source.ChunkID, source.ResourceID, source.Content)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// renderHistory formats the earlier turns of the conversation for the answer prompt, empty without any
+func renderHistory(history []Turn) string {
+	if len(history) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("<conversation>\n")
+	for _, turn := range history {
+		fmt.Fprintf(&sb, "%s: %s\n", turn.Role, turn.Content)
+	}
+	sb.WriteString("</conversation>\n")
+	return sb.String()
+}
+
+const (
+	ANSWER_SYSTEM_PROMPT = `You answer questions using only the numbered sources you are given.
+Cite every statement inline with the number of its source in square brackets, e.g. [1] or [2, 3].
+If the sources do not contain the answer, say that you cannot answer the question from them.
+Use the conversation, if there is one, only to understand what the question refers to.
+Answer in the language of the question.`
+
+	ANSWER_PROMPT = `
+{history}<sources>
+{sources}
+</sources>
+
+Question: {question}
+`
+)
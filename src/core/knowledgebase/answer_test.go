@@ -0,0 +1,159 @@
+package knowledgebase_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"raggo/src/core/knowledgebase"
+)
+
+func TestSelectSources(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+
+	tests := []struct {
+		name        string
+		results     []knowledgebase.QueryResult
+		tokenBudget int
+		wantChunks  []int64
+	}{
+		{
+			name: "all results fit",
+			results: []knowledgebase.QueryResult{
+				{ChunkID: 1, ResourceID: 10, Content: "first chunk"},
+				{ChunkID: 2, ResourceID: 10, Content: "second chunk"},
+			},
+			tokenBudget: 100,
+			wantChunks:  []int64{1, 2},
+		},
+		{
+			name: "a result that does not fit is skipped for a shorter one",
+			results: []knowledgebase.QueryResult{
+				{ChunkID: 1, ResourceID: 10, Content: "first chunk"},
+				{ChunkID: 2, ResourceID: 10, Content: long},
+				{ChunkID: 3, ResourceID: 11, Content: "third chunk"},
+			},
+			tokenBudget: 50,
+			wantChunks:  []int64{1, 3},
+		},
+		{
+			name: "empty results are skipped",
+			results: []knowledgebase.QueryResult{
+				{ChunkID: 1, ResourceID: 10, Content: "  "},
+				{ChunkID: 2, ResourceID: 10, Content: "second chunk"},
+			},
+			tokenBudget: 100,
+			wantChunks:  []int64{2},
+		},
+		{
+			name:        "no results",
+			tokenBudget: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := knowledgebase.SelectSources(tt.results, tt.tokenBudget)
+			var chunks []int64
+			for i, source := range sources {
+				if source.Number != i+1 {
+					t.Errorf("source %d has number %d", i, source.Number)
+				}
+				chunks = append(chunks, source.ChunkID)
+			}
+			if !reflect.DeepEqual(chunks, tt.wantChunks) {
+				t.Errorf("SelectSources() chunks = %v, want %v", chunks, tt.wantChunks)
+			}
+			if sources == nil {
+				t.Error("SelectSources() = nil, want an empty list")
+			}
+		})
+	}
+}
+
+func TestSelectSourcesTruncatesClosestResult(t *testing.T) {
+	results := []knowledgebase.QueryResult{
+		{ChunkID: 1, ResourceID: 10, Content: strings.Repeat("word ", 100)},
+	}
+
+	sources := knowledgebase.SelectSources(results, 20)
+	if len(sources) != 1 {
+		t.Fatalf("SelectSources() returned %d sources, want 1", len(sources))
+	}
+	if tokens := knowledgebase.EstimateBertTokenCount(sources[0].Content); tokens > 20 {
+		t.Errorf("truncated source has %d tokens, want at most 20", tokens)
+	}
+	// Every word counts as one token next to [CLS] and [SEP]
+	if words := len(strings.Fields(sources[0].Content)); words != 18 {
+		t.Errorf("truncated source keeps %d words, want 18", words)
+	}
+}
+
+func TestSourceTokenBudget(t *testing.T) {
+	question := "Which languages does Qwen support?"
+	history := []knowledgebase.Turn{
+		{Role: knowledgebase.RoleUser, Content: "What is Qwen?"},
+		{Role: knowledgebase.RoleAssistant, Content: strings.Repeat("word ", 200)},
+	}
+
+	withoutHistory := knowledgebase.SourceTokenBudget(4096, nil, question)
+	if withoutHistory <= 0 || withoutHistory >= 4096 {
+		t.Fatalf("SourceTokenBudget() without history = %d, want the budget less the prompt and the question", withoutHistory)
+	}
+	// The assistant turn alone takes 200 tokens
+	if withHistory := knowledgebase.SourceTokenBudget(4096, history, question); withHistory > withoutHistory-200 {
+		t.Errorf("SourceTokenBudget() with history = %d, want at most %d", withHistory, withoutHistory-200)
+	}
+	if got := knowledgebase.SourceTokenBudget(100, history, question); got != 0 {
+		t.Errorf("SourceTokenBudget() of a history exceeding the budget = %d, want 0", got)
+	}
+	if sources := knowledgebase.SelectSources([]knowledgebase.QueryResult{{ChunkID: 1, Content: "first chunk"}}, 0); len(sources) != 0 {
+		t.Errorf("SelectSources() without budget = %+v, want no sources", sources)
+	}
+}
+
+func TestCitedSources(t *testing.T) {
+	sources := []knowledgebase.Source{
+		{Number: 1, ChunkID: 11, ResourceID: 10},
+		{Number: 2, ChunkID: 12, ResourceID: 10},
+		{Number: 3, ChunkID: 13, ResourceID: 20},
+	}
+
+	tests := []struct {
+		name   string
+		answer string
+		want   []int64
+	}{
+		{
+			name:   "single citations",
+			answer: "Paris is the capital [1]. It lies on the Seine [3].",
+			want:   []int64{11, 13},
+		},
+		{
+			name:   "grouped citations",
+			answer: "Both agree on this [2, 3][1].",
+			want:   []int64{11, 12, 13},
+		},
+		{
+			name:   "unknown numbers are ignored",
+			answer: "See [2] and [7].",
+			want:   []int64{12},
+		},
+		{
+			name:   "no citations",
+			answer: "I cannot answer the question from the sources.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int64
+			for _, source := range knowledgebase.CitedSources(tt.answer, sources) {
+				got = append(got, source.ChunkID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CitedSources() chunks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
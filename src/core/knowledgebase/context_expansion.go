@@ -0,0 +1,280 @@
+package knowledgebase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"raggo/src/infrastructure/log"
+	"raggo/src/storage/postgres/chunkctrl"
+)
+
+// Modes of widening the chunks a query finds into coherent context
+const (
+	ContextNone      = "none"      // return the chunks as they are
+	ContextNeighbors = "neighbors" // add the chunks before and after every hit by chunk order
+	ContextPage      = "page"      // return the page of the source document every hit is on
+)
+
+// DefaultContextNeighbors is the number of chunks added on either side of a hit in neighbors mode by default
+const DefaultContextNeighbors = 1
+
+// contextSeparator joins the chunks of a context window
+const contextSeparator = "\n\n"
+
+// ValidContext reports whether the context expansion mode is known; an empty mode is the same as ContextNone
+func ValidContext(mode string) bool {
+	switch mode {
+	case "", ContextNone, ContextNeighbors, ContextPage:
+		return true
+	}
+	return false
+}
+
+// MergedWindow is a context window of one resource made of the windows of one or more hits.
+// Hits are the indices of those windows in rank order, so the first is the hit the window is ranked at;
+// Positions are the positions of its chunks in the resource in document order.
+type MergedWindow struct {
+	Hits      []int
+	Positions []int
+}
+
+// ContextWindow returns the positions of the chunks around the chunk at the given position of a resource whose
+// chunks are in document order: the neighbors on either side, or the chunks on the same page. A chunk whose page
+// is unknown is its own page.
+func ContextWindow(chunks []chunkctrl.Chunk, position int, mode string, neighbors int) []int {
+	switch mode {
+	case ContextNeighbors:
+		from, to := max(0, position-neighbors), min(len(chunks)-1, position+neighbors)
+		window := make([]int, 0, to-from+1)
+		for p := from; p <= to; p++ {
+			window = append(window, p)
+		}
+		return window
+	case ContextPage:
+		page := chunks[position].PageNumber
+		if page == 0 {
+			return []int{position}
+		}
+		var window []int
+		for p, chunk := range chunks {
+			if chunk.PageNumber == page {
+				window = append(window, p)
+			}
+		}
+		return window
+	default:
+		return []int{position}
+	}
+}
+
+// MergeWindows merges the context windows of the hits of one resource, given in rank order. Windows that overlap
+// or touch become one, so that no chunk is returned twice and adjacent windows read as one passage. The merged
+// windows are returned in the order of their best hit.
+func MergeWindows(windows [][]int) []MergedWindow {
+	var merged []MergedWindow
+	for hit, window := range windows {
+		current := MergedWindow{Hits: []int{hit}, Positions: window}
+
+		var kept []MergedWindow
+		for _, m := range merged {
+			if touches(m.Positions, current.Positions) {
+				current = union(m, current)
+			} else {
+				kept = append(kept, m)
+			}
+		}
+		merged = append(kept, current)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Hits[0] < merged[j].Hits[0]
+	})
+	return merged
+}
+
+// touches reports whether two windows share a chunk or hold adjacent chunks
+func touches(a, b []int) bool {
+	positions := make(map[int]bool, len(a))
+	for _, p := range a {
+		positions[p] = true
+	}
+	for _, p := range b {
+		if positions[p-1] || positions[p] || positions[p+1] {
+			return true
+		}
+	}
+	return false
+}
+
+// union returns the window with the hits and the positions of both windows, each once and in order
+func union(a, b MergedWindow) MergedWindow {
+	hits := make(map[int]bool)
+	positions := make(map[int]bool)
+	for _, w := range []MergedWindow{a, b} {
+		for _, h := range w.Hits {
+			hits[h] = true
+		}
+		for _, p := range w.Positions {
+			positions[p] = true
+		}
+	}
+
+	var result MergedWindow
+	for h := range hits {
+		result.Hits = append(result.Hits, h)
+	}
+	for p := range positions {
+		result.Positions = append(result.Positions, p)
+	}
+	sort.Ints(result.Hits)
+	sort.Ints(result.Positions)
+	return result
+}
+
+// windowKey identifies the hits that are expanded and merged together: those of one resource in one
+// knowledge base and, for translated hits, of one translation
+type windowKey struct {
+	knowledgeBaseID      int64
+	resourceID           int64
+	translatedResourceID int64
+}
+
+// expandContext widens the ranked hits into context windows of their resources as set by the options. A hit whose
+// window merges into the window of a better hit is dropped, so every chunk is returned once. Hits that cannot be
+// expanded are returned as they are.
+func (s *Service) expandContext(ctx context.Context, results []QueryResult, opts QueryOptions) []QueryResult {
+	if opts.Context == "" || opts.Context == ContextNone {
+		return results
+	}
+	neighbors := opts.Neighbors
+	if neighbors <= 0 {
+		neighbors = DefaultContextNeighbors
+	}
+
+	// Group the hits in rank order by the resource and translation they are expanded in
+	groups := make(map[windowKey][]int)
+	var keys []windowKey
+	for i, result := range results {
+		key := windowKey{knowledgeBaseID: result.KnowledgeBaseID, resourceID: result.ResourceID}
+		if result.TranslatedChunkID != 0 {
+			tc, err := s.translatedChunkSvc.GetByID(ctx, result.TranslatedChunkID)
+			if err != nil || tc == nil {
+				continue
+			}
+			key.translatedResourceID = tc.TranslatedResourceID
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	absorbed := make([]bool, len(results))
+	for _, key := range keys {
+		if err := s.expandGroup(ctx, key, groups[key], results, absorbed, opts.Context, neighbors); err != nil {
+			log.Error(err, "failed to expand context", "resource_id", key.resourceID, "translated_resource_id", key.translatedResourceID)
+		}
+	}
+
+	expanded := make([]QueryResult, 0, len(results))
+	for i, result := range results {
+		if !absorbed[i] {
+			expanded = append(expanded, result)
+		}
+	}
+	return expanded
+}
+
+// expandGroup expands the hits of one resource, given as indices into results in rank order, in place.
+// Hits merged into the window of a better hit are marked as absorbed.
+func (s *Service) expandGroup(ctx context.Context, key windowKey, hits []int, results []QueryResult, absorbed []bool, mode string, neighbors int) error {
+	chunks, err := s.chunkService.GetByResourceID(ctx, key.resourceID)
+	if err != nil {
+		return err
+	}
+	positions := make(map[int64]int, len(chunks))
+	for p, chunk := range chunks {
+		positions[chunk.ID] = p
+	}
+
+	var windows [][]int
+	var windowHits []int
+	for _, i := range hits {
+		p, ok := positions[results[i].ChunkID]
+		if !ok {
+			continue
+		}
+		windows = append(windows, ContextWindow(chunks, p, mode, neighbors))
+		windowHits = append(windowHits, i)
+	}
+
+	// Translated hits are expanded with the translations of their neighbors where there are any
+	var translations map[int64]string
+	if key.translatedResourceID != 0 {
+		translations, err = s.translatedChunkURLs(ctx, key.translatedResourceID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, window := range MergeWindows(windows) {
+		anchor := windowHits[window.Hits[0]]
+
+		// The content of the hits is loaded already
+		contents := make(map[int64]string, len(window.Hits))
+		for _, h := range window.Hits {
+			i := windowHits[h]
+			contents[results[i].ChunkID] = results[i].Content
+		}
+
+		parts := make([]string, 0, len(window.Positions))
+		chunkIDs := make([]int64, 0, len(window.Positions))
+		for _, p := range window.Positions {
+			chunk := chunks[p]
+			content, ok := contents[chunk.ID]
+			if !ok {
+				minioURL := chunk.MinioURL
+				if url, ok := translations[chunk.ID]; ok {
+					minioURL = url
+				}
+				bucket, objectName := s.minioService.GetBucketAndObjectFromURL(minioURL)
+				data, err := s.minioService.GetObject(ctx, bucket, objectName)
+				if err != nil {
+					return fmt.Errorf("failed to read chunk %d: %v", chunk.ID, err)
+				}
+				content = string(data)
+			}
+			if strings.TrimSpace(content) == "" {
+				continue
+			}
+			parts = append(parts, content)
+			chunkIDs = append(chunkIDs, chunk.ID)
+		}
+
+		results[anchor].Content = strings.Join(parts, contextSeparator)
+		results[anchor].ContextChunkIDs = chunkIDs
+		for _, h := range window.Hits[1:] {
+			absorbed[windowHits[h]] = true
+		}
+	}
+	return nil
+}
+
+// translatedChunkURLs maps the original chunks of a translation onto the final text of their translation,
+// leaving out rejected translations
+func (s *Service) translatedChunkURLs(ctx context.Context, translatedResourceID int64) (map[int64]string, error) {
+	tcs, err := s.translatedChunkSvc.GetByTranslatedResourceID(ctx, translatedResourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make(map[int64]string, len(tcs))
+	for _, tc := range tcs {
+		if !tc.IsRejected() {
+			urls[tc.OriginalChunkID] = tc.FinalMinioURL()
+		}
+	}
+	return urls, nil
+}
@@ -0,0 +1,97 @@
+package knowledgebase_test
+
+import (
+	"reflect"
+	"testing"
+
+	"raggo/src/core/knowledgebase"
+	"raggo/src/storage/postgres/chunkctrl"
+)
+
+func TestContextWindow(t *testing.T) {
+	// Five chunks on two pages; the image on page 1 is ordered after the text of page 2, as by earlier conversions
+	chunks := []chunkctrl.Chunk{
+		{ID: 1, PageNumber: 1},
+		{ID: 2, PageNumber: 1},
+		{ID: 3, PageNumber: 2},
+		{ID: 4, PageNumber: 1},
+		{ID: 5, PageNumber: 0},
+	}
+
+	tests := []struct {
+		name      string
+		position  int
+		mode      string
+		neighbors int
+		want      []int
+	}{
+		{name: "neighbors on either side", position: 2, mode: knowledgebase.ContextNeighbors, neighbors: 1, want: []int{1, 2, 3}},
+		{name: "neighbors clipped at the start", position: 0, mode: knowledgebase.ContextNeighbors, neighbors: 2, want: []int{0, 1, 2}},
+		{name: "neighbors clipped at the end", position: 4, mode: knowledgebase.ContextNeighbors, neighbors: 2, want: []int{2, 3, 4}},
+		{name: "page includes chunks that are not adjacent", position: 1, mode: knowledgebase.ContextPage, want: []int{0, 1, 3}},
+		{name: "unknown page is the chunk itself", position: 4, mode: knowledgebase.ContextPage, want: []int{4}},
+		{name: "no expansion", position: 2, mode: knowledgebase.ContextNone, want: []int{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := knowledgebase.ContextWindow(chunks, tt.position, tt.mode, tt.neighbors)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ContextWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeWindows(t *testing.T) {
+	tests := []struct {
+		name    string
+		windows [][]int
+		want    []knowledgebase.MergedWindow
+	}{
+		{
+			name:    "overlapping windows are merged",
+			windows: [][]int{{4, 5, 6}, {5, 6, 7}},
+			want:    []knowledgebase.MergedWindow{{Hits: []int{0, 1}, Positions: []int{4, 5, 6, 7}}},
+		},
+		{
+			name:    "touching windows are merged",
+			windows: [][]int{{4, 5}, {2, 3}},
+			want:    []knowledgebase.MergedWindow{{Hits: []int{0, 1}, Positions: []int{2, 3, 4, 5}}},
+		},
+		{
+			name:    "disjoint windows stay apart in rank order",
+			windows: [][]int{{8, 9}, {1, 2}},
+			want: []knowledgebase.MergedWindow{
+				{Hits: []int{0}, Positions: []int{8, 9}},
+				{Hits: []int{1}, Positions: []int{1, 2}},
+			},
+		},
+		{
+			name:    "a later window joins two earlier ones",
+			windows: [][]int{{1, 2}, {6, 7}, {3, 4, 5}},
+			want:    []knowledgebase.MergedWindow{{Hits: []int{0, 1, 2}, Positions: []int{1, 2, 3, 4, 5, 6, 7}}},
+		},
+		{
+			name:    "a merged window is ranked at its best hit",
+			windows: [][]int{{10}, {1, 2}, {20}, {3}},
+			want: []knowledgebase.MergedWindow{
+				{Hits: []int{0}, Positions: []int{10}},
+				{Hits: []int{1, 3}, Positions: []int{1, 2, 3}},
+				{Hits: []int{2}, Positions: []int{20}},
+			},
+		},
+		{
+			name: "no windows",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := knowledgebase.MergeWindows(tt.windows)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeWindows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
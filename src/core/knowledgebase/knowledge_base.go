@@ -2,21 +2,32 @@ package knowledgebase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/snowflake"
 	"github.com/weaviate/weaviate/entities/models"
+	"golang.org/x/sync/errgroup"
 
+	"raggo/src/core/languagedetect"
+	"raggo/src/core/queryexpansion"
 	"raggo/src/infrastructure/integrations/ollama"
 	"raggo/src/infrastructure/log"
-	"raggo/src/storage/minioctrl"
 	"raggo/src/storage/postgres/chunkctrl"
 	"raggo/src/storage/postgres/resourcectrl"
+	"raggo/src/storage/postgres/translatedchunkctrl"
+	"raggo/src/storage/postgres/translatedresourcectrl"
 	"raggo/src/storage/weaviate"
 )
 
+// ErrNoRelevantContext is returned by queries that find no chunk close enough to the query
+var ErrNoRelevantContext = errors.New("no relevant context found")
+
 type KnowledgeBase struct {
 	ID             int64     `json:"id"`
 	Name           string    `json:"name"`
@@ -33,6 +44,9 @@ type KnowledgeBaseResource struct {
 	ChunkID            int64     `json:"chunk_id"`
 	Title              string    `json:"title"`
 	ContextDescription string    `json:"context_description"`
+	ContentHash        string    `json:"content_hash"`
+	TranslatedChunkID  *int64    `json:"translated_chunk_id,omitempty"` // set for the translation of chunk ChunkID, nil for the original
+	Language           string    `json:"language,omitempty"`
 	CreatedAt          time.Time `json:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at"`
 }
@@ -44,6 +58,45 @@ type PostgresRepository interface {
 	CreateKnowledgeBase(ctx context.Context, kb *KnowledgeBase) error
 	AddResource(ctx context.Context, resource *KnowledgeBaseResource) error
 	GetKnowledgeBase(ctx context.Context, id int64) (*KnowledgeBase, error)
+	HasContentHash(ctx context.Context, knowledgeBaseID int64, contentHash string) (bool, error)
+	HasChunk(ctx context.Context, knowledgeBaseID int64, chunkID int64) (bool, error)
+	GetResourceByContentHash(ctx context.Context, knowledgeBaseID int64, contentHash string, excludedChunkIDs []int64) (*KnowledgeBaseResource, error)
+	ListResourcesByChunkIDs(ctx context.Context, knowledgeBaseID int64, chunkIDs []int64) ([]KnowledgeBaseResource, error)
+	ListResourcesByResourceID(ctx context.Context, knowledgeBaseID int64, resourceID int64) ([]KnowledgeBaseResource, error)
+	HasTranslatedChunk(ctx context.Context, knowledgeBaseID int64, translatedChunkID int64) (bool, error)
+	ListLanguages(ctx context.Context, knowledgeBaseID int64) ([]string, error)
+	ListKnowledgeBaseIDsByResourceID(ctx context.Context, resourceID int64) ([]int64, error)
+	DeleteResourcesByChunkIDs(ctx context.Context, knowledgeBaseID int64, chunkIDs []int64) error
+	DeleteResourcesByResourceID(ctx context.Context, knowledgeBaseID int64, resourceID int64) error
+	DeleteResources(ctx context.Context, knowledgeBaseID int64) error
+}
+
+// ResourceService is the part of resourcectrl.ResourceService a knowledge base uses
+type ResourceService interface {
+	GetByID(ctx context.Context, id int64) (*resourcectrl.Resource, error)
+}
+
+// ChunkService is the part of chunkctrl.ChunkService a knowledge base uses
+type ChunkService interface {
+	GetByID(ctx context.Context, id int64) (*chunkctrl.Chunk, error)
+	GetByResourceID(ctx context.Context, resourceID int64) ([]chunkctrl.Chunk, error)
+}
+
+// TranslatedResourceService is the part of translatedresourcectrl.TranslatedResourceService a knowledge base uses
+type TranslatedResourceService interface {
+	GetByOriginalID(ctx context.Context, originalID int64) ([]translatedresourcectrl.TranslatedResource, error)
+}
+
+// TranslatedChunkService is the part of translatedchunkctrl.TranslatedChunkService a knowledge base uses
+type TranslatedChunkService interface {
+	GetByID(ctx context.Context, id int64) (*translatedchunkctrl.TranslatedChunk, error)
+	GetByTranslatedResourceID(ctx context.Context, translatedResourceID int64) ([]translatedchunkctrl.TranslatedChunk, error)
+}
+
+// ObjectStorage is the part of minioctrl.MinioService a knowledge base uses
+type ObjectStorage interface {
+	GetObject(ctx context.Context, bucketName, objectName string) ([]byte, error)
+	GetBucketAndObjectFromURL(minioURL string) (string, string)
 }
 
 // Service coordinates operations between PostgreSQL, Weaviate, Ollama and MinIO
@@ -52,12 +105,26 @@ type Service struct {
 	snowflake       *snowflake.Node
 	weaviateSDK     *weaviate.SDK
 	ollamaClient    *ollama.Client
-	minioService    *minioctrl.MinioService
-	resourceService *resourcectrl.ResourceService
-	chunkService    *chunkctrl.ChunkService
+	minioService    ObjectStorage
+	resourceService ResourceService
+	chunkService    ChunkService
+	// translations of the resources, indexed on request next to their originals
+	translatedResourceSvc TranslatedResourceService
+	translatedChunkSvc    TranslatedChunkService
+	// Weaviate classes whose schema was ensured by this process
+	ensuredClasses sync.Map
 }
 
-func NewService(postgresRepo PostgresRepository, weaviateSDK *weaviate.SDK, ollamaClient *ollama.Client, minioService *minioctrl.MinioService, resourceService *resourcectrl.ResourceService, chunkService *chunkctrl.ChunkService) (*Service, error) {
+func NewService(
+	postgresRepo PostgresRepository,
+	weaviateSDK *weaviate.SDK,
+	ollamaClient *ollama.Client,
+	minioService ObjectStorage,
+	resourceService ResourceService,
+	chunkService ChunkService,
+	translatedResourceSvc TranslatedResourceService,
+	translatedChunkSvc TranslatedChunkService,
+) (*Service, error) {
 	// Initialize snowflake node
 	node, err := snowflake.NewNode(1)
 	if err != nil {
@@ -65,13 +132,15 @@ func NewService(postgresRepo PostgresRepository, weaviateSDK *weaviate.SDK, olla
 	}
 
 	return &Service{
-		postgresRepo:    postgresRepo,
-		snowflake:       node,
-		weaviateSDK:     weaviateSDK,
-		ollamaClient:    ollamaClient,
-		minioService:    minioService,
-		resourceService: resourceService,
-		chunkService:    chunkService,
+		postgresRepo:          postgresRepo,
+		snowflake:             node,
+		weaviateSDK:           weaviateSDK,
+		ollamaClient:          ollamaClient,
+		minioService:          minioService,
+		resourceService:       resourceService,
+		chunkService:          chunkService,
+		translatedResourceSvc: translatedResourceSvc,
+		translatedChunkSvc:    translatedChunkSvc,
 	}, nil
 }
 
@@ -80,6 +149,11 @@ func (s *Service) ListKnowledgeBases(ctx context.Context, offset, limit int) ([]
 	return s.postgresRepo.ListKnowledgeBases(ctx, offset, limit)
 }
 
+// GetKnowledgeBase returns the knowledge base with the given ID, nil if there is none
+func (s *Service) GetKnowledgeBase(ctx context.Context, id int64) (*KnowledgeBase, error) {
+	return s.postgresRepo.GetKnowledgeBase(ctx, id)
+}
+
 // ListKnowledgeBaseResources returns a paginated list of resources in a knowledge base
 func (s *Service) ListKnowledgeBaseResources(ctx context.Context, knowledgeBaseID int64, offset, limit int) ([]KnowledgeBaseResource, error) {
 	return s.postgresRepo.ListKnowledgeBaseResources(ctx, knowledgeBaseID, offset, limit)
@@ -90,8 +164,18 @@ func getWeaviateClassName(knowledgeBaseID int64) string {
 	return fmt.Sprintf("KnowledgeBaseResource_%d", knowledgeBaseID)
 }
 
+// AddResourceOptions controls how the chunks of a resource are indexed
+type AddResourceOptions struct {
+	// TableSummaryModel is the LLM used to summarize table chunks before embedding.
+	// When empty, table chunks are embedded from their Markdown representation only.
+	TableSummaryModel string
+	// IncludeTranslations also indexes the translated chunks of the resource, linked to their original chunks.
+	// Translations that are already indexed are skipped, so adding the resource again picks up new translations.
+	IncludeTranslations bool
+}
+
 // AddResourceToKnowledgeBase implements the business logic for adding a resource to a knowledge base
-func (s *Service) AddResourceToKnowledgeBase(ctx context.Context, knowledgeBaseID int64, resourceID int64) error {
+func (s *Service) AddResourceToKnowledgeBase(ctx context.Context, knowledgeBaseID int64, resourceID int64, opts AddResourceOptions) error {
 	// Get resource metadata
 	resource, err := s.resourceService.GetByID(ctx, resourceID)
 	if err != nil {
@@ -116,16 +200,39 @@ func (s *Service) AddResourceToKnowledgeBase(ctx context.Context, knowledgeBaseI
 		return fmt.Errorf("knowledge base not found: %d", knowledgeBaseID)
 	}
 
+	if err := s.indexChunks(ctx, kb, resource, chunks, opts); err != nil {
+		return err
+	}
+	if opts.IncludeTranslations {
+		return s.indexTranslations(ctx, kb, resource, chunks)
+	}
+	return nil
+}
+
+// indexChunks embeds the chunks of a resource and stores them in the knowledge base
+func (s *Service) indexChunks(ctx context.Context, kb *KnowledgeBase, resource *resourcectrl.Resource, chunks []chunkctrl.Chunk, opts AddResourceOptions) error {
+	knowledgeBaseID := kb.ID
+	resourceID := resource.ID
+
 	className := getWeaviateClassName(knowledgeBaseID)
 	if s.weaviateSDK != nil {
 		// Ensure schema exists in Weaviate
-		if err = s.ensureWeaviateSchema(ctx, className); err != nil {
+		if err := s.ensureWeaviateSchema(ctx, className); err != nil {
 			return fmt.Errorf("failed to ensure Weaviate schema: %v", err)
 		}
 	}
 
 	// Process each chunk
 	for _, chunk := range chunks {
+		// A chunk is indexed once, so adding a resource again only picks up its new chunks
+		indexed, err := s.postgresRepo.HasChunk(ctx, knowledgeBaseID, chunk.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check for indexed chunk: %v", err)
+		}
+		if indexed {
+			continue
+		}
+
 		// Get chunk content from MinIO
 		// MinioURL format is "bucket/objectKey"
 		parts := strings.Split(chunk.MinioURL, "/")
@@ -137,11 +244,6 @@ func (s *Service) AddResourceToKnowledgeBase(ctx context.Context, knowledgeBaseI
 			return fmt.Errorf("failed to get chunk content: %v", err)
 		}
 
-		embedding, err := s.ollamaClient.GetEmbedding(ctx, kb.EmbeddingModel, string(content))
-		if err != nil {
-			return fmt.Errorf("failed to generate embedding: %v", err)
-		}
-
 		// Create knowledge base resource
 		kbResource := &KnowledgeBaseResource{
 			ID:              s.snowflake.Generate().Int64(),
@@ -149,41 +251,356 @@ func (s *Service) AddResourceToKnowledgeBase(ctx context.Context, knowledgeBaseI
 			ResourceID:      resourceID,
 			ChunkID:         chunk.ID,
 			Title:           fmt.Sprintf("%s - Part %d", resource.Filename, chunk.Order), // TODO: chunks table add order column
+			ContentHash:     chunk.ContentHash,
+			Language:        chunk.Language,
+		}
+		if kbResource.Language == "" {
+			// The language is detected by translations with an automatic source language, otherwise here
+			kbResource.Language = languagedetect.Detect(string(content)).Language
+		}
+
+		// The knowledge base has a single embedding model, so a chunk with the same content already indexed here
+		// already has the vector we would compute. The chunk is recorded without one and shares that vector.
+		if chunk.ContentHash != "" {
+			duplicate, err := s.postgresRepo.HasContentHash(ctx, knowledgeBaseID, chunk.ContentHash)
+			if err != nil {
+				return fmt.Errorf("failed to check for duplicate chunk: %v", err)
+			}
+			if duplicate {
+				log.Info("Sharing vector of duplicate chunk", "chunk_id", chunk.ID, "content_hash", chunk.ContentHash)
+				if err := s.postgresRepo.AddResource(ctx, kbResource); err != nil {
+					return fmt.Errorf("failed to add resource to knowledge base: %v", err)
+				}
+				continue
+			}
+		}
+
+		embeddingText := string(content)
+		if chunk.IsTable() {
+			embeddingText, err = s.prepareTableEmbeddingText(ctx, chunk, opts.TableSummaryModel, kbResource)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Figures without caption have nothing to be searched by
+		if strings.TrimSpace(embeddingText) == "" {
+			continue
+		}
+
+		embedding, err := s.ollamaClient.GetEmbedding(ctx, kb.EmbeddingModel, embeddingText)
+		if err != nil {
+			return fmt.Errorf("failed to generate embedding: %v", err)
+		}
+
+		if err := s.storeResource(ctx, className, kbResource, chunk.Kind, embedding); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// indexTranslations embeds the translated chunks of a resource next to their originals. A translation is indexed
+// under the ID of its original chunk, so retrieval returns every chunk once, in the language that matched best.
+func (s *Service) indexTranslations(ctx context.Context, kb *KnowledgeBase, resource *resourcectrl.Resource, chunks []chunkctrl.Chunk) error {
+	translatedResources, err := s.translatedResourceSvc.GetByOriginalID(ctx, resource.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get translations: %v", err)
+	}
+
+	chunksByID := make(map[int64]chunkctrl.Chunk, len(chunks))
+	for _, chunk := range chunks {
+		chunksByID[chunk.ID] = chunk
+	}
+
+	className := getWeaviateClassName(kb.ID)
+	for _, tr := range translatedResources {
+		translatedChunks, err := s.translatedChunkSvc.GetByTranslatedResourceID(ctx, tr.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get translated chunks: %v", err)
+		}
+
+		indexed := 0
+		for _, tc := range translatedChunks {
+			// Rejected translations are not fit to be retrieved
+			chunk, ok := chunksByID[tc.OriginalChunkID]
+			if !ok || tc.IsRejected() {
+				continue
+			}
+			exists, err := s.postgresRepo.HasTranslatedChunk(ctx, kb.ID, tc.ID)
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+
+			bucket, objectName := s.minioService.GetBucketAndObjectFromURL(tc.FinalMinioURL())
+			content, err := s.minioService.GetObject(ctx, bucket, objectName)
+			if err != nil {
+				return fmt.Errorf("failed to get translated chunk content: %v", err)
+			}
+			if strings.TrimSpace(string(content)) == "" {
+				continue
+			}
+
+			embedding, err := s.ollamaClient.GetEmbedding(ctx, kb.EmbeddingModel, string(content))
+			if err != nil {
+				return fmt.Errorf("failed to generate embedding: %v", err)
+			}
+
+			translatedChunkID := tc.ID
+			kbResource := &KnowledgeBaseResource{
+				ID:                s.snowflake.Generate().Int64(),
+				KnowledgeBaseID:   kb.ID,
+				ResourceID:        resource.ID,
+				ChunkID:           chunk.ID,
+				TranslatedChunkID: &translatedChunkID,
+				Title:             fmt.Sprintf("%s (%s) - Part %d", resource.Filename, tr.TargetLanguage, chunk.Order),
+				Language:          tr.TargetLanguage,
+			}
+			if err := s.storeResource(ctx, className, kbResource, chunk.Kind, embedding); err != nil {
+				return err
+			}
+			indexed++
+		}
+
+		log.Info("Indexed translated chunks", "knowledge_base_id", kb.ID, "translated_resource_id", tr.ID, "chunks", indexed)
+	}
+
+	return nil
+}
+
+// storeResource stores the knowledge base resource in PostgreSQL and its vector in Weaviate
+func (s *Service) storeResource(ctx context.Context, className string, kbResource *KnowledgeBaseResource, kind string, embedding []float32) error {
+	// Store in PostgreSQL
+	if err := s.postgresRepo.AddResource(ctx, kbResource); err != nil {
+		return fmt.Errorf("failed to add resource to knowledge base: %v", err)
+	}
+
+	// Store vector in Weaviate
+	if s.weaviateSDK != nil {
+		// Convert properties to map for Weaviate
+		props := map[string]interface{}{
+			"knowledgeBaseId": kbResource.KnowledgeBaseID,
+			"resourceId":      kbResource.ResourceID,
+			"chunkId":         kbResource.ChunkID,
+			"title":           kbResource.Title,
+			"description":     kbResource.ContextDescription,
+			"kind":            kind,
+			"language":        kbResource.Language,
+		}
+		if kbResource.TranslatedChunkID != nil {
+			props["translatedChunkId"] = *kbResource.TranslatedChunkID
+		}
+
+		// Create object with vector
+		vectorObj := weaviate.VectorObject{
+			Vector:     embedding,
+			Properties: props,
+		}
+
+		if err := s.weaviateSDK.AddVector(ctx, className, vectorObj); err != nil {
+			return fmt.Errorf("failed to store vector: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// IndexResourceChunks adds newly created chunks of a resource to every knowledge base that already includes the resource
+func (s *Service) IndexResourceChunks(ctx context.Context, resourceID int64, chunks []chunkctrl.Chunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	resource, err := s.resourceService.GetByID(ctx, resourceID)
+	if err != nil {
+		return fmt.Errorf("failed to get resource: %v", err)
+	}
+	if resource == nil {
+		return fmt.Errorf("resource not found: %d", resourceID)
+	}
+
+	knowledgeBaseIDs, err := s.postgresRepo.ListKnowledgeBaseIDsByResourceID(ctx, resourceID)
+	if err != nil {
+		return err
+	}
+
+	for _, knowledgeBaseID := range knowledgeBaseIDs {
+		kb, err := s.postgresRepo.GetKnowledgeBase(ctx, knowledgeBaseID)
+		if err != nil {
+			return fmt.Errorf("failed to get knowledge base: %v", err)
+		}
+		if kb == nil {
+			continue
 		}
 
-		// Store in PostgreSQL
-		if err = s.postgresRepo.AddResource(ctx, kbResource); err != nil {
-			return fmt.Errorf("failed to add resource to knowledge base: %v", err)
+		if err := s.indexChunks(ctx, kb, resource, chunks, AddResourceOptions{}); err != nil {
+			return fmt.Errorf("failed to index chunks in knowledge base %d: %v", knowledgeBaseID, err)
 		}
+	}
 
-		// Store vector in Weaviate
+	return nil
+}
+
+// RemoveResourceChunks removes the rows and vectors of the given chunks from every knowledge base that includes the resource
+func (s *Service) RemoveResourceChunks(ctx context.Context, resourceID int64, chunkIDs []int64) error {
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+
+	knowledgeBaseIDs, err := s.postgresRepo.ListKnowledgeBaseIDsByResourceID(ctx, resourceID)
+	if err != nil {
+		return err
+	}
+
+	for _, knowledgeBaseID := range knowledgeBaseIDs {
 		if s.weaviateSDK != nil {
-			// Convert properties to map for Weaviate
-			props := map[string]interface{}{
-				"knowledgeBaseId": kbResource.KnowledgeBaseID,
-				"resourceId":      kbResource.ResourceID,
-				"chunkId":         kbResource.ChunkID,
-				"title":           kbResource.Title,
-				"description":     kbResource.ContextDescription,
+			removed, err := s.postgresRepo.ListResourcesByChunkIDs(ctx, knowledgeBaseID, chunkIDs)
+			if err != nil {
+				return err
+			}
+			if err := s.handOverVectors(ctx, knowledgeBaseID, removed); err != nil {
+				return err
 			}
 
-			// Create object with vector
-			vectorObj := weaviate.VectorObject{
-				Vector:     embedding,
-				Properties: props,
+			className := getWeaviateClassName(knowledgeBaseID)
+			if err := s.weaviateSDK.DeleteVectorsByNumberProperty(ctx, className, "chunkId", chunkIDs); err != nil {
+				return fmt.Errorf("failed to delete vectors from knowledge base %d: %v", knowledgeBaseID, err)
 			}
+		}
+
+		if err := s.postgresRepo.DeleteResourcesByChunkIDs(ctx, knowledgeBaseID, chunkIDs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveResource removes the rows and vectors of a resource from every knowledge base that includes it
+func (s *Service) RemoveResource(ctx context.Context, resourceID int64) error {
+	knowledgeBaseIDs, err := s.postgresRepo.ListKnowledgeBaseIDsByResourceID(ctx, resourceID)
+	if err != nil {
+		return err
+	}
+
+	for _, knowledgeBaseID := range knowledgeBaseIDs {
+		if s.weaviateSDK != nil {
+			removed, err := s.postgresRepo.ListResourcesByResourceID(ctx, knowledgeBaseID, resourceID)
+			if err != nil {
+				return err
+			}
+			if err := s.handOverVectors(ctx, knowledgeBaseID, removed); err != nil {
+				return err
+			}
+
+			className := getWeaviateClassName(knowledgeBaseID)
+			if err := s.weaviateSDK.DeleteVectorsByNumberProperty(ctx, className, "resourceId", []int64{resourceID}); err != nil {
+				return fmt.Errorf("failed to delete vectors from knowledge base %d: %v", knowledgeBaseID, err)
+			}
+		}
+
+		if err := s.postgresRepo.DeleteResourcesByResourceID(ctx, knowledgeBaseID, resourceID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handOverVectors moves the vector of every removed chunk whose content is still held by another chunk of the
+// knowledge base to the earliest of those chunks, so that shared content stays searchable after the removed rows
+// and their vectors are deleted. A vector is only deleted with the last chunk pointing to it.
+func (s *Service) handOverVectors(ctx context.Context, knowledgeBaseID int64, removed []KnowledgeBaseResource) error {
+	var removedChunkIDs []int64
+	for _, r := range removed {
+		if r.TranslatedChunkID == nil {
+			removedChunkIDs = append(removedChunkIDs, r.ChunkID)
+		}
+	}
 
-			if err = s.weaviateSDK.AddVector(ctx, className, vectorObj); err != nil {
-				return fmt.Errorf("failed to store vector: %v", err)
+	className := getWeaviateClassName(knowledgeBaseID)
+	for _, r := range removed {
+		if r.ContentHash == "" || r.TranslatedChunkID != nil {
+			continue
+		}
+		heir, err := s.postgresRepo.GetResourceByContentHash(ctx, knowledgeBaseID, r.ContentHash, removedChunkIDs)
+		if err != nil {
+			return err
+		}
+		if heir == nil {
+			continue
+		}
+
+		// Only the chunk that was indexed first holds the vector; translations indexed under it are removed with it
+		if err := s.ensureWeaviateSchema(ctx, className); err != nil {
+			return fmt.Errorf("failed to ensure Weaviate schema: %v", err)
+		}
+		objects, err := s.weaviateSDK.FindByNumberProperty(ctx, className, "chunkId", r.ChunkID, []string{"translatedChunkId"})
+		if err != nil {
+			return fmt.Errorf("failed to find vector of chunk %d: %v", r.ChunkID, err)
+		}
+		for _, object := range objects {
+			if object.Properties["translatedChunkId"] != nil {
+				continue
+			}
+			err := s.weaviateSDK.MergeProperties(ctx, className, object.ID, map[string]interface{}{
+				"resourceId": heir.ResourceID,
+				"chunkId":    heir.ChunkID,
+				"title":      heir.Title,
+				"language":   heir.Language,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to hand over vector of chunk %d: %v", r.ChunkID, err)
 			}
+			log.Info("Handed over shared vector", "knowledge_base_id", knowledgeBaseID, "from_chunk_id", r.ChunkID, "to_chunk_id", heir.ChunkID)
 		}
 	}
 
 	return nil
 }
 
-// ensureWeaviateSchema ensures the required schema exists in Weaviate
+// ListResourceKnowledgeBaseIDs returns the knowledge bases that include the resource
+func (s *Service) ListResourceKnowledgeBaseIDs(ctx context.Context, resourceID int64) ([]int64, error) {
+	return s.postgresRepo.ListKnowledgeBaseIDsByResourceID(ctx, resourceID)
+}
+
+// prepareTableEmbeddingText returns the text used to embed a table chunk.
+// The Markdown representation keeps the row/column structure, and when a summary model is given
+// the LLM generated summary is prepended and stored as the context description of the resource.
+func (s *Service) prepareTableEmbeddingText(ctx context.Context, chunk chunkctrl.Chunk, summaryModel string, kbResource *KnowledgeBaseResource) (string, error) {
+	bucket, objectName := s.minioService.GetBucketAndObjectFromURL(chunk.TableMarkdownMinioURL)
+	markdown, err := s.minioService.GetObject(ctx, bucket, objectName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get table markdown: %v", err)
+	}
+
+	if summaryModel == "" {
+		return string(markdown), nil
+	}
+
+	prompt := strings.Replace(TABLE_SUMMARY_PROMPT, "{table_content}", string(markdown), 1)
+	summary, err := s.ollamaClient.Generate(ctx, summaryModel, "", prompt, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate table summary: %v", err)
+	}
+	summary = strings.TrimSpace(summary)
+	kbResource.ContextDescription = summary
+
+	return summary + "\n\n" + string(markdown), nil
+}
+
+// ensureWeaviateSchema ensures the required schema exists in Weaviate. A class created before some of the
+// properties were introduced gets the missing ones, so queries can request every property. A class is checked
+// once per process.
 func (s *Service) ensureWeaviateSchema(ctx context.Context, className string) error {
+	if _, ok := s.ensuredClasses.Load(className); ok {
+		return nil
+	}
+
 	properties := []*models.Property{
 		{
 			Name:            "knowledgeBaseId",
@@ -210,26 +627,140 @@ func (s *Service) ensureWeaviateSchema(ctx context.Context, className string) er
 			DataType:        []string{"text"},
 			IndexFilterable: &[]bool{true}[0],
 		},
+		{
+			Name:            "kind",
+			DataType:        []string{"string"},
+			IndexFilterable: &[]bool{true}[0],
+		},
+		{
+			Name:            "language",
+			DataType:        []string{"string"},
+			IndexFilterable: &[]bool{true}[0],
+		},
+		{
+			Name:            "translatedChunkId",
+			DataType:        []string{"number"},
+			IndexFilterable: &[]bool{true}[0],
+		},
 	}
 
 	err := s.weaviateSDK.CreateSchema(ctx, className, properties, "none")
-	if err != nil && !strings.Contains(err.Error(), "already exists") {
-		return err
+	if err != nil {
+		if !strings.Contains(err.Error(), "already exists") {
+			return err
+		}
+		if err := s.weaviateSDK.AddMissingProperties(ctx, className, properties); err != nil {
+			return err
+		}
 	}
+	s.ensuredClasses.Store(className, true)
 	return nil
 }
 
 // QueryResult represents a single result from the knowledge base query
 type QueryResult struct {
-	ChunkID     int64   `json:"chunk_id"`
-	ResourceID  int64   `json:"resource_id"`
-	Score       float64 `json:"score"`
-	Content     string  `json:"content"`
-	Description string  `json:"description"`
-	MinioURL    string  `json:"minio_url"`
+	ChunkID       int64   `json:"chunk_id"`
+	ResourceID    int64   `json:"resource_id"`
+	Kind          string  `json:"kind"`
+	Score         float64 `json:"score"` // vector distance, lower is closer
+	Content       string  `json:"content"`
+	Description   string  `json:"description"`
+	MinioURL      string  `json:"minio_url"`
+	TableHTML     string  `json:"table_html,omitempty"`
+	TableMarkdown string  `json:"table_markdown,omitempty"`
+	ImageMinioURL string  `json:"image_minio_url,omitempty"`
+	// Language of the content; when TranslatedChunkID is set the content is that translation of chunk ChunkID
+	Language          string `json:"language,omitempty"`
+	TranslatedChunkID int64  `json:"translated_chunk_id,omitempty"`
+	// Query is the query, its expansion or its translation that found the content
+	Query string `json:"query,omitempty"`
+	// FusionScore is the reciprocal rank fusion score of a query expansion mode that fuses rankings, higher is better
+	FusionScore float64 `json:"fusion_score,omitempty"`
+	// KnowledgeBaseID is the knowledge base the chunk was found in; NormalizedScore ranks the results of
+	// different knowledge bases against each other from 0 to 1, see NormalizeScores
+	KnowledgeBaseID int64   `json:"knowledge_base_id"`
+	NormalizedScore float64 `json:"normalized_score"`
+	// ContextChunkIDs are the chunks, in document order, whose content makes up Content after context expansion
+	ContextChunkIDs []int64 `json:"context_chunk_ids,omitempty"`
+}
+
+// QueryOptions controls how a knowledge base is queried
+type QueryOptions struct {
+	// TranslationModel translates the query into the other languages of the knowledge base,
+	// so that chunks are found whatever their language. Empty to search with the query as given.
+	TranslationModel string
+	// Expansion transforms the query before it is searched, one of the queryexpansion modes; ExpansionModel writes
+	// the transformed queries. Without either the query is searched as it is.
+	Expansion      string
+	ExpansionModel string
+	// Context widens every hit into coherent context, one of the context expansion modes: ContextNeighbors adds
+	// Neighbors chunks on either side by chunk order, ContextPage returns the page of the hit. Overlapping windows
+	// of a resource are merged and every chunk is returned once.
+	Context   string
+	Neighbors int
+}
+
+// DeduplicateByChunk keeps the closest result of every chunk, so a chunk found in several languages
+// is returned once in its best matching language. The results are ordered from closest to farthest.
+func DeduplicateByChunk(results []QueryResult) []QueryResult {
+	best := make(map[int64]int, len(results))
+	var deduplicated []QueryResult
+	for _, result := range results {
+		i, ok := best[result.ChunkID]
+		if !ok {
+			best[result.ChunkID] = len(deduplicated)
+			deduplicated = append(deduplicated, result)
+			continue
+		}
+		if result.Score < deduplicated[i].Score {
+			deduplicated[i] = result
+		}
+	}
+
+	sort.SliceStable(deduplicated, func(i, j int) bool {
+		return deduplicated[i].Score < deduplicated[j].Score
+	})
+	return deduplicated
+}
+
+// FuseByChunk fuses the rankings of several variants of a query with reciprocal rank fusion. Every chunk is
+// returned once, in the language that matched best, ordered by its fusion score.
+func FuseByChunk(rankings [][]QueryResult) []QueryResult {
+	fused := queryexpansion.ReciprocalRankFusion(rankings,
+		func(result QueryResult) int64 { return result.ChunkID },
+		func(current, candidate QueryResult) bool { return candidate.Score < current.Score },
+	)
+
+	results := make([]QueryResult, 0, len(fused))
+	for _, f := range fused {
+		result := f.Item
+		result.FusionScore = f.Score
+		results = append(results, result)
+	}
+	return results
 }
 
-// ResetWeaviateContent deletes and recreates the Weaviate schema for a knowledge base
+// NormalizeScores sets the normalized score of the results of one knowledge base on an absolute scale, so that
+// a weak best hit of one knowledge base does not outrank a strong hit of another. A cosine distance, which ranges
+// from 0 to 2, is mapped onto its certainty, 1 for identical vectors and 0 for opposite ones. If the results were
+// fused from fusedRankings rankings, their fusion score is divided by the highest score reciprocal rank fusion
+// can give, that of a result ranked first in every ranking.
+func NormalizeScores(results []QueryResult, fusedRankings int) {
+	if fusedRankings == 0 {
+		for i := range results {
+			results[i].NormalizedScore = math.Max(0, math.Min(1, 1-results[i].Score/2))
+		}
+		return
+	}
+
+	highest := float64(fusedRankings) / float64(queryexpansion.RRFConstant+1)
+	for i := range results {
+		results[i].NormalizedScore = math.Min(1, results[i].FusionScore/highest)
+	}
+}
+
+// ResetWeaviateContent deletes and recreates the Weaviate schema for a knowledge base. The resources of the
+// knowledge base are removed with their vectors, so adding them again indexes them from scratch.
 func (s *Service) ResetWeaviateContent(ctx context.Context, knowledgeBaseID int64) error {
 	if s.weaviateSDK == nil {
 		return fmt.Errorf("weaviate is not configured")
@@ -251,6 +782,11 @@ func (s *Service) ResetWeaviateContent(ctx context.Context, knowledgeBaseID int6
 	if err != nil {
 		return fmt.Errorf("failed to delete Weaviate schema: %v", err)
 	}
+	s.ensuredClasses.Delete(className)
+
+	if err := s.postgresRepo.DeleteResources(ctx, knowledgeBaseID); err != nil {
+		return fmt.Errorf("failed to delete knowledge base resources: %v", err)
+	}
 
 	// Recreate schema
 	err = s.ensureWeaviateSchema(ctx, className)
@@ -261,31 +797,171 @@ func (s *Service) ResetWeaviateContent(ctx context.Context, knowledgeBaseID int6
 	return nil
 }
 
-// QueryKnowledgeBase implements the RAG query logic
-func (s *Service) QueryKnowledgeBase(ctx context.Context, knowledgeBaseID int64, query string) ([]QueryResult, error) {
+// QueryKnowledgeBase implements the RAG query logic. The query is searched as given, or as transformed by the
+// expansion mode, and, with a translation model, in each other language of the knowledge base. Every chunk is
+// returned once, in the language that matched best; the rankings of an expansion mode that searches several
+// variants of the query are fused with reciprocal rank fusion.
+func (s *Service) QueryKnowledgeBase(ctx context.Context, knowledgeBaseID int64, query string, opts QueryOptions) ([]QueryResult, error) {
+	return s.QueryKnowledgeBases(ctx, []int64{knowledgeBaseID}, query, opts)
+}
+
+// QueryKnowledgeBases searches several knowledge bases at once like QueryKnowledgeBase. The query is embedded once
+// per distinct embedding model and the classes of the knowledge bases are searched concurrently. The results of
+// every knowledge base get a normalized score, see NormalizeScores, and are merged into one list, best first,
+// tagged with their knowledge base. Finally the hits are widened into context windows if the options ask for it.
+func (s *Service) QueryKnowledgeBases(ctx context.Context, knowledgeBaseIDs []int64, query string, opts QueryOptions) ([]QueryResult, error) {
 	if s.weaviateSDK == nil {
 		return nil, fmt.Errorf("weaviate is not configured")
 	}
+	if !queryexpansion.Valid(opts.Expansion) {
+		return nil, fmt.Errorf("unknown query expansion mode: %s", opts.Expansion)
+	}
+	if !ValidContext(opts.Context) {
+		return nil, fmt.Errorf("unknown context expansion mode: %s", opts.Context)
+	}
 
-	// Get knowledge base to determine embedding model
-	kb, err := s.postgresRepo.GetKnowledgeBase(ctx, knowledgeBaseID)
+	// Get knowledge bases to determine their embedding models
+	var kbs []*KnowledgeBase
+	seen := make(map[int64]bool, len(knowledgeBaseIDs))
+	for _, id := range knowledgeBaseIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		kb, err := s.postgresRepo.GetKnowledgeBase(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get knowledge base: %v", err)
+		}
+		if kb == nil {
+			return nil, fmt.Errorf("knowledge base not found: %d", id)
+		}
+		kbs = append(kbs, kb)
+	}
+	if len(kbs) == 0 {
+		return nil, fmt.Errorf("no knowledge base to query")
+	}
+
+	queries, err := s.translateQuery(ctx, kbs, query, opts.TranslationModel)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get knowledge base: %v", err)
+		return nil, err
 	}
-	if kb == nil {
-		return nil, fmt.Errorf("knowledge base not found: %d", knowledgeBaseID)
+	// The expansions take the place of the query; its translations are searched next to them
+	queries = append(s.expandQuery(ctx, query, opts), queries[1:]...)
+
+	embeddings, err := s.embedQueries(ctx, kbs, queries)
+	if err != nil {
+		return nil, err
+	}
+
+	// Search every variant of the query in every knowledge base in parallel, each into a ranking of its own
+	rankings := make([][][]QueryResult, len(kbs))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, kb := range kbs {
+		rankings[i] = make([][]QueryResult, len(queries))
+		for j, q := range queries {
+			g.Go(func() error {
+				ranking, err := s.searchQuery(gctx, kb, q, embeddings[kb.EmbeddingModel][j])
+				if err != nil {
+					return err
+				}
+				rankings[i][j] = ranking
+				return nil
+			})
+		}
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	var queryResults []QueryResult
+	fuse := queryexpansion.Fuses(opts.Expansion)
+	for i, kb := range kbs {
+		var kbResults []QueryResult
+		fusedRankings := 0
+		if fuse {
+			kbResults = FuseByChunk(rankings[i])
+			fusedRankings = len(rankings[i])
+		} else {
+			for _, ranking := range rankings[i] {
+				kbResults = append(kbResults, ranking...)
+			}
+			kbResults = DeduplicateByChunk(kbResults)
+		}
+		NormalizeScores(kbResults, fusedRankings)
+		for j := range kbResults {
+			kbResults[j].KnowledgeBaseID = kb.ID
+		}
+		queryResults = append(queryResults, kbResults...)
+	}
+	if len(queryResults) == 0 {
+		return nil, ErrNoRelevantContext
+	}
+
+	sort.SliceStable(queryResults, func(i, j int) bool {
+		if queryResults[i].NormalizedScore != queryResults[j].NormalizedScore {
+			return queryResults[i].NormalizedScore > queryResults[j].NormalizedScore
+		}
+		return queryResults[i].Score < queryResults[j].Score
+	})
+	return s.expandContext(ctx, queryResults, opts), nil
+}
+
+// embedQueries embeds every variant of the query once per distinct embedding model of the knowledge bases.
+// The embeddings are keyed by model and in the order of the queries.
+func (s *Service) embedQueries(ctx context.Context, kbs []*KnowledgeBase, queries []string) (map[string][][]float32, error) {
+	embeddings := make(map[string][][]float32)
+	for _, kb := range kbs {
+		if _, ok := embeddings[kb.EmbeddingModel]; !ok {
+			embeddings[kb.EmbeddingModel] = make([][]float32, len(queries))
+		}
 	}
 
-	// Get query embedding
-	embedding, err := s.ollamaClient.GetEmbedding(ctx, kb.EmbeddingModel, query)
+	g, gctx := errgroup.WithContext(ctx)
+	for model, vectors := range embeddings {
+		for i, q := range queries {
+			g.Go(func() error {
+				embedding, err := s.ollamaClient.GetEmbedding(gctx, model, q)
+				if err != nil {
+					return fmt.Errorf("failed to generate query embedding: %v", err)
+				}
+				vectors[i] = embedding
+				return nil
+			})
+		}
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return embeddings, nil
+}
+
+// expandQuery returns the texts to search for the query in the expansion mode of the options.
+// Without an expansion model, or if the expansion fails, the query is searched as it is.
+func (s *Service) expandQuery(ctx context.Context, query string, opts QueryOptions) []string {
+	if opts.Expansion == "" || opts.Expansion == queryexpansion.ModeNone || opts.ExpansionModel == "" {
+		return []string{query}
+	}
+
+	provider := ollama.NewOllamaProvider(s.ollamaClient, opts.ExpansionModel)
+	queries, err := queryexpansion.Expand(ctx, provider, opts.Expansion, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate query embedding: %v", err)
+		log.Error(err, "failed to expand query, searching it as it is", "mode", opts.Expansion)
+		return []string{query}
 	}
+	log.Info("Expanded query", "mode", opts.Expansion, "queries", len(queries))
+	return queries
+}
 
+// searchQuery returns the chunks of a knowledge base closest to one embedded variant of a query, closest first
+func (s *Service) searchQuery(ctx context.Context, kb *KnowledgeBase, query string, embedding []float32) ([]QueryResult, error) {
 	// Search similar vectors in Weaviate
-	className := getWeaviateClassName(knowledgeBaseID)
+	className := getWeaviateClassName(kb.ID)
+	if err := s.ensureWeaviateSchema(ctx, className); err != nil {
+		return nil, fmt.Errorf("failed to ensure Weaviate schema: %v", err)
+	}
 	config := weaviate.QueryConfig{
-		Fields:    []string{"chunkId", "description"},
+		Fields:    []string{"chunkId", "description", "language", "translatedChunkId"},
 		Limit:     20,
 		Certainty: 0.7, // Minimum similarity threshold
 	}
@@ -300,48 +976,151 @@ func (s *Service) QueryKnowledgeBase(ctx context.Context, knowledgeBaseID int64,
 	// Get chunk content from MinIO for each result
 	var queryResults []QueryResult
 	for _, result := range results {
-		if result.Properties["chunkId"] == nil {
+		queryResult, ok := s.loadQueryResult(ctx, result)
+		if !ok {
 			continue
 		}
-		chunkID := int64(result.Properties["chunkId"].(float64))
-		var description string
-		if result.Properties["description"] != nil {
-			description = result.Properties["description"].(string)
-		}
+		queryResult.Query = query
+		queryResults = append(queryResults, *queryResult)
+	}
 
-		chunk, err := s.chunkService.GetByID(ctx, chunkID)
+	sort.SliceStable(queryResults, func(i, j int) bool {
+		return queryResults[i].Score < queryResults[j].Score
+	})
+	return queryResults, nil
+}
+
+// translateQuery returns the query followed by its translations into the languages of the knowledge bases
+// other than the language of the query. A language the query cannot be translated into is searched without.
+func (s *Service) translateQuery(ctx context.Context, kbs []*KnowledgeBase, query, model string) ([]string, error) {
+	queries := []string{query}
+	if model == "" {
+		return queries, nil
+	}
+
+	var languages []string
+	for _, kb := range kbs {
+		kbLanguages, err := s.postgresRepo.ListLanguages(ctx, kb.ID)
 		if err != nil {
-			continue
+			return nil, err
 		}
+		languages = append(languages, kbLanguages...)
+	}
 
-		parts := strings.Split(chunk.MinioURL, "/")
-		if len(parts) != 2 {
+	// Variants of a language such as zh-TW and zh-CN are searched once
+	searched := map[string]bool{languagedetect.Normalize(languagedetect.Detect(query).Language): true}
+	for _, language := range languages {
+		if searched[languagedetect.Normalize(language)] {
 			continue
 		}
+		searched[languagedetect.Normalize(language)] = true
 
-		content, err := s.minioService.GetObject(ctx, parts[0], parts[1])
+		prompt := strings.NewReplacer("{language}", language, "{query}", query).Replace(QUERY_TRANSLATION_PROMPT)
+		translation, err := s.ollamaClient.Generate(ctx, model, "", prompt, nil)
 		if err != nil {
+			log.Error(err, "failed to translate query", "language", language)
 			continue
 		}
+		if translation = strings.TrimSpace(translation); translation != "" {
+			queries = append(queries, translation)
+		}
+	}
 
-		queryResults = append(queryResults, QueryResult{
-			ChunkID:     chunkID,
-			ResourceID:  chunk.ResourceID,
-			Score:       result.Score,
-			Content:     string(content),
-			Description: description,
-			MinioURL:    chunk.MinioURL,
-		})
+	return queries, nil
+}
+
+// loadQueryResult loads the chunk of a vector search result, or its translation if the translation was found.
+// It reports false for results whose chunk or translation no longer exists.
+func (s *Service) loadQueryResult(ctx context.Context, result weaviate.QueryResult) (*QueryResult, bool) {
+	if result.Properties["chunkId"] == nil {
+		return nil, false
+	}
+	chunkID := int64(result.Properties["chunkId"].(float64))
+	var description, language string
+	if result.Properties["description"] != nil {
+		description = result.Properties["description"].(string)
+	}
+	if result.Properties["language"] != nil {
+		language = result.Properties["language"].(string)
 	}
 
-	if len(queryResults) == 0 {
-		return nil, fmt.Errorf("no relevant context found")
+	chunk, err := s.chunkService.GetByID(ctx, chunkID)
+	if err != nil || chunk == nil {
+		return nil, false
 	}
 
-	return queryResults, nil
+	queryResult := &QueryResult{
+		ChunkID:       chunkID,
+		ResourceID:    chunk.ResourceID,
+		Kind:          chunk.Kind,
+		Score:         result.Score,
+		Description:   description,
+		MinioURL:      chunk.MinioURL,
+		ImageMinioURL: chunk.ImageMinioURL,
+		Language:      language,
+	}
+
+	if translatedChunkID, ok := result.Properties["translatedChunkId"].(float64); ok && translatedChunkID > 0 {
+		tc, err := s.translatedChunkSvc.GetByID(ctx, int64(translatedChunkID))
+		if err != nil || tc == nil || tc.IsRejected() {
+			return nil, false
+		}
+		queryResult.TranslatedChunkID = tc.ID
+		queryResult.MinioURL = tc.FinalMinioURL()
+	} else if chunk.IsTable() {
+		s.attachTableRepresentations(ctx, chunk, queryResult)
+	}
+
+	bucket, objectName := s.minioService.GetBucketAndObjectFromURL(queryResult.MinioURL)
+	content, err := s.minioService.GetObject(ctx, bucket, objectName)
+	if err != nil {
+		return nil, false
+	}
+	queryResult.Content = string(content)
+
+	return queryResult, true
+}
+
+// attachTableRepresentations loads the original HTML and Markdown of a table chunk into the query result
+func (s *Service) attachTableRepresentations(ctx context.Context, chunk *chunkctrl.Chunk, result *QueryResult) {
+	if chunk.TableHTMLMinioURL != "" {
+		bucket, objectName := s.minioService.GetBucketAndObjectFromURL(chunk.TableHTMLMinioURL)
+		if tableHTML, err := s.minioService.GetObject(ctx, bucket, objectName); err == nil {
+			result.TableHTML = string(tableHTML)
+		} else {
+			log.Error(err, "failed to get table html", "chunk_id", chunk.ID)
+		}
+	}
+
+	if chunk.TableMarkdownMinioURL != "" {
+		bucket, objectName := s.minioService.GetBucketAndObjectFromURL(chunk.TableMarkdownMinioURL)
+		if markdown, err := s.minioService.GetObject(ctx, bucket, objectName); err == nil {
+			result.TableMarkdown = string(markdown)
+		} else {
+			log.Error(err, "failed to get table markdown", "chunk_id", chunk.ID)
+		}
+	}
 }
 
 const (
+	TABLE_SUMMARY_PROMPT = `
+Here is a table extracted from a document, formatted as Markdown
+<table>
+{table_content}
+</table>
+
+Please give a short succinct summary of what this table describes, including its key columns and notable values, for the purposes of improving search retrieval of the table.
+Answer only with the summary and nothing else.
+`
+	QUERY_TRANSLATION_PROMPT = `
+Translate the following search query into the language with the code {language}.
+Keep names, product terms and numbers as they are.
+<query>
+{query}
+</query>
+
+Answer only with the translated query and nothing else.
+`
 	DOCUMENT_CONTEXT_PROMPT = `
 <document>
 {doc_content}
@@ -0,0 +1,422 @@
+package knowledgebase_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	weaviateclient "github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate/entities/models"
+
+	"raggo/src/core/knowledgebase"
+	"raggo/src/infrastructure/integrations/ollama"
+	"raggo/src/storage/postgres/chunkctrl"
+	"raggo/src/storage/postgres/resourcectrl"
+	"raggo/src/storage/weaviate"
+)
+
+func TestDeduplicateByChunk(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []knowledgebase.QueryResult
+		want    []knowledgebase.QueryResult
+	}{
+		{
+			name: "translation matches better than the original",
+			results: []knowledgebase.QueryResult{
+				{ChunkID: 1, Score: 0.2, Language: "en"},
+				{ChunkID: 2, Score: 0.25, Language: "en"},
+				{ChunkID: 1, Score: 0.1, Language: "ja", TranslatedChunkID: 11},
+			},
+			want: []knowledgebase.QueryResult{
+				{ChunkID: 1, Score: 0.1, Language: "ja", TranslatedChunkID: 11},
+				{ChunkID: 2, Score: 0.25, Language: "en"},
+			},
+		},
+		{
+			name: "original matches better than the translation",
+			results: []knowledgebase.QueryResult{
+				{ChunkID: 1, Score: 0.3, Language: "de", TranslatedChunkID: 12},
+				{ChunkID: 1, Score: 0.15, Language: "en"},
+			},
+			want: []knowledgebase.QueryResult{
+				{ChunkID: 1, Score: 0.15, Language: "en"},
+			},
+		},
+		{
+			name: "no results",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := knowledgebase.DeduplicateByChunk(tt.results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DeduplicateByChunk() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuseByChunk(t *testing.T) {
+	rankings := [][]knowledgebase.QueryResult{
+		{
+			{ChunkID: 1, Score: 0.1, Query: "qwen languages"},
+			{ChunkID: 2, Score: 0.2, Query: "qwen languages"},
+		},
+		{
+			{ChunkID: 2, Score: 0.15, Query: "Which languages does Qwen support?"},
+			{ChunkID: 3, Score: 0.3, Query: "Which languages does Qwen support?"},
+		},
+	}
+
+	got := knowledgebase.FuseByChunk(rankings)
+	var chunks []int64
+	for _, result := range got {
+		chunks = append(chunks, result.ChunkID)
+		if result.FusionScore <= 0 {
+			t.Errorf("chunk %d has fusion score %v", result.ChunkID, result.FusionScore)
+		}
+	}
+	if want := []int64{2, 1, 3}; !reflect.DeepEqual(chunks, want) {
+		t.Fatalf("FuseByChunk() chunks = %v, want %v", chunks, want)
+	}
+	if got[0].Score != 0.15 || got[0].Query != "Which languages does Qwen support?" {
+		t.Errorf("FuseByChunk() kept %+v for chunk 2, want the closest match", got[0])
+	}
+}
+
+func TestNormalizeScoresAcrossKnowledgeBases(t *testing.T) {
+	// The best hit of a knowledge base without a close match must not outrank a close match of another
+	weak := []knowledgebase.QueryResult{{ChunkID: 1, Score: 0.6}, {ChunkID: 2, Score: 0.7}}
+	strong := []knowledgebase.QueryResult{{ChunkID: 3, Score: 0.1}, {ChunkID: 4, Score: 0.8}}
+	knowledgebase.NormalizeScores(weak, 0)
+	knowledgebase.NormalizeScores(strong, 0)
+
+	if weak[0].NormalizedScore >= strong[0].NormalizedScore {
+		t.Errorf("weak best hit scores %v, strong best hit %v", weak[0].NormalizedScore, strong[0].NormalizedScore)
+	}
+	if weak[0].NormalizedScore <= strong[1].NormalizedScore {
+		t.Errorf("closer weak hit scores %v, farther strong hit %v", weak[0].NormalizedScore, strong[1].NormalizedScore)
+	}
+
+	// The same holds for fused rankings: a chunk found by one of the query variants scores below one found by both
+	weakFused := knowledgebase.FuseByChunk([][]knowledgebase.QueryResult{{{ChunkID: 1, Score: 0.6}}, nil})
+	strongFused := knowledgebase.FuseByChunk([][]knowledgebase.QueryResult{{{ChunkID: 3, Score: 0.1}}, {{ChunkID: 3, Score: 0.2}}})
+	knowledgebase.NormalizeScores(weakFused, 2)
+	knowledgebase.NormalizeScores(strongFused, 2)
+
+	if weakFused[0].NormalizedScore >= strongFused[0].NormalizedScore {
+		t.Errorf("weak fused best hit scores %v, strong fused best hit %v", weakFused[0].NormalizedScore, strongFused[0].NormalizedScore)
+	}
+}
+
+func TestNormalizeScores(t *testing.T) {
+	tests := []struct {
+		name          string
+		results       []knowledgebase.QueryResult
+		fusedRankings int
+		want          []float64
+	}{
+		{
+			name: "distances",
+			results: []knowledgebase.QueryResult{
+				{ChunkID: 1, Score: 0.1},
+				{ChunkID: 2, Score: 0.2},
+				{ChunkID: 3, Score: 0.5},
+			},
+			want: []float64{0.95, 0.9, 0.75},
+		},
+		{
+			name: "distances out of range",
+			results: []knowledgebase.QueryResult{
+				{ChunkID: 1, Score: -0.0001},
+				{ChunkID: 2, Score: 2.0001},
+			},
+			want: []float64{1, 0},
+		},
+		{
+			name: "fusion scores",
+			results: []knowledgebase.QueryResult{
+				{ChunkID: 1, Score: 0.3, FusionScore: 2.0 / 61},
+				{ChunkID: 2, Score: 0.1, FusionScore: 1.0 / 61},
+				{ChunkID: 3, Score: 0.5, FusionScore: 1.0 / 122},
+			},
+			fusedRankings: 2,
+			want:          []float64{1, 0.5, 0.25},
+		},
+		{
+			name:          "single fused result ranked second",
+			results:       []knowledgebase.QueryResult{{ChunkID: 1, Score: 0.4, FusionScore: 1.0 / 62}},
+			fusedRankings: 1,
+			want:          []float64{61.0 / 62},
+		},
+		{
+			name: "fusion scores of three rankings",
+			results: []knowledgebase.QueryResult{
+				{ChunkID: 1, Score: 0.2, FusionScore: 1.0 / 61},
+				{ChunkID: 2, Score: 0.4, FusionScore: 1.0 / 61},
+			},
+			fusedRankings: 3,
+			want:          []float64{1.0 / 3, 1.0 / 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			knowledgebase.NormalizeScores(tt.results, tt.fusedRankings)
+			for i, result := range tt.results {
+				if diff := result.NormalizedScore - tt.want[i]; diff > 1e-9 || diff < -1e-9 {
+					t.Errorf("NormalizeScores() result %d = %v, want %v", i, result.NormalizedScore, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// fakeWeaviate serves the schema and object endpoints of Weaviate from memory
+type fakeWeaviate struct {
+	mu      sync.Mutex
+	classes map[string]*models.Class
+	objects map[string][]map[string]interface{}
+}
+
+func newFakeWeaviate() *fakeWeaviate {
+	return &fakeWeaviate{classes: map[string]*models.Class{}, objects: map[string][]map[string]interface{}{}}
+}
+
+func (f *fakeWeaviate) count(className string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.objects[className])
+}
+
+func (f *fakeWeaviate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	className := strings.TrimPrefix(r.URL.Path, "/v1/schema/")
+	switch {
+	case r.URL.Path == "/v1/meta":
+		json.NewEncoder(w).Encode(map[string]string{"version": "1.28.2"})
+	case r.URL.Path == "/v1/schema" && r.Method == http.MethodGet:
+		schema := &models.Schema{}
+		for _, class := range f.classes {
+			schema.Classes = append(schema.Classes, class)
+		}
+		json.NewEncoder(w).Encode(schema)
+	case r.URL.Path == "/v1/schema" && r.Method == http.MethodPost:
+		var class models.Class
+		json.NewDecoder(r.Body).Decode(&class)
+		f.classes[class.Class] = &class
+		json.NewEncoder(w).Encode(class)
+	case strings.HasPrefix(r.URL.Path, "/v1/schema/") && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(f.classes[className])
+	case strings.HasPrefix(r.URL.Path, "/v1/schema/") && r.Method == http.MethodDelete:
+		delete(f.classes, className)
+		delete(f.objects, className)
+	case r.URL.Path == "/v1/objects" && r.Method == http.MethodPost:
+		var object map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&object)
+		object["id"] = uuid.NewString()
+		className, _ := object["class"].(string)
+		f.objects[className] = append(f.objects[className], object)
+		json.NewEncoder(w).Encode(object)
+	default:
+		http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusNotFound)
+	}
+}
+
+// fakeEmbedder serves the embedding endpoint of Ollama
+func fakeEmbedder(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(ollama.EmbeddingResponse{Embedding: []float64{0.1, 0.2, 0.3}})
+}
+
+type fakeRepository struct {
+	knowledgeBases map[int64]*knowledgebase.KnowledgeBase
+	resources      []knowledgebase.KnowledgeBaseResource
+}
+
+func (r *fakeRepository) ListKnowledgeBases(ctx context.Context, offset, limit int) ([]knowledgebase.KnowledgeBase, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) ListKnowledgeBaseResources(ctx context.Context, knowledgeBaseID int64, offset, limit int) ([]knowledgebase.KnowledgeBaseResource, error) {
+	return r.filter(func(res knowledgebase.KnowledgeBaseResource) bool { return res.KnowledgeBaseID == knowledgeBaseID }), nil
+}
+
+func (r *fakeRepository) CreateKnowledgeBase(ctx context.Context, kb *knowledgebase.KnowledgeBase) error {
+	r.knowledgeBases[kb.ID] = kb
+	return nil
+}
+
+func (r *fakeRepository) AddResource(ctx context.Context, resource *knowledgebase.KnowledgeBaseResource) error {
+	r.resources = append(r.resources, *resource)
+	return nil
+}
+
+func (r *fakeRepository) GetKnowledgeBase(ctx context.Context, id int64) (*knowledgebase.KnowledgeBase, error) {
+	return r.knowledgeBases[id], nil
+}
+
+func (r *fakeRepository) HasContentHash(ctx context.Context, knowledgeBaseID int64, contentHash string) (bool, error) {
+	return len(r.filter(func(res knowledgebase.KnowledgeBaseResource) bool {
+		return res.KnowledgeBaseID == knowledgeBaseID && res.ContentHash == contentHash
+	})) > 0, nil
+}
+
+func (r *fakeRepository) HasChunk(ctx context.Context, knowledgeBaseID int64, chunkID int64) (bool, error) {
+	return len(r.filter(func(res knowledgebase.KnowledgeBaseResource) bool {
+		return res.KnowledgeBaseID == knowledgeBaseID && res.ChunkID == chunkID && res.TranslatedChunkID == nil
+	})) > 0, nil
+}
+
+func (r *fakeRepository) GetResourceByContentHash(ctx context.Context, knowledgeBaseID int64, contentHash string, excludedChunkIDs []int64) (*knowledgebase.KnowledgeBaseResource, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) ListResourcesByChunkIDs(ctx context.Context, knowledgeBaseID int64, chunkIDs []int64) ([]knowledgebase.KnowledgeBaseResource, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) ListResourcesByResourceID(ctx context.Context, knowledgeBaseID int64, resourceID int64) ([]knowledgebase.KnowledgeBaseResource, error) {
+	return r.filter(func(res knowledgebase.KnowledgeBaseResource) bool {
+		return res.KnowledgeBaseID == knowledgeBaseID && res.ResourceID == resourceID
+	}), nil
+}
+
+func (r *fakeRepository) HasTranslatedChunk(ctx context.Context, knowledgeBaseID int64, translatedChunkID int64) (bool, error) {
+	return false, nil
+}
+
+func (r *fakeRepository) ListLanguages(ctx context.Context, knowledgeBaseID int64) ([]string, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) ListKnowledgeBaseIDsByResourceID(ctx context.Context, resourceID int64) ([]int64, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) DeleteResourcesByChunkIDs(ctx context.Context, knowledgeBaseID int64, chunkIDs []int64) error {
+	return nil
+}
+
+func (r *fakeRepository) DeleteResourcesByResourceID(ctx context.Context, knowledgeBaseID int64, resourceID int64) error {
+	r.resources = r.filter(func(res knowledgebase.KnowledgeBaseResource) bool {
+		return res.KnowledgeBaseID != knowledgeBaseID || res.ResourceID != resourceID
+	})
+	return nil
+}
+
+func (r *fakeRepository) DeleteResources(ctx context.Context, knowledgeBaseID int64) error {
+	r.resources = r.filter(func(res knowledgebase.KnowledgeBaseResource) bool { return res.KnowledgeBaseID != knowledgeBaseID })
+	return nil
+}
+
+func (r *fakeRepository) filter(keep func(knowledgebase.KnowledgeBaseResource) bool) []knowledgebase.KnowledgeBaseResource {
+	var kept []knowledgebase.KnowledgeBaseResource
+	for _, res := range r.resources {
+		if keep(res) {
+			kept = append(kept, res)
+		}
+	}
+	return kept
+}
+
+type fakeResources map[int64]*resourcectrl.Resource
+
+func (f fakeResources) GetByID(ctx context.Context, id int64) (*resourcectrl.Resource, error) {
+	return f[id], nil
+}
+
+type fakeChunks []chunkctrl.Chunk
+
+func (f fakeChunks) GetByID(ctx context.Context, id int64) (*chunkctrl.Chunk, error) {
+	for i := range f {
+		if f[i].ID == id {
+			return &f[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (f fakeChunks) GetByResourceID(ctx context.Context, resourceID int64) ([]chunkctrl.Chunk, error) {
+	var chunks []chunkctrl.Chunk
+	for _, chunk := range f {
+		if chunk.ResourceID == resourceID {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks, nil
+}
+
+type fakeStorage map[string]string
+
+func (f fakeStorage) GetObject(ctx context.Context, bucketName, objectName string) ([]byte, error) {
+	content, ok := f[bucketName+"/"+objectName]
+	if !ok {
+		return nil, fmt.Errorf("object %s/%s not found", bucketName, objectName)
+	}
+	return []byte(content), nil
+}
+
+func (f fakeStorage) GetBucketAndObjectFromURL(minioURL string) (string, string) {
+	bucket, object, _ := strings.Cut(minioURL, "/")
+	return bucket, object
+}
+
+func TestResetWeaviateContentThenAddResourceAgain(t *testing.T) {
+	ctx := context.Background()
+	weaviateServer := httptest.NewServer(newFakeWeaviate())
+	defer weaviateServer.Close()
+	ollamaServer := httptest.NewServer(http.HandlerFunc(fakeEmbedder))
+	defer ollamaServer.Close()
+
+	client, err := weaviateclient.NewClient(weaviateclient.Config{Host: strings.TrimPrefix(weaviateServer.URL, "http://"), Scheme: "http"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := weaviateServer.Config.Handler.(*fakeWeaviate)
+	repo := &fakeRepository{knowledgeBases: map[int64]*knowledgebase.KnowledgeBase{1: {ID: 1, EmbeddingModel: "embedder"}}}
+	chunks := fakeChunks{
+		{ID: 11, ResourceID: 100, MinioURL: "chunks/11", ContentHash: "hash-11", Language: "en", Kind: "text"},
+		{ID: 12, ResourceID: 100, MinioURL: "chunks/12", ContentHash: "hash-12", Language: "en", Kind: "text"},
+	}
+	storage := fakeStorage{"chunks/11": "first chunk", "chunks/12": "second chunk"}
+	service, err := knowledgebase.NewService(repo, weaviate.NewSDK(client), ollama.NewClient(ollamaServer.URL, ollamaServer.Client()),
+		storage, fakeResources{100: {ID: 100, Filename: "doc.pdf"}}, chunks, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	className := "KnowledgeBaseResource_1"
+	if err := service.AddResourceToKnowledgeBase(ctx, 1, 100, knowledgebase.AddResourceOptions{}); err != nil {
+		t.Fatalf("AddResourceToKnowledgeBase() error = %v", err)
+	}
+	if got := fake.count(className); got != 2 {
+		t.Fatalf("indexed %d vectors, want 2", got)
+	}
+
+	if err := service.ResetWeaviateContent(ctx, 1); err != nil {
+		t.Fatalf("ResetWeaviateContent() error = %v", err)
+	}
+	if got := fake.count(className); got != 0 {
+		t.Fatalf("%d vectors left after reset, want 0", got)
+	}
+
+	if err := service.AddResourceToKnowledgeBase(ctx, 1, 100, knowledgebase.AddResourceOptions{}); err != nil {
+		t.Fatalf("AddResourceToKnowledgeBase() after reset error = %v", err)
+	}
+	if got := fake.count(className); got != 2 {
+		t.Errorf("indexed %d vectors after reset, want 2", got)
+	}
+	if got := len(repo.resources); got != 2 {
+		t.Errorf("%d knowledge base resources after reset, want 2", got)
+	}
+}
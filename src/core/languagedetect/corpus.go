@@ -0,0 +1,77 @@
+package languagedetect
+
+// corpus holds a sample of everyday and technical prose per language from which the trigram profiles are built.
+// Languages with a script of their own are recognized by the script and need no sample.
+var corpus = map[string]string{
+	"en": `All human beings are born free and equal in dignity and rights. They are endowed with reason and conscience
+and should act towards one another in a spirit of brotherhood. Everyone has the right to life, liberty and security of person.
+Please read the following instructions carefully before you use the device for the first time. Keep this manual in a safe
+place so that you can find it again when you need it. The warranty does not cover damage that was caused by improper use.
+We would like to thank you for choosing our product and hope that you will enjoy working with it. If you have any questions,
+our customer service team will be happy to help you. The results of the study show that the new method is faster and more
+reliable than the old one, which is why we recommend that it should be used whenever possible. This is the way things are.`,
+
+	"de": `Alle Menschen sind frei und gleich an Würde und Rechten geboren. Sie sind mit Vernunft und Gewissen begabt
+und sollen einander im Geist der Brüderlichkeit begegnen. Jeder hat das Recht auf Leben, Freiheit und Sicherheit der Person.
+Bitte lesen Sie die folgenden Hinweise sorgfältig durch, bevor Sie das Gerät zum ersten Mal benutzen. Bewahren Sie diese
+Anleitung an einem sicheren Ort auf, damit Sie sie wiederfinden, wenn Sie sie brauchen. Die Garantie gilt nicht für Schäden,
+die durch unsachgemäßen Gebrauch entstanden sind. Wir möchten uns bei Ihnen bedanken, dass Sie sich für unser Produkt
+entschieden haben, und wünschen Ihnen viel Freude damit. Wenn Sie Fragen haben, hilft Ihnen unser Kundendienst gerne weiter.
+Die Ergebnisse der Untersuchung zeigen, dass die neue Methode schneller und zuverlässiger ist als die alte. Das ist nicht schlecht.`,
+
+	"fr": `Tous les êtres humains naissent libres et égaux en dignité et en droits. Ils sont doués de raison et de conscience
+et doivent agir les uns envers les autres dans un esprit de fraternité. Tout individu a droit à la vie, à la liberté et à la
+sûreté de sa personne. Veuillez lire attentivement les instructions suivantes avant d'utiliser l'appareil pour la première fois.
+Conservez ce manuel dans un endroit sûr afin de pouvoir le retrouver lorsque vous en aurez besoin. La garantie ne couvre pas les
+dommages causés par une utilisation incorrecte. Nous vous remercions d'avoir choisi notre produit et nous espérons que vous
+aurez plaisir à l'utiliser. Si vous avez des questions, notre service client se fera un plaisir de vous aider. Les résultats de
+l'étude montrent que la nouvelle méthode est plus rapide et plus fiable que l'ancienne, c'est pourquoi nous la recommandons.`,
+
+	"es": `Todos los seres humanos nacen libres e iguales en dignidad y derechos y, dotados como están de razón y conciencia,
+deben comportarse fraternalmente los unos con los otros. Todo individuo tiene derecho a la vida, a la libertad y a la seguridad
+de su persona. Por favor, lea atentamente las siguientes instrucciones antes de utilizar el aparato por primera vez. Guarde este
+manual en un lugar seguro para poder encontrarlo cuando lo necesite. La garantía no cubre los daños causados por un uso
+inadecuado. Queremos agradecerle que haya elegido nuestro producto y esperamos que disfrute trabajando con él. Si tiene alguna
+pregunta, nuestro equipo de atención al cliente estará encantado de ayudarle. Los resultados del estudio muestran que el nuevo
+método es más rápido y más fiable que el anterior, por lo que recomendamos utilizarlo siempre que sea posible.`,
+
+	"it": `Tutti gli esseri umani nascono liberi ed eguali in dignità e diritti. Essi sono dotati di ragione e di coscienza e
+devono agire gli uni verso gli altri in spirito di fratellanza. Ogni individuo ha diritto alla vita, alla libertà ed alla
+sicurezza della propria persona. Si prega di leggere attentamente le seguenti istruzioni prima di utilizzare l'apparecchio per
+la prima volta. Conservare questo manuale in un luogo sicuro per poterlo ritrovare quando serve. La garanzia non copre i danni
+causati da un uso improprio. Desideriamo ringraziarla per aver scelto il nostro prodotto e speriamo che lavorare con esso le
+dia soddisfazione. Se ha delle domande, il nostro servizio clienti sarà lieto di aiutarla. I risultati dello studio mostrano
+che il nuovo metodo è più veloce e più affidabile di quello vecchio, per questo motivo ne consigliamo l'uso quando possibile.`,
+
+	"pt": `Todos os seres humanos nascem livres e iguais em dignidade e em direitos. Dotados de razão e de consciência, devem
+agir uns para com os outros em espírito de fraternidade. Todo indivíduo tem direito à vida, à liberdade e à segurança pessoal.
+Por favor, leia atentamente as seguintes instruções antes de utilizar o aparelho pela primeira vez. Guarde este manual num
+local seguro para que o possa encontrar quando precisar dele. A garantia não cobre os danos causados por uma utilização
+incorreta. Gostaríamos de lhe agradecer por ter escolhido o nosso produto e esperamos que goste de trabalhar com ele. Se tiver
+alguma dúvida, a nossa equipa de apoio ao cliente terá todo o gosto em ajudá-lo. Os resultados do estudo mostram que o novo
+método é mais rápido e mais fiável do que o antigo, razão pela qual recomendamos que seja utilizado sempre que possível.`,
+
+	"nl": `Alle mensen worden vrij en gelijk in waardigheid en rechten geboren. Zij zijn begiftigd met verstand en geweten, en
+behoren zich jegens elkander in een geest van broederschap te gedragen. Een ieder heeft recht op leven, vrijheid en
+onschendbaarheid van zijn persoon. Lees de volgende aanwijzingen zorgvuldig door voordat u het apparaat voor het eerst
+gebruikt. Bewaar deze handleiding op een veilige plaats, zodat u haar terugvindt wanneer u haar nodig hebt. De garantie geldt
+niet voor schade die door onjuist gebruik is ontstaan. Wij willen u bedanken dat u voor ons product hebt gekozen en hopen dat u
+er veel plezier van zult hebben. Als u vragen hebt, helpt onze klantenservice u graag verder. De resultaten van het onderzoek
+laten zien dat de nieuwe methode sneller en betrouwbaarder is dan de oude, daarom raden wij aan haar waar mogelijk te gebruiken.`,
+
+	"ru": `Все люди рождаются свободными и равными в своем достоинстве и правах. Они наделены разумом и совестью и должны
+поступать в отношении друг друга в духе братства. Каждый человек имеет право на жизнь, на свободу и на личную
+неприкосновенность. Пожалуйста, внимательно прочитайте следующие указания, прежде чем использовать прибор в первый раз.
+Храните это руководство в надежном месте, чтобы вы могли найти его, когда оно вам понадобится. Гарантия не распространяется
+на повреждения, вызванные неправильным использованием. Мы хотим поблагодарить вас за то, что вы выбрали наш продукт, и
+надеемся, что работа с ним доставит вам удовольствие. Если у вас есть вопросы, наша служба поддержки с радостью вам поможет.
+Результаты исследования показывают, что новый метод быстрее и надежнее старого, поэтому мы рекомендуем использовать его.`,
+
+	"uk": `Усі люди народжуються вільними і рівними у своїй гідності та правах. Вони наділені розумом і совістю і повинні
+діяти у відношенні один до одного в дусі братерства. Кожна людина має право на життя, на свободу і на особисту
+недоторканність. Будь ласка, уважно прочитайте наступні вказівки, перш ніж користуватися приладом уперше. Зберігайте цю
+інструкцію в надійному місці, щоб ви могли знайти її, коли вона вам знадобиться. Гарантія не поширюється на пошкодження,
+спричинені неправильним використанням. Ми хочемо подякувати вам за те, що ви обрали наш продукт, і сподіваємося, що робота з
+ним принесе вам задоволення. Якщо у вас є питання, наша служба підтримки з радістю вам допоможе. Результати дослідження
+показують, що новий метод швидший і надійніший за старий, тому ми рекомендуємо використовувати його, де це можливо.`,
+}
@@ -0,0 +1,234 @@
+package languagedetect
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Auto is the source language that asks for the language of every chunk to be detected
+const Auto = "auto"
+
+const (
+	// minLetters is the number of letters below which a text is too short to tell its language
+	minLetters = 8
+	// minConfidence is the confidence below which a detection is reported as undetermined
+	minConfidence = 0.5
+)
+
+// Detection is the language of a text as an ISO 639-1 code, empty if it could not be determined
+type Detection struct {
+	Language   string  `json:"language"`
+	Confidence float64 `json:"confidence"`
+}
+
+// scriptLanguages are the languages recognized by their script alone
+var scriptLanguages = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Thai, "th"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Devanagari, "hi"},
+}
+
+// names maps language names accepted as source or target language onto their codes
+var names = map[string]string{
+	"english":    "en",
+	"german":     "de",
+	"french":     "fr",
+	"spanish":    "es",
+	"italian":    "it",
+	"portuguese": "pt",
+	"dutch":      "nl",
+	"russian":    "ru",
+	"ukrainian":  "uk",
+	"chinese":    "zh",
+	"japanese":   "ja",
+	"korean":     "ko",
+	"thai":       "th",
+	"arabic":     "ar",
+	"hebrew":     "he",
+	"greek":      "el",
+	"hindi":      "hi",
+}
+
+// profile is the trigram model of one language
+type profile struct {
+	counts map[string]float64
+	total  float64
+}
+
+var profiles = buildProfiles()
+
+func buildProfiles() map[string]*profile {
+	result := make(map[string]*profile, len(corpus))
+	for language, text := range corpus {
+		p := &profile{counts: make(map[string]float64)}
+		for _, gram := range trigrams(text) {
+			p.counts[gram]++
+			p.total++
+		}
+		result[language] = p
+	}
+	return result
+}
+
+// Detect identifies the language of a text locally. CJK, Korean and other languages with a script of their own
+// are recognized by their script; Latin and Cyrillic texts are scored against character trigram profiles.
+func Detect(text string) Detection {
+	var letters, latin, cyrillic, han, kana int
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		default:
+			for _, s := range scriptLanguages {
+				if unicode.Is(s.table, r) {
+					scripts[s.language]++
+					break
+				}
+			}
+		}
+	}
+	if letters == 0 {
+		return Detection{}
+	}
+
+	// Han characters count towards Chinese and Japanese alike; a few kana make it Japanese
+	best, bestCount := "", 0
+	if han+kana > 0 {
+		if kana*10 >= han+kana {
+			best, bestCount = "ja", han+kana
+		} else {
+			best, bestCount = "zh", han
+		}
+	}
+	for language, count := range scripts {
+		if count > bestCount {
+			best, bestCount = language, count
+		}
+	}
+	if latin > bestCount || cyrillic > bestCount {
+		if latin+cyrillic < minLetters {
+			return Detection{}
+		}
+		return detectByTrigrams(text, latin >= cyrillic)
+	}
+	return Detection{Language: best, Confidence: float64(bestCount) / float64(letters)}
+}
+
+// detectByTrigrams scores the text against the profiles of the Latin or Cyrillic languages
+// with a naive Bayes model and returns the language with the highest posterior
+func detectByTrigrams(text string, latin bool) Detection {
+	grams := trigrams(text)
+	if len(grams) == 0 {
+		return Detection{}
+	}
+
+	scores := make(map[string]float64)
+	for language, p := range profiles {
+		if isCyrillic(language) == latin {
+			continue
+		}
+		// add-one smoothing over the trigrams of the profile plus one unseen trigram
+		denominator := p.total + float64(len(p.counts)) + 1
+		var score float64
+		for _, gram := range grams {
+			score += math.Log((p.counts[gram] + 1) / denominator)
+		}
+		scores[language] = score
+	}
+
+	languages := make([]string, 0, len(scores))
+	for language := range scores {
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if scores[languages[i]] != scores[languages[j]] {
+			return scores[languages[i]] > scores[languages[j]]
+		}
+		return languages[i] < languages[j]
+	})
+
+	// posterior of the best language; the log-likelihoods are averaged per trigram so long texts are not overconfident
+	best := languages[0]
+	var sum float64
+	for _, language := range languages {
+		sum += math.Exp((scores[language] - scores[best]) / math.Sqrt(float64(len(grams))))
+	}
+	confidence := 1 / sum
+	if confidence < minConfidence {
+		return Detection{Confidence: confidence}
+	}
+	return Detection{Language: best, Confidence: confidence}
+}
+
+func isCyrillic(language string) bool {
+	return language == "ru" || language == "uk"
+}
+
+// trigrams returns the character trigrams of the words of the text, padded with a space on either side
+func trigrams(text string) []string {
+	var grams []string
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range words {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			grams = append(grams, string(runes[i:i+3]))
+		}
+	}
+	return grams
+}
+
+// Normalize returns the primary language code of a language tag or an English language name,
+// so that "zh-TW", "zh_CN" and "Chinese" all become "zh". Unknown languages are returned lower-cased.
+func Normalize(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code, ok := names[language]; ok {
+		return code
+	}
+	if i := strings.IndexAny(language, "-_"); i > 0 {
+		return language[:i]
+	}
+	return language
+}
+
+// Same reports whether two languages given as codes, tags or names are the same language
+func Same(a, b string) bool {
+	return a != "" && Normalize(a) == Normalize(b)
+}
+
+// Dominant returns the most frequent language of a set of detections, empty if none was determined
+func Dominant(detections []Detection) string {
+	counts := make(map[string]int)
+	for _, d := range detections {
+		if d.Language != "" {
+			counts[d.Language]++
+		}
+	}
+	best := ""
+	for language, count := range counts {
+		if count > counts[best] || (count == counts[best] && language < best) {
+			best = language
+		}
+	}
+	return best
+}
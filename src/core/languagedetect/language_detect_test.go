@@ -0,0 +1,70 @@
+package languagedetect_test
+
+import (
+	"testing"
+
+	"raggo/src/core/languagedetect"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "english", text: "The server restarts automatically when the configuration file has been changed.", want: "en"},
+		{name: "german", text: "Der Server wird automatisch neu gestartet, wenn die Konfigurationsdatei geändert wurde.", want: "de"},
+		{name: "french", text: "Le serveur redémarre automatiquement lorsque le fichier de configuration a été modifié.", want: "fr"},
+		{name: "spanish", text: "El servidor se reinicia automáticamente cuando se ha modificado el archivo de configuración.", want: "es"},
+		{name: "italian", text: "Il server si riavvia automaticamente quando il file di configurazione è stato modificato.", want: "it"},
+		{name: "portuguese", text: "O servidor reinicia automaticamente quando o ficheiro de configuração foi alterado.", want: "pt"},
+		{name: "dutch", text: "De server wordt automatisch opnieuw gestart wanneer het configuratiebestand is gewijzigd.", want: "nl"},
+		{name: "russian", text: "Сервер автоматически перезапускается, когда файл конфигурации был изменен.", want: "ru"},
+		{name: "ukrainian", text: "Сервер автоматично перезапускається, коли файл конфігурації було змінено.", want: "uk"},
+		{name: "chinese", text: "修改配置文件后，服务器会自动重新启动。", want: "zh"},
+		{name: "japanese", text: "設定ファイルが変更されると、サーバーは自動的に再起動します。", want: "ja"},
+		{name: "korean", text: "구성 파일이 변경되면 서버가 자동으로 다시 시작됩니다.", want: "ko"},
+		{name: "greek", text: "Ο διακομιστής επανεκκινείται αυτόματα όταν αλλάξει το αρχείο ρυθμίσεων.", want: "el"},
+		{name: "too short", text: "OK", want: ""},
+		{name: "no letters", text: "42 + 7 = 49", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := languagedetect.Detect(tt.text); got.Language != tt.want {
+				t.Errorf("Detect() = %q (confidence %.2f), want %q", got.Language, got.Confidence, tt.want)
+			}
+		})
+	}
+}
+
+func TestSame(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "zh", b: "zh-TW", want: true},
+		{a: "en", b: "English", want: true},
+		{a: "pt", b: "pt_BR", want: true},
+		{a: "de", b: "en", want: false},
+		{a: "", b: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := languagedetect.Same(tt.a, tt.b); got != tt.want {
+				t.Errorf("Same(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDominant(t *testing.T) {
+	detections := []languagedetect.Detection{{Language: "de"}, {}, {Language: "en"}, {Language: "de"}}
+	if got := languagedetect.Dominant(detections); got != "de" {
+		t.Errorf("Dominant() = %q, want %q", got, "de")
+	}
+	if got := languagedetect.Dominant([]languagedetect.Detection{{}}); got != "" {
+		t.Errorf("Dominant() = %q, want empty", got)
+	}
+}
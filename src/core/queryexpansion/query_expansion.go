@@ -0,0 +1,167 @@
+package queryexpansion
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Modes of transforming a query before it is searched
+const (
+	ModeNone       = "none"        // search the query as it is
+	ModeHyDE       = "hyde"        // search a hypothetical answer written by the model instead of the query
+	ModeMultiQuery = "multi_query" // search the query and paraphrases of it and fuse the rankings
+	ModeStepBack   = "step_back"   // search the query and a more generic step-back question and fuse the rankings
+)
+
+// Modes lists the valid modes
+var Modes = []string{ModeNone, ModeHyDE, ModeMultiQuery, ModeStepBack}
+
+const (
+	// MultiQueryCount is the number of paraphrases searched next to the query in multi-query mode
+	MultiQueryCount = 3
+	// RRFConstant dampens the weight of the top ranks in reciprocal rank fusion, 60 as in the original paper
+	RRFConstant = 60
+)
+
+// Generator writes the texts a query is expanded into, e.g. an ollama.OllamaProvider
+type Generator interface {
+	Reasoning(ctx context.Context, system string, prompt string) (string, error)
+}
+
+// Valid reports whether the mode is known; an empty mode is the same as ModeNone
+func Valid(mode string) bool {
+	if mode == "" {
+		return true
+	}
+	for _, m := range Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Fuses reports whether the texts of a mode are searched as separate rankings that need to be fused
+func Fuses(mode string) bool {
+	return mode == ModeMultiQuery || mode == ModeStepBack
+}
+
+// Expand returns the texts to search for a query in the given mode
+func Expand(ctx context.Context, generator Generator, mode, query string) ([]string, error) {
+	switch mode {
+	case "", ModeNone:
+		return []string{query}, nil
+	case ModeHyDE:
+		prompt := strings.Replace(HYDE_PROMPT, "{query}", query, 1)
+		passage, err := generator.Reasoning(ctx, HYDE_SYSTEM_PROMPT, prompt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write hypothetical answer: %v", err)
+		}
+		if passage = strings.TrimSpace(passage); passage == "" {
+			return []string{query}, nil
+		}
+		return []string{passage}, nil
+	case ModeMultiQuery:
+		prompt := strings.NewReplacer("{count}", strconv.Itoa(MultiQueryCount), "{query}", query).Replace(MULTI_QUERY_PROMPT)
+		paraphrases, err := generator.Reasoning(ctx, MULTI_QUERY_SYSTEM_PROMPT, prompt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to paraphrase query: %v", err)
+		}
+		return append([]string{query}, ParseLines(paraphrases, query, MultiQueryCount)...), nil
+	case ModeStepBack:
+		prompt := strings.Replace(STEP_BACK_PROMPT, "{query}", query, 1)
+		stepBack, err := generator.Reasoning(ctx, STEP_BACK_SYSTEM_PROMPT, prompt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write step-back question: %v", err)
+		}
+		return append([]string{query}, ParseLines(stepBack, query, 1)...), nil
+	default:
+		return nil, fmt.Errorf("unknown query expansion mode: %s", mode)
+	}
+}
+
+// listMarker matches the numbering or bullet models put in front of the lines of a list
+var listMarker = regexp.MustCompile(`^(\d+[.)]|[-*•])\s*`)
+
+// ParseLines returns up to limit distinct queries written one per line, without list markers, quotes and
+// copies of the original query
+func ParseLines(text, query string, limit int) []string {
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(query)): true}
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(listMarker.ReplaceAllString(strings.TrimSpace(line), ""))
+		line = strings.Trim(line, "\"'“”")
+		if line == "" || seen[strings.ToLower(line)] {
+			continue
+		}
+		seen[strings.ToLower(line)] = true
+		lines = append(lines, line)
+		if len(lines) == limit {
+			break
+		}
+	}
+	return lines
+}
+
+// Fused is an item of a fused ranking with its reciprocal rank fusion score, higher is better
+type Fused[T any] struct {
+	Item  T
+	Score float64
+}
+
+// ReciprocalRankFusion merges rankings into one. Every item scores the sum of 1/(RRFConstant+rank) over the rankings
+// it appears in, so items found by several queries rise to the top. Items are told apart by their key; of an item
+// found more than once, the keep function chooses which occurrence to return.
+func ReciprocalRankFusion[T any, K comparable](rankings [][]T, key func(T) K, keep func(current, candidate T) bool) []Fused[T] {
+	index := make(map[K]int)
+	var fused []Fused[T]
+	for _, ranking := range rankings {
+		for rank, item := range ranking {
+			score := 1 / float64(RRFConstant+rank+1)
+			i, ok := index[key(item)]
+			if !ok {
+				index[key(item)] = len(fused)
+				fused = append(fused, Fused[T]{Item: item, Score: score})
+				continue
+			}
+			fused[i].Score += score
+			if keep != nil && keep(fused[i].Item, item) {
+				fused[i].Item = item
+			}
+		}
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
+	})
+	return fused
+}
+
+const (
+	HYDE_SYSTEM_PROMPT = `You write the passage of a document that answers a question, for a search engine to find similar passages.
+Write in the language of the question. Return only the passage, without a title or remarks.`
+
+	HYDE_PROMPT = `
+Write a short passage of three to five sentences that answers this question:
+{query}
+`
+
+	MULTI_QUERY_SYSTEM_PROMPT = `You rephrase search queries so that a search engine finds documents the original wording would miss.
+Keep the language and the meaning of the query. Return only the rephrased queries, one per line, without numbering.`
+
+	MULTI_QUERY_PROMPT = `
+Write {count} different rephrasings of this query:
+{query}
+`
+
+	STEP_BACK_SYSTEM_PROMPT = `You turn a specific question into a more generic step-back question about the concept or principle behind it,
+whose answer helps to answer the specific question. Keep the language of the question. Return only the step-back question.`
+
+	STEP_BACK_PROMPT = `
+Question: {query}
+`
+)
@@ -0,0 +1,131 @@
+package queryexpansion_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"raggo/src/core/queryexpansion"
+)
+
+// fakeGenerator answers every prompt with the same text
+type fakeGenerator struct {
+	answer string
+	err    error
+	system string
+}
+
+func (g *fakeGenerator) Reasoning(ctx context.Context, system string, prompt string) (string, error) {
+	g.system = system
+	return g.answer, g.err
+}
+
+func TestExpand(t *testing.T) {
+	query := "qwen languages"
+
+	tests := []struct {
+		name    string
+		mode    string
+		answer  string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", mode: queryexpansion.ModeNone, want: []string{query}},
+		{name: "empty mode", mode: "", want: []string{query}},
+		{
+			name:   "hyde searches the hypothetical answer only",
+			mode:   queryexpansion.ModeHyDE,
+			answer: "Qwen supports 29 languages, among them Chinese and English.\n",
+			want:   []string{"Qwen supports 29 languages, among them Chinese and English."},
+		},
+		{name: "hyde without an answer", mode: queryexpansion.ModeHyDE, answer: " ", want: []string{query}},
+		{
+			name:   "multi query keeps the query first",
+			mode:   queryexpansion.ModeMultiQuery,
+			answer: "1. Which languages does Qwen support?\n2. Qwen multilingual support\n3. Qwen languages\n4. Languages of Qwen models",
+			want:   []string{query, "Which languages does Qwen support?", "Qwen multilingual support", "Languages of Qwen models"},
+		},
+		{
+			name:   "step back",
+			mode:   queryexpansion.ModeStepBack,
+			answer: "How multilingual are large language models?",
+			want:   []string{query, "How multilingual are large language models?"},
+		},
+		{name: "unknown mode", mode: "rerank", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryexpansion.Expand(context.Background(), &fakeGenerator{answer: tt.answer}, tt.mode, query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandFails(t *testing.T) {
+	generator := &fakeGenerator{err: errors.New("model not found")}
+	if _, err := queryexpansion.Expand(context.Background(), generator, queryexpansion.ModeHyDE, "query"); err == nil {
+		t.Error("Expand() error = nil, want the error of the generator")
+	}
+	if !strings.Contains(generator.system, "passage") {
+		t.Errorf("HyDE asked with system prompt %q", generator.system)
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	text := "- \"First query\"\n\n* second query\n3) First query\n• original\n5. third query"
+
+	got := queryexpansion.ParseLines(text, "Original", 5)
+	want := []string{"First query", "second query", "third query"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLines() = %q, want %q", got, want)
+	}
+
+	if got := queryexpansion.ParseLines(text, "Original", 1); !reflect.DeepEqual(got, want[:1]) {
+		t.Errorf("ParseLines() with limit 1 = %q, want %q", got, want[:1])
+	}
+}
+
+func TestReciprocalRankFusion(t *testing.T) {
+	type hit struct {
+		id       string
+		distance float64
+	}
+	rankings := [][]hit{
+		{{"a", 0.10}, {"b", 0.20}, {"c", 0.30}},
+		{{"c", 0.15}, {"b", 0.25}},
+		{{"b", 0.22}, {"d", 0.40}},
+	}
+
+	fused := queryexpansion.ReciprocalRankFusion(rankings,
+		func(h hit) string { return h.id },
+		func(current, candidate hit) bool { return candidate.distance < current.distance },
+	)
+
+	var ids []string
+	for _, f := range fused {
+		ids = append(ids, f.Item.id)
+	}
+	// b is found by all three queries, c by two; a and d by one, a at a better rank
+	if want := []string{"b", "c", "a", "d"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ReciprocalRankFusion() order = %v, want %v", ids, want)
+	}
+	if fused[0].Item.distance != 0.20 {
+		t.Errorf("kept distance %v of b, want the closest 0.20", fused[0].Item.distance)
+	}
+	if fused[1].Item.distance != 0.15 {
+		t.Errorf("kept distance %v of c, want the closest 0.15", fused[1].Item.distance)
+	}
+
+	wantScore := 1.0/62 + 1.0/62 + 1.0/61
+	if diff := fused[0].Score - wantScore; diff > 1e-12 || diff < -1e-12 {
+		t.Errorf("score of b = %v, want %v", fused[0].Score, wantScore)
+	}
+}
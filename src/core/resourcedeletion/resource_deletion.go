@@ -0,0 +1,235 @@
+package resourcedeletion
+
+import (
+	"context"
+	"fmt"
+
+	"raggo/src/core/knowledgebase"
+	"raggo/src/infrastructure/log"
+	"raggo/src/storage/minioctrl"
+	"raggo/src/storage/postgres/chunkctrl"
+	"raggo/src/storage/postgres/resourcectrl"
+	"raggo/src/storage/postgres/translatedchunkctrl"
+	"raggo/src/storage/postgres/translatedresourcectrl"
+)
+
+// Plan lists everything that belongs to a resource and is removed together with it
+type Plan struct {
+	ResourceID            int64    `json:"resourceId"`
+	Filename              string   `json:"filename"`
+	Versions              int      `json:"versions"`
+	Chunks                int      `json:"chunks"`
+	TranslatedResourceIDs []int64  `json:"translatedResourceIds"`
+	TranslatedChunks      int      `json:"translatedChunks"`
+	KnowledgeBaseIDs      []int64  `json:"knowledgeBaseIds"`
+	Objects               []string `json:"objects"` // MinIO URLs
+}
+
+// Service removes resources and every artifact derived from them
+type Service struct {
+	minioService              *minioctrl.MinioService
+	resourceService           *resourcectrl.ResourceService
+	chunkService              *chunkctrl.ChunkService
+	translatedResourceService *translatedresourcectrl.TranslatedResourceService
+	translatedChunkService    *translatedchunkctrl.TranslatedChunkService
+	knowledgeBaseService      *knowledgebase.Service
+}
+
+func NewService(
+	minioService *minioctrl.MinioService,
+	resourceService *resourcectrl.ResourceService,
+	chunkService *chunkctrl.ChunkService,
+	translatedResourceService *translatedresourcectrl.TranslatedResourceService,
+	translatedChunkService *translatedchunkctrl.TranslatedChunkService,
+	knowledgeBaseService *knowledgebase.Service,
+) *Service {
+	return &Service{
+		minioService:              minioService,
+		resourceService:           resourceService,
+		chunkService:              chunkService,
+		translatedResourceService: translatedResourceService,
+		translatedChunkService:    translatedChunkService,
+		knowledgeBaseService:      knowledgeBaseService,
+	}
+}
+
+// Plan collects what deleting the resource would remove without changing anything.
+// It returns nil if the resource does not exist.
+func (s *Service) Plan(ctx context.Context, resourceID int64) (*Plan, error) {
+	resource, err := s.resourceService.GetByID(ctx, resourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource: %w", err)
+	}
+	if resource == nil {
+		return nil, nil
+	}
+
+	plan := &Plan{
+		ResourceID: resource.ID,
+		Filename:   resource.Filename,
+	}
+	seen := make(map[string]bool)
+	addObject := func(minioURL string) {
+		if minioURL == "" || seen[minioURL] {
+			return
+		}
+		seen[minioURL] = true
+		plan.Objects = append(plan.Objects, minioURL)
+	}
+
+	addObject(resource.MinioURL)
+	addObject(resource.TextMinioURL)
+	addObject(resource.MarkdownMinioURL)
+	versions, err := s.resourceService.ListVersions(ctx, resource.ID)
+	if err != nil {
+		return nil, err
+	}
+	plan.Versions = len(versions)
+	for _, version := range versions {
+		addObject(version.MinioURL)
+	}
+
+	chunks, err := s.chunkService.GetByResourceID(ctx, resource.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunks: %w", err)
+	}
+	plan.Chunks = len(chunks)
+	chunkIDs := make([]int64, len(chunks))
+	for i, chunk := range chunks {
+		chunkIDs[i] = chunk.ID
+		addChunkObjects(chunk, addObject)
+	}
+
+	translatedResources, err := s.translatedResourceService.GetByOriginalID(ctx, resource.ID)
+	if err != nil {
+		return nil, err
+	}
+	plan.TranslatedResourceIDs = make([]int64, len(translatedResources))
+	for i, tr := range translatedResources {
+		plan.TranslatedResourceIDs[i] = tr.ID
+		addObject(tr.MinioURL)
+	}
+
+	translatedChunks, err := s.translatedChunkService.GetByOriginalChunkIDs(ctx, chunkIDs)
+	if err != nil {
+		return nil, err
+	}
+	plan.TranslatedChunks = len(translatedChunks)
+	for _, tc := range translatedChunks {
+		addObject(tc.MinioURL)
+		addObject(tc.RevisionMinioURL)
+	}
+
+	plan.KnowledgeBaseIDs, err = s.knowledgeBaseService.ListResourceKnowledgeBaseIDs(ctx, resource.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// Delete removes the resource, its versions, chunks, translations, knowledge base entries and MinIO objects.
+// Objects are removed before the rows referencing them, so a failed deletion can simply be run again.
+// It returns the executed plan, or nil if the resource does not exist.
+func (s *Service) Delete(ctx context.Context, resourceID int64) (*Plan, error) {
+	plan, err := s.Plan(ctx, resourceID)
+	if err != nil || plan == nil {
+		return plan, err
+	}
+
+	if err := s.knowledgeBaseService.RemoveResource(ctx, resourceID); err != nil {
+		return nil, fmt.Errorf("failed to remove resource from knowledge bases: %w", err)
+	}
+
+	for _, minioURL := range plan.Objects {
+		bucket, objectName := s.minioService.GetBucketAndObjectFromURL(minioURL)
+		if err := s.minioService.DeleteObject(ctx, bucket, objectName); err != nil {
+			return nil, fmt.Errorf("failed to delete object %s: %w", minioURL, err)
+		}
+	}
+
+	chunks, err := s.chunkService.GetByResourceID(ctx, resourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunks: %w", err)
+	}
+	chunkIDs := make([]int64, len(chunks))
+	for i, chunk := range chunks {
+		chunkIDs[i] = chunk.ID
+	}
+
+	if err := s.translatedChunkService.DeleteByOriginalChunkIDs(ctx, chunkIDs); err != nil {
+		return nil, err
+	}
+	for _, translatedResourceID := range plan.TranslatedResourceIDs {
+		if err := s.translatedChunkService.DeleteByTranslatedResourceID(ctx, translatedResourceID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.translatedResourceService.DeleteByOriginalID(ctx, resourceID); err != nil {
+		return nil, err
+	}
+	if err := s.chunkService.DeleteByResourceID(ctx, resourceID); err != nil {
+		return nil, fmt.Errorf("failed to delete chunks: %w", err)
+	}
+	if err := s.resourceService.Delete(ctx, resourceID); err != nil {
+		return nil, err
+	}
+
+	log.Info("Deleted resource", "resource_id", resourceID, "chunks", plan.Chunks, "objects", len(plan.Objects))
+	return plan, nil
+}
+
+// DeleteChunks removes single chunks of a resource along with their knowledge base entries,
+// translations and MinIO objects. It is used when a re-conversion drops chunks from a document.
+func (s *Service) DeleteChunks(ctx context.Context, resourceID int64, chunks []chunkctrl.Chunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	chunkIDs := make([]int64, len(chunks))
+	for i, chunk := range chunks {
+		chunkIDs[i] = chunk.ID
+	}
+
+	if err := s.knowledgeBaseService.RemoveResourceChunks(ctx, resourceID, chunkIDs); err != nil {
+		return err
+	}
+
+	translatedChunks, err := s.translatedChunkService.GetByOriginalChunkIDs(ctx, chunkIDs)
+	if err != nil {
+		return err
+	}
+
+	var objects []string
+	for _, tc := range translatedChunks {
+		objects = append(objects, tc.MinioURL)
+		if tc.RevisionMinioURL != "" {
+			objects = append(objects, tc.RevisionMinioURL)
+		}
+	}
+	for _, chunk := range chunks {
+		addChunkObjects(chunk, func(minioURL string) {
+			objects = append(objects, minioURL)
+		})
+	}
+	for _, minioURL := range objects {
+		bucket, objectName := s.minioService.GetBucketAndObjectFromURL(minioURL)
+		if err := s.minioService.DeleteObject(ctx, bucket, objectName); err != nil {
+			return fmt.Errorf("failed to delete object %s: %w", minioURL, err)
+		}
+	}
+
+	if err := s.translatedChunkService.DeleteByOriginalChunkIDs(ctx, chunkIDs); err != nil {
+		return err
+	}
+	return s.chunkService.DeleteByIDs(ctx, chunkIDs)
+}
+
+// addChunkObjects passes the chunk content and every artifact stored next to it
+func addChunkObjects(chunk chunkctrl.Chunk, add func(minioURL string)) {
+	for _, minioURL := range []string{chunk.MinioURL, chunk.TableHTMLMinioURL, chunk.TableMarkdownMinioURL, chunk.ImageMinioURL} {
+		if minioURL != "" {
+			add(minioURL)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package translationexport
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// writeBilingualMarkdown writes a two column table with the source and the translation of every chunk side by side
+func writeBilingualMarkdown(w io.Writer, doc *Document) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "| %s | %s |\n", markdownCell(doc.SourceLanguage), markdownCell(doc.TargetLanguage))
+	sb.WriteString("| --- | --- |\n")
+	for _, segment := range doc.Segments {
+		fmt.Fprintf(&sb, "| %s | %s |\n", markdownCell(segment.Source), markdownCell(segment.Target))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// markdownCell fits a text into a single table cell, line breaks become <br>
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(strings.TrimSpace(text), "|", `\|`)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
+var bilingualHTMLTmpl = template.Must(template.New("bilingual").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 8px; vertical-align: top; width: 50%; white-space: pre-wrap; }
+td.untranslated { color: #999; }
+</style>
+</head>
+<body>
+<table>
+<thead>
+<tr><th>{{.SourceLanguage}}</th><th>{{.TargetLanguage}}{{if .Country}} ({{.Country}}){{end}}</th></tr>
+</thead>
+<tbody>
+{{- range .Segments}}
+<tr id="{{.ID}}"><td lang="{{$.SourceLanguage}}">{{.Source}}</td>{{if .Target}}<td lang="{{$.TargetLanguage}}">{{.Target}}</td>{{else}}<td class="untranslated"></td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+</body>
+</html>
+`))
+
+// writeBilingualHTML writes an HTML table with the source and the translation of every chunk side by side
+func writeBilingualHTML(w io.Writer, doc *Document) error {
+	return bilingualHTMLTmpl.Execute(w, doc)
+}
@@ -0,0 +1,139 @@
+package translationexport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"raggo/src/core/document"
+	"raggo/src/core/translationmemory"
+)
+
+// Output formats of a translation
+const (
+	FormatText              = "text"
+	FormatMarkdown          = "markdown"
+	FormatBilingualMarkdown = "bilingual-markdown"
+	FormatBilingualHTML     = "bilingual-html"
+	FormatXLIFF             = "xliff"
+	FormatTMX               = "tmx"
+	FormatSRT               = "srt"
+	FormatVTT               = "vtt"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	ErrNotSubtitles  = errors.New("source is not a subtitle file")
+)
+
+// Segment is a chunk of the source document aligned with its translation
+type Segment struct {
+	ID     string // chunk ID of the source chunk
+	Kind   string
+	Source string
+	Target string // empty if the chunk has not been translated
+}
+
+// Document is a translated resource with its segments in document order
+type Document struct {
+	Name           string // file name of the source document
+	SourceLanguage string
+	TargetLanguage string
+	Country        string
+	Model          string
+	Segments       []Segment
+}
+
+type format struct {
+	contentType string
+	extension   string
+	write       func(w io.Writer, doc *Document) error
+}
+
+var formats = map[string]format{
+	FormatText:              {"text/plain; charset=utf-8", "txt", writeText},
+	FormatMarkdown:          {"text/markdown; charset=utf-8", "md", writeMarkdown},
+	FormatBilingualMarkdown: {"text/markdown; charset=utf-8", "md", writeBilingualMarkdown},
+	FormatBilingualHTML:     {"text/html; charset=utf-8", "html", writeBilingualHTML},
+	FormatXLIFF:             {"application/xliff+xml; charset=utf-8", "xlf", writeXLIFF},
+	FormatTMX:               {"application/x-tmx+xml; charset=utf-8", "tmx", writeTMX},
+	FormatSRT:               {"application/x-subrip; charset=utf-8", "srt", writeSRT},
+	FormatVTT:               {"text/vtt; charset=utf-8", "vtt", writeVTT},
+}
+
+// IsValidFormat reports whether the format is one of the output formats
+func IsValidFormat(name string) bool {
+	_, ok := formats[name]
+	return ok
+}
+
+// ContentType returns the MIME type of the format
+func ContentType(name string) string {
+	return formats[name].contentType
+}
+
+// Filename returns the name of the exported file, derived from the source document name and the target locale
+func Filename(doc *Document, name string) string {
+	base := doc.Name
+	if i := strings.LastIndex(base, "."); i > 0 {
+		base = base[:i]
+	}
+	locale := doc.TargetLanguage
+	if doc.Country != "" {
+		locale += "_" + doc.Country
+	}
+	return fmt.Sprintf("%s.%s.%s", base, locale, formats[name].extension)
+}
+
+// Write writes the document in the format
+func Write(w io.Writer, doc *Document, name string) error {
+	f, ok := formats[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+	}
+	return f.write(w, doc)
+}
+
+// targetParts returns the translated segments as document parts, skipping the untranslated ones
+func targetParts(doc *Document) []document.Part {
+	parts := make([]document.Part, 0, len(doc.Segments))
+	for _, segment := range doc.Segments {
+		if segment.Target == "" {
+			continue
+		}
+		parts = append(parts, document.Part{Kind: segment.Kind, Content: segment.Target})
+	}
+	return parts
+}
+
+func writeText(w io.Writer, doc *Document) error {
+	text, _ := document.Assemble(targetParts(doc))
+	_, err := io.WriteString(w, text)
+	return err
+}
+
+func writeMarkdown(w io.Writer, doc *Document) error {
+	_, markdown := document.Assemble(targetParts(doc))
+	_, err := io.WriteString(w, markdown)
+	return err
+}
+
+// writeTMX exports the translated segments as translation units for CAT tools
+func writeTMX(w io.Writer, doc *Document) error {
+	entries := make([]translationmemory.Entry, 0, len(doc.Segments))
+	for _, segment := range doc.Segments {
+		if segment.Target == "" {
+			continue
+		}
+		entries = append(entries, translationmemory.Entry{
+			SourceLanguage: doc.SourceLanguage,
+			TargetLanguage: doc.TargetLanguage,
+			Country:        doc.Country,
+			Model:          doc.Model,
+			SourceText:     segment.Source,
+			TargetText:     segment.Target,
+		})
+	}
+	return translationmemory.WriteTMX(w, doc.SourceLanguage, entries)
+}
@@ -0,0 +1,195 @@
+package translationexport_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"raggo/src/core/translationexport"
+)
+
+func testDocument() *translationexport.Document {
+	return &translationexport.Document{
+		Name:           "manual.pdf",
+		SourceLanguage: "en",
+		TargetLanguage: "de",
+		Segments: []translationexport.Segment{
+			{ID: "c1", Kind: "text", Source: "# Setup", Target: "# Einrichtung"},
+			{ID: "c2", Kind: "text", Source: "Press | hold\nthe button.", Target: "Taste drücken\n& halten."},
+			{ID: "c3", Kind: "text", Source: "Not translated yet."},
+		},
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	tests := []struct {
+		format       string
+		wantContains []string
+		wantMissing  []string
+	}{
+		{
+			format:       translationexport.FormatMarkdown,
+			wantContains: []string{"# Einrichtung\n\nTaste drücken\n& halten."},
+			wantMissing:  []string{"Not translated yet."},
+		},
+		{
+			format: translationexport.FormatBilingualMarkdown,
+			wantContains: []string{
+				"| en | de |",
+				"| # Setup | # Einrichtung |",
+				`| Press \| hold<br>the button. | Taste drücken<br>& halten. |`,
+				"| Not translated yet. |  |",
+			},
+		},
+		{
+			format:       translationexport.FormatBilingualHTML,
+			wantContains: []string{`<tr id="c2"><td lang="en">Press | hold` + "\n", "Taste drücken\n&amp; halten.</td>", `<td class="untranslated"></td>`},
+		},
+		{
+			format: translationexport.FormatXLIFF,
+			wantContains: []string{
+				`<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="en" trgLang="de">`,
+				`<unit id="u2" name="c2">`,
+				`<segment state="translated">`,
+				`<target>Taste drücken&#xA;&amp; halten.</target>`,
+				`<segment state="initial">`,
+			},
+		},
+		{
+			format:       translationexport.FormatTMX,
+			wantContains: []string{`<seg># Einrichtung</seg>`},
+			wantMissing:  []string{"Not translated yet."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := translationexport.Write(&buf, testDocument(), tt.format); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			got := buf.String()
+			for _, want := range tt.wantContains {
+				if !strings.Contains(got, want) {
+					t.Errorf("Write() output does not contain %q:\n%s", want, got)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(got, missing) {
+					t.Errorf("Write() output contains %q:\n%s", missing, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteXLIFFIsWellFormed(t *testing.T) {
+	var buf bytes.Buffer
+	if err := translationexport.Write(&buf, testDocument(), translationexport.FormatXLIFF); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	var parsed struct {
+		Units []struct {
+			Name   string  `xml:"name,attr"`
+			Source string  `xml:"segment>source"`
+			Target *string `xml:"segment>target"`
+		} `xml:"file>unit"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatalf("xml.Unmarshal() error = %v", err)
+	}
+	if len(parsed.Units) != 3 {
+		t.Fatalf("got %d units, want 3", len(parsed.Units))
+	}
+	if parsed.Units[1].Target == nil || *parsed.Units[1].Target != "Taste drücken\n& halten." {
+		t.Errorf("unit 2 target = %v, want the translation", parsed.Units[1].Target)
+	}
+	if parsed.Units[2].Target != nil {
+		t.Errorf("untranslated unit has target %q", *parsed.Units[2].Target)
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	err := translationexport.Write(&bytes.Buffer{}, testDocument(), "docx")
+	if !errors.Is(err, translationexport.ErrUnknownFormat) {
+		t.Errorf("Write() error = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestFilename(t *testing.T) {
+	doc := testDocument()
+	doc.Country = "AT"
+	if got := translationexport.Filename(doc, translationexport.FormatXLIFF); got != "manual.de_AT.xlf" {
+		t.Errorf("Filename() = %q, want manual.de_AT.xlf", got)
+	}
+}
+
+func TestParseCues(t *testing.T) {
+	text := "WEBVTT\n\nNOTE a comment\n\n1\n00:00:01.000 --> 00:00:04.500 align:start\nHello\nworld\n\n01:02.250 --> 01:03.000\nBye"
+	want := []translationexport.Cue{
+		{Start: time.Second, End: 4500 * time.Millisecond, Text: "Hello\nworld"},
+		{Start: time.Minute + 2250*time.Millisecond, End: time.Minute + 3*time.Second, Text: "Bye"},
+	}
+
+	got := translationexport.ParseCues(text)
+	if len(got) != len(want) {
+		t.Fatalf("ParseCues() returned %d cues, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cue %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWriteSubtitles(t *testing.T) {
+	doc := &translationexport.Document{
+		SourceLanguage: "en",
+		TargetLanguage: "de",
+		Segments: []translationexport.Segment{
+			{
+				// Translation kept the cues
+				Source: "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n2\n00:00:02,500 --> 00:00:03,000\nWorld",
+				Target: "1\n00:00:01,000 --> 00:00:02,000\nHallo\n\n2\n00:00:02,500 --> 00:00:03,000\nWelt",
+			},
+			{
+				// Translation lost the cues and is shown over their whole time span
+				Source: "3\n00:00:04,000 --> 00:00:05,000\nGood\n\n4\n00:00:05,000 --> 00:00:06,000\nbye",
+				Target: "Auf Wiedersehen",
+			},
+			{
+				// Untranslated
+				Source: "5\n00:00:07,000 --> 00:00:08,000\nThe end",
+			},
+		},
+	}
+
+	var srt bytes.Buffer
+	if err := translationexport.Write(&srt, doc, translationexport.FormatSRT); err != nil {
+		t.Fatalf("Write(srt) error = %v", err)
+	}
+	wantSRT := "1\n00:00:01,000 --> 00:00:02,000\nHallo\n\n" +
+		"2\n00:00:02,500 --> 00:00:03,000\nWelt\n\n" +
+		"3\n00:00:04,000 --> 00:00:06,000\nAuf Wiedersehen\n\n" +
+		"4\n00:00:07,000 --> 00:00:08,000\nThe end\n\n"
+	if srt.String() != wantSRT {
+		t.Errorf("Write(srt) = %q, want %q", srt.String(), wantSRT)
+	}
+
+	var vtt bytes.Buffer
+	if err := translationexport.Write(&vtt, doc, translationexport.FormatVTT); err != nil {
+		t.Fatalf("Write(vtt) error = %v", err)
+	}
+	if !strings.HasPrefix(vtt.String(), "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHallo\n\n") {
+		t.Errorf("Write(vtt) = %q", vtt.String())
+	}
+
+	err := translationexport.Write(&bytes.Buffer{}, testDocument(), translationexport.FormatSRT)
+	if !errors.Is(err, translationexport.ErrNotSubtitles) {
+		t.Errorf("Write(srt) of a non subtitle document error = %v, want ErrNotSubtitles", err)
+	}
+}
@@ -0,0 +1,146 @@
+package translationexport
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cue is a subtitle shown between its start and end time
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// cueTimingRegex matches the timing line of an SRT or WebVTT cue, e.g. 00:00:01,000 --> 00:00:04,500.
+// WebVTT allows omitting the hours and adding cue settings after the end time.
+var cueTimingRegex = regexp.MustCompile(`^((?:\d+:)?\d{2}:\d{2}[,.]\d{3})\s+-->\s+((?:\d+:)?\d{2}:\d{2}[,.]\d{3})`)
+
+// cueBlockSeparatorRegex matches the blank lines between cues
+var cueBlockSeparatorRegex = regexp.MustCompile(`\n\s*\n`)
+
+// ParseCues returns the cues of an SRT or WebVTT text. Blocks without a timing line,
+// like the WEBVTT header and NOTE blocks, are skipped.
+func ParseCues(text string) []Cue {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var cues []Cue
+	for _, block := range cueBlockSeparatorRegex.Split(strings.TrimSpace(text), -1) {
+		lines := strings.Split(block, "\n")
+		for i, line := range lines {
+			match := cueTimingRegex.FindStringSubmatch(strings.TrimSpace(line))
+			if match == nil {
+				continue
+			}
+			start, err := parseTimestamp(match[1])
+			if err != nil {
+				break
+			}
+			end, err := parseTimestamp(match[2])
+			if err != nil {
+				break
+			}
+			cues = append(cues, Cue{Start: start, End: end, Text: strings.TrimSpace(strings.Join(lines[i+1:], "\n"))})
+			break
+		}
+	}
+	return cues
+}
+
+// parseTimestamp parses [hh:]mm:ss,mmm or [hh:]mm:ss.mmm
+func parseTimestamp(timestamp string) (time.Duration, error) {
+	timestamp = strings.Replace(timestamp, ",", ".", 1)
+	clock, millis, _ := strings.Cut(timestamp, ".")
+	fields := strings.Split(clock, ":")
+	if len(fields) == 2 {
+		fields = append([]string{"0"}, fields...)
+	}
+
+	var values [4]int
+	for i, field := range append(fields, millis) {
+		value, err := strconv.Atoi(field)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q", timestamp)
+		}
+		values[i] = value
+	}
+	return time.Duration(values[0])*time.Hour +
+		time.Duration(values[1])*time.Minute +
+		time.Duration(values[2])*time.Second +
+		time.Duration(values[3])*time.Millisecond, nil
+}
+
+func formatTimestamp(d time.Duration, millisSeparator string) string {
+	hours := d / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	seconds := (d % time.Minute) / time.Second
+	millis := (d % time.Second) / time.Millisecond
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", hours, minutes, seconds, millisSeparator, millis)
+}
+
+// TranslatedCues returns the cues of the source segments with the text of their translation.
+// The cues of a translation that kept the cue structure of its chunk are used as they are.
+// Otherwise the whole translation of the chunk is shown over the time span of its cues,
+// and untranslated chunks keep their source cues.
+func TranslatedCues(doc *Document) ([]Cue, error) {
+	var cues []Cue
+	for _, segment := range doc.Segments {
+		sourceCues := ParseCues(segment.Source)
+		if len(sourceCues) == 0 {
+			continue
+		}
+
+		switch targetCues := ParseCues(segment.Target); {
+		case segment.Target == "":
+			cues = append(cues, sourceCues...)
+		case len(targetCues) == len(sourceCues):
+			for i := range sourceCues {
+				cues = append(cues, Cue{Start: sourceCues[i].Start, End: sourceCues[i].End, Text: targetCues[i].Text})
+			}
+		default:
+			cues = append(cues, Cue{
+				Start: sourceCues[0].Start,
+				End:   sourceCues[len(sourceCues)-1].End,
+				Text:  strings.TrimSpace(segment.Target),
+			})
+		}
+	}
+
+	if len(cues) == 0 {
+		return nil, ErrNotSubtitles
+	}
+	return cues, nil
+}
+
+func writeSRT(w io.Writer, doc *Document) error {
+	cues, err := TranslatedCues(doc)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	for i, cue := range cues {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1, formatTimestamp(cue.Start, ","), formatTimestamp(cue.End, ","), cue.Text)
+	}
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+func writeVTT(w io.Writer, doc *Document) error {
+	cues, err := TranslatedCues(doc)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n\n")
+	for _, cue := range cues {
+		fmt.Fprintf(&sb, "%s --> %s\n%s\n\n", formatTimestamp(cue.Start, "."), formatTimestamp(cue.End, "."), cue.Text)
+	}
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,73 @@
+package translationexport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+const xliffNamespace = "urn:oasis:names:tc:xliff:document:2.0"
+
+type xliffDocument struct {
+	XMLName xml.Name  `xml:"xliff"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	SrcLang string    `xml:"srcLang,attr"`
+	TrgLang string    `xml:"trgLang,attr"`
+	File    xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	ID       string      `xml:"id,attr"`
+	Original string      `xml:"original,attr,omitempty"`
+	Units    []xliffUnit `xml:"unit"`
+}
+
+type xliffUnit struct {
+	ID      string       `xml:"id,attr"`
+	Name    string       `xml:"name,attr,omitempty"`
+	Segment xliffSegment `xml:"segment"`
+}
+
+type xliffSegment struct {
+	State  string  `xml:"state,attr"`
+	Source string  `xml:"source"`
+	Target *string `xml:"target"`
+}
+
+// writeXLIFF writes the document as an XLIFF 2.0 file with one unit per chunk.
+// Untranslated chunks have no target and the state initial, so CAT tools pick them up as open work.
+func writeXLIFF(w io.Writer, doc *Document) error {
+	xliff := xliffDocument{
+		Xmlns:   xliffNamespace,
+		Version: "2.0",
+		SrcLang: doc.SourceLanguage,
+		TrgLang: doc.TargetLanguage,
+		File:    xliffFile{ID: "f1", Original: doc.Name},
+	}
+
+	for i, segment := range doc.Segments {
+		unit := xliffUnit{
+			// Unit IDs must be NMTOKENs, the chunk ID is kept as the name
+			ID:      fmt.Sprintf("u%d", i+1),
+			Name:    segment.ID,
+			Segment: xliffSegment{State: "initial", Source: segment.Source},
+		}
+		if segment.Target != "" {
+			target := segment.Target
+			unit.Segment.State = "translated"
+			unit.Segment.Target = &target
+		}
+		xliff.File.Units = append(xliff.File.Units, unit)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(xliff); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,153 @@
+package translationflow
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	ViolationMissingTerm         = "missing_term"         // a glossary term was not translated as prescribed
+	ViolationTranslatedProtected = "translated_protected" // a do-not-translate term is missing from the translation
+)
+
+// GlossaryTerm prescribes how a source term has to be translated
+type GlossaryTerm struct {
+	SourceTerm string `json:"source_term"`
+	TargetTerm string `json:"target_term"`
+}
+
+// Glossary holds the terminology a translation has to follow
+type Glossary struct {
+	Terms          []GlossaryTerm `json:"terms"`
+	DoNotTranslate []string       `json:"do_not_translate"`
+}
+
+// Violation is a glossary rule the translation does not follow
+type Violation struct {
+	Kind       string `json:"kind"`
+	SourceTerm string `json:"source_term"`
+	Expected   string `json:"expected"`
+}
+
+// Signature identifies the rules of the glossary so that translations following different glossaries are not
+// mixed up. The order of the rules does not matter. It is empty for a glossary without rules.
+func (g *Glossary) Signature() string {
+	if g == nil || (len(g.Terms) == 0 && len(g.DoNotTranslate) == 0) {
+		return ""
+	}
+
+	normalized := Glossary{
+		Terms:          append([]GlossaryTerm(nil), g.Terms...),
+		DoNotTranslate: append([]string(nil), g.DoNotTranslate...),
+	}
+	sort.Slice(normalized.Terms, func(i, j int) bool {
+		if normalized.Terms[i].SourceTerm != normalized.Terms[j].SourceTerm {
+			return normalized.Terms[i].SourceTerm < normalized.Terms[j].SourceTerm
+		}
+		return normalized.Terms[i].TargetTerm < normalized.Terms[j].TargetTerm
+	})
+	sort.Strings(normalized.DoNotTranslate)
+	return signature(normalized)
+}
+
+// Relevant returns the part of the glossary whose source terms occur in the text
+func (g *Glossary) Relevant(text string) *Glossary {
+	if g == nil {
+		return nil
+	}
+
+	relevant := &Glossary{}
+	for _, term := range g.Terms {
+		if containsTerm(text, term.SourceTerm) {
+			relevant.Terms = append(relevant.Terms, term)
+		}
+	}
+	for _, term := range g.DoNotTranslate {
+		if containsTerm(text, term) {
+			relevant.DoNotTranslate = append(relevant.DoNotTranslate, term)
+		}
+	}
+	return relevant
+}
+
+// IsEmpty reports whether the glossary has no rules
+func (g *Glossary) IsEmpty() bool {
+	return g == nil || (len(g.Terms) == 0 && len(g.DoNotTranslate) == 0)
+}
+
+// Verify checks the translation of the source text against the glossary.
+// Terms are matched case-insensitively, do-not-translate terms have to appear verbatim.
+func (g *Glossary) Verify(source, translation string) []Violation {
+	if g == nil {
+		return nil
+	}
+
+	relevant := g.Relevant(source)
+	var violations []Violation
+	for _, term := range relevant.Terms {
+		if !containsTerm(translation, term.TargetTerm) {
+			violations = append(violations, Violation{
+				Kind:       ViolationMissingTerm,
+				SourceTerm: term.SourceTerm,
+				Expected:   term.TargetTerm,
+			})
+		}
+	}
+	for _, term := range relevant.DoNotTranslate {
+		if !strings.Contains(translation, term) {
+			violations = append(violations, Violation{
+				Kind:       ViolationTranslatedProtected,
+				SourceTerm: term,
+				Expected:   term,
+			})
+		}
+	}
+	return violations
+}
+
+// String renders the glossary for the prompt templates
+func (g *Glossary) String() string {
+	if g.IsEmpty() {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, term := range g.Terms {
+		sb.WriteString(term.SourceTerm + " => " + term.TargetTerm + "\n")
+	}
+	for _, term := range g.DoNotTranslate {
+		sb.WriteString(term + " => " + term + " (do not translate)\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// containsTerm reports whether the text contains the term, ignoring case.
+// Word boundaries are only required at ASCII letter or digit edges, so "API" does not match "RAPID"
+// while terms in Chinese or Japanese still match inside a sentence.
+func containsTerm(text, term string) bool {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return false
+	}
+
+	pattern := regexp.QuoteMeta(term)
+	runes := []rune(term)
+	if isWordRune(runes[0]) {
+		pattern = `\b` + pattern
+	}
+	if isWordRune(runes[len(runes)-1]) {
+		pattern += `\b`
+	}
+
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return strings.Contains(strings.ToLower(text), strings.ToLower(term))
+	}
+	return re.MatchString(text)
+}
+
+func isWordRune(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
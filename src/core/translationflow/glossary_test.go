@@ -0,0 +1,112 @@
+package translationflow_test
+
+import (
+	"reflect"
+	"testing"
+
+	"raggo/src/core/translationflow"
+)
+
+func TestGlossaryVerify(t *testing.T) {
+	glossary := &translationflow.Glossary{
+		Terms: []translationflow.GlossaryTerm{
+			{SourceTerm: "API", TargetTerm: "應用程式介面"},
+			{SourceTerm: "power supply", TargetTerm: "電源供應器"},
+		},
+		DoNotTranslate: []string{"RaggoBox"},
+	}
+
+	tests := []struct {
+		name        string
+		source      string
+		translation string
+		want        []translationflow.Violation
+	}{
+		{
+			name:        "all terms followed",
+			source:      "Connect the Power Supply to the RaggoBox and call the API.",
+			translation: "將電源供應器連接到 RaggoBox 並呼叫應用程式介面。",
+		},
+		{
+			name:        "term inside another word is not matched",
+			source:      "RAPID charging",
+			translation: "快速充電",
+		},
+		{
+			name:        "missing term and translated product name",
+			source:      "The RaggoBox exposes an API.",
+			translation: "拉格盒提供一個 API。",
+			want: []translationflow.Violation{
+				{Kind: translationflow.ViolationMissingTerm, SourceTerm: "API", Expected: "應用程式介面"},
+				{Kind: translationflow.ViolationTranslatedProtected, SourceTerm: "RaggoBox", Expected: "RaggoBox"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := glossary.Verify(tt.source, tt.translation)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Verify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGlossaryRelevant(t *testing.T) {
+	glossary := &translationflow.Glossary{
+		Terms: []translationflow.GlossaryTerm{
+			{SourceTerm: "firmware", TargetTerm: "韌體"},
+			{SourceTerm: "battery", TargetTerm: "電池"},
+		},
+		DoNotTranslate: []string{"Raggo"},
+	}
+
+	got := glossary.Relevant("Update the Firmware before use.").String()
+	want := "firmware => 韌體"
+	if got != want {
+		t.Errorf("Relevant().String() = %q, want %q", got, want)
+	}
+
+	var empty *translationflow.Glossary
+	if got := empty.Relevant("anything").String(); got != "" {
+		t.Errorf("nil glossary rendered %q, want empty string", got)
+	}
+}
+
+func TestGlossarySignature(t *testing.T) {
+	glossary := &translationflow.Glossary{
+		Terms: []translationflow.GlossaryTerm{
+			{SourceTerm: "firmware", TargetTerm: "韌體"},
+			{SourceTerm: "battery", TargetTerm: "電池"},
+		},
+		DoNotTranslate: []string{"Raggo", "USB-C"},
+	}
+	reordered := &translationflow.Glossary{
+		Terms: []translationflow.GlossaryTerm{
+			{SourceTerm: "battery", TargetTerm: "電池"},
+			{SourceTerm: "firmware", TargetTerm: "韌體"},
+		},
+		DoNotTranslate: []string{"USB-C", "Raggo"},
+	}
+	changed := &translationflow.Glossary{
+		Terms: []translationflow.GlossaryTerm{
+			{SourceTerm: "battery", TargetTerm: "蓄電池"},
+			{SourceTerm: "firmware", TargetTerm: "韌體"},
+		},
+		DoNotTranslate: []string{"USB-C", "Raggo"},
+	}
+
+	if glossary.Signature() == "" {
+		t.Fatal("Signature() of a glossary with rules is empty")
+	}
+	if glossary.Signature() != reordered.Signature() {
+		t.Errorf("Signature() depends on the order of the rules")
+	}
+	if glossary.Signature() == changed.Signature() {
+		t.Errorf("Signature() does not change with a rule")
+	}
+	if got := (*translationflow.Glossary)(nil).Signature(); got != "" {
+		t.Errorf("Signature() of no glossary = %q, want empty", got)
+	}
+}
@@ -0,0 +1,71 @@
+package translationflow
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter bounds how many generation requests run against a provider at the same time and how fast they start.
+// One limiter is shared by all translations that use the same provider.
+type Limiter struct {
+	slots    chan struct{}
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewLimiter returns a limiter allowing maxConcurrency requests at once and starting at most
+// requestsPerSecond of them per second. Zero or negative values leave the respective bound off.
+func NewLimiter(maxConcurrency int, requestsPerSecond float64) *Limiter {
+	l := &Limiter{}
+	if maxConcurrency > 0 {
+		l.slots = make(chan struct{}, maxConcurrency)
+	}
+	if requestsPerSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	return l
+}
+
+// Acquire blocks until a request may start. Every successful Acquire must be followed by Release.
+func (l *Limiter) Acquire(ctx context.Context) error {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if l.interval > 0 {
+		// Reserve the next start time, so that waiting requests start one interval apart
+		l.mu.Lock()
+		now := time.Now()
+		start := l.next
+		if start.Before(now) {
+			start = now
+		}
+		l.next = start.Add(l.interval)
+		l.mu.Unlock()
+
+		timer := time.NewTimer(start.Sub(now))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			l.Release()
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Release frees the slot of a finished request
+func (l *Limiter) Release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
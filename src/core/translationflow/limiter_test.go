@@ -0,0 +1,115 @@
+package translationflow_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"raggo/src/core/translationflow"
+)
+
+func TestLimiterConcurrency(t *testing.T) {
+	limiter := translationflow.NewLimiter(2, 0)
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Acquire(context.Background()); err != nil {
+				t.Error(err)
+				return
+			}
+			defer limiter.Release()
+
+			n := running.Add(1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	if got := maxRunning.Load(); got != 2 {
+		t.Errorf("max concurrent requests = %d, want 2", got)
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	limiter := translationflow.NewLimiter(0, 100) // one request every 10ms
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.Acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		limiter.Release()
+	}
+
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("4 requests at 100/s took %v, want at least 30ms", elapsed)
+	}
+}
+
+func TestLimiterCanceled(t *testing.T) {
+	limiter := translationflow.NewLimiter(1, 0)
+	if err := limiter.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Acquire(ctx); err == nil {
+		t.Error("Acquire() succeeded on a full limiter with a canceled context")
+	}
+}
+
+// echoProvider translates a part by returning it upper-cased after a delay that is longer for earlier parts
+type echoProvider struct {
+	chunks []string
+}
+
+func (p *echoProvider) TextSplit(ctx context.Context, text string, chunkSize, chunkOverLap int) ([]string, error) {
+	return p.chunks, nil
+}
+
+func (p *echoProvider) Reasoning(ctx context.Context, system string, prompt string) (string, error) {
+	for i, chunk := range p.chunks {
+		if strings.Contains(prompt, "<TRANSLATE_THIS>\n"+chunk+"\n</TRANSLATE_THIS>") {
+			time.Sleep(time.Duration(len(p.chunks)-i) * 2 * time.Millisecond)
+			return strings.ToUpper(chunk), nil
+		}
+	}
+	return "", nil
+}
+
+func (p *echoProvider) TokenLength(ctx context.Context, text string) (int, error) {
+	return 1000, nil
+}
+
+func TestConcurrentMultiChunkKeepsOrder(t *testing.T) {
+	provider := &echoProvider{chunks: []string{"one", "two", "three", "four"}}
+	tf := translationflow.NewTranslationFlow(
+		provider,
+		translationflow.WithSkipReflection(true),
+		translationflow.WithConcurrency(4),
+		translationflow.WithLimiter(translationflow.NewLimiter(2, 0)),
+	)
+
+	got, err := tf.Translate(context.Background(), "one two three four", "English", "German", "")
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+	if got != "ONE TWO THREE FOUR" {
+		t.Errorf("Translate() = %q, want the parts in source order", got)
+	}
+}
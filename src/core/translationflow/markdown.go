@@ -0,0 +1,48 @@
+package translationflow
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// fencedCodeBlockRegex matches Markdown code blocks fenced by ``` or ~~~, including their fences
+var fencedCodeBlockRegex = regexp.MustCompile("(?ms)^[ \t]*(```|~~~)[^\n]*\n.*?^[ \t]*(```|~~~)[ \t]*$")
+
+// codeBlockPlaceholderRegex matches the placeholders of protected code blocks
+var codeBlockPlaceholderRegex = regexp.MustCompile(`\[\[CODE_BLOCK_\d+\]\]`)
+
+// codeBlockPlaceholder returns the placeholder standing in for the i-th code block while the text is translated
+func codeBlockPlaceholder(i int) string {
+	return fmt.Sprintf("[[CODE_BLOCK_%d]]", i+1)
+}
+
+// protectCodeBlocks replaces the fenced code blocks of a Markdown text by placeholders,
+// so that code is never sent to the model and comes back unchanged
+func protectCodeBlocks(text string) (string, []string) {
+	var blocks []string
+	masked := fencedCodeBlockRegex.ReplaceAllStringFunc(text, func(block string) string {
+		placeholder := codeBlockPlaceholder(len(blocks))
+		blocks = append(blocks, block)
+		return placeholder
+	})
+	return masked, blocks
+}
+
+// onlyPlaceholders reports whether a masked text has nothing left to translate
+func onlyPlaceholders(masked string) bool {
+	return strings.TrimSpace(codeBlockPlaceholderRegex.ReplaceAllString(masked, "")) == ""
+}
+
+// restoreCodeBlocks puts the code blocks back in place of their placeholders.
+// A translation that lost a placeholder is rejected, since the code block would silently disappear from the output.
+func restoreCodeBlocks(translation string, blocks []string) (string, error) {
+	for i, block := range blocks {
+		placeholder := codeBlockPlaceholder(i)
+		if !strings.Contains(translation, placeholder) {
+			return "", fmt.Errorf("translation lost code block %d", i+1)
+		}
+		translation = strings.Replace(translation, placeholder, block, 1)
+	}
+	return translation, nil
+}
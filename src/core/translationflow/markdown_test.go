@@ -0,0 +1,85 @@
+package translationflow_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"raggo/src/core/translationflow"
+)
+
+// fixedProvider answers every prompt with the same answer and records the prompts it was given
+type fixedProvider struct {
+	answer  string
+	prompts []string
+}
+
+func (p *fixedProvider) TextSplit(ctx context.Context, text string, chunkSize, chunkOverLap int) ([]string, error) {
+	return []string{text}, nil
+}
+
+func (p *fixedProvider) Reasoning(ctx context.Context, system string, prompt string) (string, error) {
+	p.prompts = append(p.prompts, prompt)
+	return p.answer, nil
+}
+
+func (p *fixedProvider) TokenLength(ctx context.Context, text string) (int, error) {
+	return 10, nil
+}
+
+func TestTranslateKeepsCodeBlocks(t *testing.T) {
+	codeBlock := "```go\nfmt.Println(\"Hello\")\n```"
+
+	tests := []struct {
+		name         string
+		text         string
+		answer       string
+		want         string
+		wantErr      bool
+		wantReasoned int
+	}{
+		{
+			name:         "code block is restored",
+			text:         "## Example\n\nPrint a greeting:\n\n" + codeBlock,
+			answer:       "## Beispiel\n\nEinen Gruß ausgeben:\n\n[[CODE_BLOCK_1]]",
+			want:         "## Beispiel\n\nEinen Gruß ausgeben:\n\n" + codeBlock,
+			wantReasoned: 3,
+		},
+		{
+			name:         "lost placeholder is an error",
+			text:         "Print a greeting:\n\n" + codeBlock,
+			answer:       "Einen Gruß ausgeben:",
+			wantErr:      true,
+			wantReasoned: 3,
+		},
+		{
+			name:         "code only is not translated",
+			text:         codeBlock + "\n",
+			want:         codeBlock + "\n",
+			wantReasoned: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fixedProvider{answer: tt.answer}
+			tf := translationflow.NewTranslationFlow(provider)
+			got, err := tf.Translate(context.Background(), tt.text, "English", "German", "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Translate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Translate() = %q, want %q", got, tt.want)
+			}
+
+			if len(provider.prompts) != tt.wantReasoned {
+				t.Fatalf("Translate() reasoned %d times, want %d", len(provider.prompts), tt.wantReasoned)
+			}
+			for i, prompt := range provider.prompts {
+				if strings.Contains(prompt, "fmt.Println") {
+					t.Errorf("prompt %d contains the code block", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package translationflow
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Stages of the translation flow that can run on their own model
+const (
+	StageInitial     = "initial"
+	StageReflection  = "reflection"
+	StageImprovement = "improvement"
+	// StageQualityEstimation scores a finished translation and does not change it
+	StageQualityEstimation = "quality_estimation"
+)
+
+const (
+	DefaultReviewRounds = 1
+	MaxReviewRounds     = 5
+)
+
+// Names of the templates that can be overridden
+const (
+	TmplOneChunkInitialSystem             = "one_chunk_initial_system"
+	TmplOneChunkInitialPrompt             = "one_chunk_initial_prompt"
+	TmplOneChunkReflectionSystem          = "one_chunk_reflection_system"
+	TmplOneChunkReflectionPrompt          = "one_chunk_reflection_prompt"
+	TmplOneChunkReflectionCountryPrompt   = "one_chunk_reflection_country_prompt"
+	TmplOneChunkImprovementSystem         = "one_chunk_improvement_system"
+	TmplOneChunkImprovementPrompt         = "one_chunk_improvement_prompt"
+	TmplMultiChunkInitialSystem           = "multi_chunk_initial_system"
+	TmplMultiChunkInitialPrompt           = "multi_chunk_initial_prompt"
+	TmplMultiChunkReflectionSystem        = "multi_chunk_reflection_system"
+	TmplMultiChunkReflectionPrompt        = "multi_chunk_reflection_prompt"
+	TmplMultiChunkReflectionCountryPrompt = "multi_chunk_reflection_country_prompt"
+	TmplMultiChunkImprovementSystem       = "multi_chunk_improvement_system"
+	TmplMultiChunkImprovementPrompt       = "multi_chunk_improvement_prompt"
+	TmplQualityEstimationSystem           = "quality_estimation_system"
+	TmplQualityEstimationPrompt           = "quality_estimation_prompt"
+	TmplBackTranslationSystem             = "back_translation_system"
+	TmplBackTranslationPrompt             = "back_translation_prompt"
+)
+
+// defaultTemplates maps every template name to its compiled-in text
+var defaultTemplates = map[string]string{
+	TmplOneChunkInitialSystem:             OneChunkInitialTranslationSystemMessageTmpl,
+	TmplOneChunkInitialPrompt:             OneChunkInitialTranslationPromptTmpl,
+	TmplOneChunkReflectionSystem:          OneChunkReflectOnTranslationSystemMessageTmpl,
+	TmplOneChunkReflectionPrompt:          OneChunkReflectOnTranslationPromptTmpl,
+	TmplOneChunkReflectionCountryPrompt:   OneChunkReflectOnTranslationWithCountryPromptTmpl,
+	TmplOneChunkImprovementSystem:         OneChunkImprovementTranslationSystemMessageTmpl,
+	TmplOneChunkImprovementPrompt:         OneChunkImprovementTranslationPromptTmpl,
+	TmplMultiChunkInitialSystem:           MultiChunkTranslationSystemMessageTmpl,
+	TmplMultiChunkInitialPrompt:           MultiChunkTranslationPromptTmpl,
+	TmplMultiChunkReflectionSystem:        MultiChunkReflectionSystemMessageTmpl,
+	TmplMultiChunkReflectionPrompt:        MultiChunkReflectionPromptTmpl,
+	TmplMultiChunkReflectionCountryPrompt: MultiChunkReflectionWithCountryPromptTmpl,
+	TmplMultiChunkImprovementSystem:       MultiChunkImprovementSystemMessageTmpl,
+	TmplMultiChunkImprovementPrompt:       MultiChunkImprovementPromptTmpl,
+	TmplQualityEstimationSystem:           QualityEstimationSystemMessageTmpl,
+	TmplQualityEstimationPrompt:           QualityEstimationPromptTmpl,
+	TmplBackTranslationSystem:             BackTranslationSystemMessageTmpl,
+	TmplBackTranslationPrompt:             BackTranslationPromptTmpl,
+}
+
+// sampleTemplateData fills every field so that validation executes all branches of a template
+var sampleTemplateData = TemplateData{
+	SourceLang:       "English",
+	TargetLang:       "Chinese",
+	Country:          "Taiwan",
+	SourceText:       "source",
+	TaggedText:       "<TRANSLATE_THIS>source</TRANSLATE_THIS>",
+	ChunkToTranslate: "source",
+	Translation1:     "translation",
+	TranslationChunk: "translation",
+	Reflection:       "reflection",
+	ReflectionChunk:  "reflection",
+	Glossary:         "source => translation",
+	References:       "<SOURCE>source</SOURCE>\n<TRANSLATION>translation</TRANSLATION>",
+	StyleGuide:       "Tone: neutral",
+}
+
+// Templates holds the compiled templates of a translation flow
+type Templates struct {
+	templates map[string]*template.Template
+}
+
+// NewTemplates compiles the default templates with the overrides applied.
+// Overrides with an unknown name, invalid syntax or unknown fields are rejected here
+// so that a broken template never reaches a running translation.
+func NewTemplates(overrides map[string]string) (*Templates, error) {
+	for name := range overrides {
+		if _, ok := defaultTemplates[name]; !ok {
+			return nil, fmt.Errorf("unknown template %q", name)
+		}
+	}
+
+	t := &Templates{templates: make(map[string]*template.Template, len(defaultTemplates))}
+	for name, text := range defaultTemplates {
+		if override, ok := overrides[name]; ok {
+			text = override
+		}
+
+		tmpl, err := parseTemplate(name, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %q: %w", name, err)
+		}
+		if err := tmpl.Execute(&bytes.Buffer{}, sampleTemplateData); err != nil {
+			return nil, fmt.Errorf("failed to execute template %q: %w", name, err)
+		}
+		t.templates[name] = tmpl
+	}
+
+	return t, nil
+}
+
+// parseTemplate compiles the text together with the shared prompt blocks
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Parse(PromptBlocksTmpl)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Parse(text)
+}
+
+// DefaultTemplates returns the compiled-in templates
+func DefaultTemplates() *Templates {
+	t, err := NewTemplates(nil)
+	if err != nil {
+		// The compiled-in templates are covered by tests
+		panic(err)
+	}
+	return t
+}
+
+func (t *Templates) execute(name string, data TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.templates[name].Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// LoadTemplateDir reads template overrides from the files of a directory.
+// Every file must be named after the template it overrides with a .tmpl extension, e.g. one_chunk_initial_prompt.tmpl.
+func LoadTemplateDir(dir string) (map[string]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list template files: %w", err)
+	}
+
+	overrides := make(map[string]string, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template file: %w", err)
+		}
+		overrides[strings.TrimSuffix(filepath.Base(file), ".tmpl")] = string(content)
+	}
+
+	// Validate the overrides before anything is translated with them
+	if _, err := NewTemplates(overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// PipelineConfig defines the stages a translation runs through. It is given per request
+// or stored as a named translation profile.
+type PipelineConfig struct {
+	SkipReflection bool              `json:"skip_reflection,omitempty"` // return the initial translation as a cheap draft
+	ReviewRounds   int               `json:"review_rounds,omitempty"`   // reflection and improvement rounds, DefaultReviewRounds if zero
+	Models         map[string]string `json:"models,omitempty"`          // model per stage, the model of the request if not set
+	Templates      map[string]string `json:"templates,omitempty"`       // template overrides by template name
+}
+
+// Validate checks the stage names, the number of review rounds and compiles the template overrides
+func (c *PipelineConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.ReviewRounds < 0 || c.ReviewRounds > MaxReviewRounds {
+		return fmt.Errorf("review_rounds must be between 0 and %d", MaxReviewRounds)
+	}
+	for stage := range c.Models {
+		if stage != StageInitial && stage != StageReflection && stage != StageImprovement && stage != StageQualityEstimation {
+			return fmt.Errorf("unknown stage %q", stage)
+		}
+	}
+	_, err := NewTemplates(c.Templates)
+	return err
+}
+
+// Signature identifies the pipeline so that translations of different pipelines are not mixed up.
+// It is empty for the default pipeline.
+func (c *PipelineConfig) Signature() string {
+	if c == nil || (!c.SkipReflection && c.ReviewRounds <= DefaultReviewRounds && len(c.Models) == 0 && len(c.Templates) == 0) {
+		return ""
+	}
+
+	// Map keys are marshalled in sorted order, so equal pipelines have equal signatures
+	normalized := *c
+	if normalized.ReviewRounds == DefaultReviewRounds {
+		normalized.ReviewRounds = 0
+	}
+	return signature(normalized)
+}
+
+// signature returns a short hash of the JSON encoding of v, empty if it cannot be encoded
+func signature(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// TemplateNames returns the names of all templates that can be overridden
+func TemplateNames() []string {
+	names := make([]string, 0, len(defaultTemplates))
+	for name := range defaultTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,154 @@
+package translationflow_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"raggo/src/core/translationflow"
+)
+
+func TestNewTemplates(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		wantErr   string
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:      "valid override",
+			overrides: map[string]string{translationflow.TmplOneChunkInitialPrompt: "Translate to {{.TargetLang}}: {{.SourceText}}"},
+		},
+		{
+			name:      "override with shared blocks",
+			overrides: map[string]string{translationflow.TmplOneChunkInitialPrompt: "Translate to {{.TargetLang}}: {{.SourceText}}\n{{template \"markdown_instructions\"}}{{template \"translation_instructions\" .}}"},
+		},
+		{
+			name:      "unknown block",
+			overrides: map[string]string{translationflow.TmplOneChunkInitialPrompt: "{{template \"summary_instructions\" .}}"},
+			wantErr:   "failed to execute",
+		},
+		{
+			name:      "unknown template",
+			overrides: map[string]string{"one_chunk_summary_prompt": "{{.SourceText}}"},
+			wantErr:   "unknown template",
+		},
+		{
+			name:      "invalid syntax",
+			overrides: map[string]string{translationflow.TmplOneChunkInitialPrompt: "{{.SourceText"},
+			wantErr:   "failed to parse",
+		},
+		{
+			name:      "unknown field",
+			overrides: map[string]string{translationflow.TmplMultiChunkImprovementPrompt: "{{.Draft}}"},
+			wantErr:   "failed to execute",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := translationflow.NewTemplates(tt.overrides)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("NewTemplates() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("NewTemplates() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, translationflow.TmplOneChunkInitialPrompt+".tmpl"), []byte("Translate: {{.SourceText}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	overrides, err := translationflow.LoadTemplateDir(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplateDir() error = %v", err)
+	}
+	if overrides[translationflow.TmplOneChunkInitialPrompt] != "Translate: {{.SourceText}}" {
+		t.Errorf("LoadTemplateDir() = %v", overrides)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "unknown.tmpl"), []byte("{{.SourceText}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := translationflow.LoadTemplateDir(dir); err == nil {
+		t.Error("LoadTemplateDir() accepted an unknown template")
+	}
+}
+
+func TestPipelineConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *translationflow.PipelineConfig
+		wantErr bool
+	}{
+		{name: "nil", config: nil},
+		{name: "draft", config: &translationflow.PipelineConfig{SkipReflection: true}},
+		{name: "stage models", config: &translationflow.PipelineConfig{Models: map[string]string{translationflow.StageReflection: "qwen2.5"}}},
+		{name: "too many rounds", config: &translationflow.PipelineConfig{ReviewRounds: translationflow.MaxReviewRounds + 1}, wantErr: true},
+		{name: "unknown stage", config: &translationflow.PipelineConfig{Models: map[string]string{"summary": "qwen2.5"}}, wantErr: true},
+		{name: "invalid template", config: &translationflow.PipelineConfig{Templates: map[string]string{translationflow.TmplOneChunkInitialSystem: "{{"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPipelineStages(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            []translationflow.Option
+		wantPrompts     int
+		wantReflections int
+	}{
+		{
+			name:        "skip reflection",
+			opts:        []translationflow.Option{translationflow.WithSkipReflection(true)},
+			wantPrompts: 1,
+		},
+		{
+			name:            "default",
+			wantPrompts:     2,
+			wantReflections: 1,
+		},
+		{
+			name:            "extra review round",
+			opts:            []translationflow.Option{translationflow.WithReviewRounds(2)},
+			wantPrompts:     3,
+			wantReflections: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &recordingProvider{tokenLength: 10}
+			reviewer := &recordingProvider{}
+			opts := append(tt.opts, translationflow.WithStageProvider(translationflow.StageReflection, reviewer))
+
+			tf := translationflow.NewTranslationFlow(provider, opts...)
+			if _, err := tf.Translate(context.Background(), "Hello World", "English", "Chinese", "Taiwan"); err != nil {
+				t.Fatalf("Translate() error = %v", err)
+			}
+
+			if len(provider.prompts) != tt.wantPrompts {
+				t.Errorf("provider got %d prompts, want %d", len(provider.prompts), tt.wantPrompts)
+			}
+			if len(reviewer.prompts) != tt.wantReflections {
+				t.Errorf("reflection provider got %d prompts, want %d", len(reviewer.prompts), tt.wantReflections)
+			}
+		})
+	}
+}
@@ -1,5 +1,61 @@
 package translationflow
 
+// PromptBlocksTmpl defines the instructions shared by several prompts. Every template, including overrides,
+// is parsed together with these blocks and can include them with {{template "<name>" .}}.
+const PromptBlocksTmpl = `
+{{- define "markdown_instructions"}}Keep the Markdown formatting of the source text (headings, lists, emphasis, links, tables) and placeholders such as [[CODE_BLOCK_1]] exactly as they are.{{end}}
+{{- define "translation_instructions"}}
+{{- if .Glossary}}
+
+Translate the terms below exactly as given. Terms marked "do not translate" must be kept as in the source:
+<GLOSSARY>
+{{.Glossary}}
+</GLOSSARY>
+{{- end}}
+{{- if .StyleGuide}}
+
+Write the translation following the style guide below, delimited by XML tags <STYLE_GUIDE></STYLE_GUIDE>:
+<STYLE_GUIDE>
+{{.StyleGuide}}
+</STYLE_GUIDE>
+{{- end}}
+{{- end}}
+{{- define "reflection_instructions"}}
+{{- if .Glossary}}
+
+The translation must follow the glossary below, delimited by XML tags <GLOSSARY></GLOSSARY>. Terms marked "do not translate" must be kept as in the source.
+Point out every place where the translation deviates from the glossary.
+<GLOSSARY>
+{{.Glossary}}
+</GLOSSARY>
+{{- end}}
+{{- if .StyleGuide}}
+
+The translation must follow the style guide below, delimited by XML tags <STYLE_GUIDE></STYLE_GUIDE>.
+Point out every place where the translation does not follow it.
+<STYLE_GUIDE>
+{{.StyleGuide}}
+</STYLE_GUIDE>
+{{- end}}
+{{- end}}
+{{- define "improvement_instructions"}}
+{{- if .Glossary}}
+
+The edited translation must follow the glossary below, delimited by XML tags <GLOSSARY></GLOSSARY>. Terms marked "do not translate" must be kept as in the source:
+<GLOSSARY>
+{{.Glossary}}
+</GLOSSARY>
+{{- end}}
+{{- if .StyleGuide}}
+
+The edited translation must follow the style guide below, delimited by XML tags <STYLE_GUIDE></STYLE_GUIDE>:
+<STYLE_GUIDE>
+{{.StyleGuide}}
+</STYLE_GUIDE>
+{{- end}}
+{{- end}}
+`
+
 const (
 	OneChunkInitialTranslationSystemMessageTmpl = `
 You are an expert linguist, specializing in translation from {{.SourceLang}} to {{.TargetLang}}.
@@ -7,7 +63,17 @@ You are an expert linguist, specializing in translation from {{.SourceLang}} to
 	OneChunkInitialTranslationPromptTmpl = `
 This is an {{.SourceLang}} to {{.TargetLang}} translation, please provide the {{.TargetLang}} translation for this text. \
 Do not provide any explanations or text apart from the translation.
+{{template "markdown_instructions"}}
 {{.SourceLang}}: {{.SourceText}}
+{{- if .References}}
+
+Similar texts were translated before as shown below, delimited by XML tags <REFERENCE_TRANSLATIONS></REFERENCE_TRANSLATIONS>.
+Reuse their wording and terminology where the meaning is the same, but translate only the text you are given:
+<REFERENCE_TRANSLATIONS>
+{{.References}}
+</REFERENCE_TRANSLATIONS>
+{{- end}}
+{{- template "translation_instructions" .}}
 
 {{.TargetLang}}:
 `
@@ -34,6 +100,7 @@ When writing suggestions, pay attention to whether there are ways to improve the
 (ii) fluency (by applying {{.TargetLang}} grammar, spelling and punctuation rules, and ensuring there are no unnecessary repetitions),\n\
 (iii) style (by ensuring the translations reflect the style of the source text and take into account any cultural context),\n\
 (iv) terminology (by ensuring terminology use is consistent and reflects the source text domain; and by only ensuring you use equivalent idioms {{.TargetLang}}).\n\
+{{- template "reflection_instructions" .}}
 
 Write a list of specific, helpful and constructive suggestions for improving the translation.
 Each suggestion should address one specific part of the translation.
@@ -57,6 +124,7 @@ When writing suggestions, pay attention to whether there are ways to improve the
 (ii) fluency (by applying {{.TargetLang}} grammar, spelling and punctuation rules, and ensuring there are no unnecessary repetitions),\n\
 (iii) style (by ensuring the translations reflect the style of the source text and take into account any cultural context),\n\
 (iv) terminology (by ensuring terminology use is consistent and reflects the source text domain; and by only ensuring you use equivalent idioms {{.TargetLang}}).\n\
+{{- template "reflection_instructions" .}}
 
 Write a list of specific, helpful and constructive suggestions for improving the translation.
 Each suggestion should address one specific part of the translation.
@@ -91,7 +159,9 @@ Please take into account the expert suggestions when editing the translation. Ed
 (iii) style (by ensuring the translations reflect the style of the source text)
 (iv) terminology (inappropriate for context, inconsistent use), or
 (v) other errors.
+{{- template "improvement_instructions" .}}
 
+{{template "markdown_instructions"}}
 Output only the new translation and nothing else.
 `
 )
@@ -113,7 +183,17 @@ To reiterate, you should translate only this part of the text, shown here again
 <TRANSLATE_THIS>
 {{.ChunkToTranslate}}
 </TRANSLATE_THIS>
+{{- if .References}}
+
+Similar texts were translated before as shown below, delimited by XML tags <REFERENCE_TRANSLATIONS></REFERENCE_TRANSLATIONS>.
+Reuse their wording and terminology where the meaning is the same, but translate only the text you are given:
+<REFERENCE_TRANSLATIONS>
+{{.References}}
+</REFERENCE_TRANSLATIONS>
+{{- end}}
+{{- template "translation_instructions" .}}
 
+{{template "markdown_instructions"}}
 Output only the translation of the portion you are asked to translate, and nothing else.`
 
 	MultiChunkReflectionSystemMessageTmpl = "You are an expert linguist specializing in translation from {{.SourceLang}} to {{.TargetLang}}. You will be provided with a source text and its translation and your goal is to improve the translation."
@@ -144,6 +224,7 @@ When writing suggestions, pay attention to whether there are ways to improve the
 (ii) fluency (by applying {{.TargetLang}} grammar, spelling and punctuation rules, and ensuring there are no unnecessary repetitions),
 (iii) style (by ensuring the translations reflect the style of the source text and take into account any cultural context),
 (iv) terminology (by ensuring terminology use is consistent and reflects the source text domain; and by only ensuring you use equivalent idioms {{.TargetLang}}).
+{{- template "reflection_instructions" .}}
 
 Write a list of specific, helpful and constructive suggestions for improving the translation.
 Each suggestion should address one specific part of the translation.
@@ -174,6 +255,7 @@ When writing suggestions, pay attention to whether there are ways to improve the
 (ii) fluency (by applying {{.TargetLang}} grammar, spelling and punctuation rules, and ensuring there are no unnecessary repetitions),
 (iii) style (by ensuring the translations reflect the style of the source text and take into account any cultural context),
 (iv) terminology (by ensuring terminology use is consistent and reflects the source text domain; and by only ensuring you use equivalent idioms {{.TargetLang}}).
+{{- template "reflection_instructions" .}}
 
 Write a list of specific, helpful and constructive suggestions for improving the translation.
 Each suggestion should address one specific part of the translation.
@@ -215,6 +297,50 @@ to whether there are ways to improve the translation's
 (iii) style (by ensuring the translations reflect the style of the source text)
 (iv) terminology (inappropriate for context, inconsistent use), or
 (v) other errors.
+{{- template "improvement_instructions" .}}
 
+{{template "markdown_instructions"}}
 Output only the new translation of the indicated part and nothing else.`
 )
+
+const (
+	QualityEstimationSystemMessageTmpl = `
+You are an expert reviewer of translations from {{.SourceLang}} to {{.TargetLang}}. You rate translations strictly and consistently.
+`
+	QualityEstimationPromptTmpl = `
+Rate the translation of the source text below, delimited by XML tags <SOURCE_TEXT></SOURCE_TEXT> and <TRANSLATION></TRANSLATION>:
+
+<SOURCE_TEXT>
+{{.SourceText}}
+</SOURCE_TEXT>
+
+<TRANSLATION>
+{{.Translation1}}
+</TRANSLATION>
+{{- if .Glossary}}
+
+The terms below must be translated exactly as given. Terms marked "do not translate" must be kept as in the source:
+<GLOSSARY>
+{{.Glossary}}
+</GLOSSARY>
+{{- end}}
+
+Score each criterion from 1 (unusable) to 5 (flawless):
+- accuracy: the translation conveys the full meaning of the source text, without additions, omissions or mistranslations
+- fluency: the translation reads naturally and follows the grammar, spelling and punctuation of {{.TargetLang}}{{if .Country}} as used in {{.Country}}{{end}}
+- terminology: terms are translated correctly and consistently{{if .Glossary}} and follow the glossary{{end}}
+
+Answer with a single JSON object and nothing else, for example:
+{"accuracy": 4, "fluency": 5, "terminology": 3, "comment": "One sentence on the most important problem."}
+`
+	BackTranslationSystemMessageTmpl = `
+You are an expert linguist, specializing in translation from {{.SourceLang}} to {{.TargetLang}}.
+`
+	BackTranslationPromptTmpl = `
+Translate the text below from {{.SourceLang}} to {{.TargetLang}} as literally as possible, without correcting or improving it. \
+Do not provide any explanations or text apart from the translation.
+{{.SourceLang}}: {{.SourceText}}
+
+{{.TargetLang}}:
+`
+)
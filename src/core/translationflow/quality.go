@@ -0,0 +1,160 @@
+package translationflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"raggo/src/infrastructure/log"
+)
+
+const (
+	DefaultQualityThreshold = 0.6
+	// judgeWeight is the share of the judge rating in the quality score when a back-translation similarity is available
+	judgeWeight = 0.7
+)
+
+// Embedder turns a text into an embedding vector
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// QualityEstimate rates a translation without a reference translation
+type QualityEstimate struct {
+	// Ratings of the judge from 1 to 5
+	Accuracy    int    `json:"accuracy"`
+	Fluency     int    `json:"fluency"`
+	Terminology int    `json:"terminology"`
+	Comment     string `json:"comment,omitempty"`
+	// BackTranslationSimilarity is the cosine similarity of the source and the translation translated back,
+	// nil without an embedder
+	BackTranslationSimilarity *float64 `json:"back_translation_similarity,omitempty"`
+	// Score combines the ratings and the back-translation similarity, between 0 and 1
+	Score float64 `json:"score"`
+}
+
+// WithEmbedder compares the source with a back-translation during quality estimation
+func WithEmbedder(embedder Embedder) Option {
+	return func(tf *TranslationFlow) {
+		tf.embedder = embedder
+	}
+}
+
+// EstimateQuality scores a finished translation. A judge rates accuracy, fluency and terminology against a rubric,
+// and with an embedder the translation is translated back and compared with the source.
+// If the back-translation fails, the score rests on the judge alone.
+func (tf *TranslationFlow) EstimateQuality(ctx context.Context, source, translation, sourceLanguage, targetLanguage, country string) (*QualityEstimate, error) {
+	data := TemplateData{
+		SourceLang:   sourceLanguage,
+		TargetLang:   targetLanguage,
+		Country:      country,
+		SourceText:   source,
+		Translation1: translation,
+		Glossary:     tf.glossary.Relevant(source).String(),
+	}
+
+	system, prompt, err := tf.executeTemplates(TmplQualityEstimationSystem, TmplQualityEstimationPrompt, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare quality estimation templates: %w", err)
+	}
+	log.Debug("quality estimation", "system", system, "prompt", prompt)
+	answer, err := tf.reasoning(ctx, StageQualityEstimation, system, prompt)
+	if err != nil {
+		return nil, err
+	}
+	estimate, err := ParseQualityEstimate(answer)
+	if err != nil {
+		return nil, err
+	}
+
+	if tf.embedder != nil {
+		similarity, err := tf.backTranslationSimilarity(ctx, source, translation, sourceLanguage, targetLanguage)
+		if err != nil {
+			log.Info("Failed to compare back-translation, scoring with the judge alone", "error", err.Error())
+		} else {
+			estimate.BackTranslationSimilarity = &similarity
+		}
+	}
+
+	estimate.Score = estimate.combinedScore()
+	return estimate, nil
+}
+
+// backTranslationSimilarity translates the translation back into the source language
+// and returns the cosine similarity of its embedding and the embedding of the source
+func (tf *TranslationFlow) backTranslationSimilarity(ctx context.Context, source, translation, sourceLanguage, targetLanguage string) (float64, error) {
+	data := TemplateData{
+		SourceLang: targetLanguage,
+		TargetLang: sourceLanguage,
+		SourceText: translation,
+	}
+	system, prompt, err := tf.executeTemplates(TmplBackTranslationSystem, TmplBackTranslationPrompt, data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare back-translation templates: %w", err)
+	}
+	backTranslation, err := tf.reasoning(ctx, StageQualityEstimation, system, prompt)
+	if err != nil {
+		return 0, err
+	}
+	log.Debug("back-translation result", "translation", backTranslation)
+
+	sourceEmbedding, err := tf.embedder.Embed(ctx, source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to embed source text: %w", err)
+	}
+	backEmbedding, err := tf.embedder.Embed(ctx, backTranslation)
+	if err != nil {
+		return 0, fmt.Errorf("failed to embed back-translation: %w", err)
+	}
+	return CosineSimilarity(sourceEmbedding, backEmbedding), nil
+}
+
+// ParseQualityEstimate reads the JSON ratings of the judge, ignoring any text around the JSON object
+func ParseQualityEstimate(answer string) (*QualityEstimate, error) {
+	start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("quality estimation answer has no JSON object: %q", answer)
+	}
+
+	var estimate QualityEstimate
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &estimate); err != nil {
+		return nil, fmt.Errorf("failed to parse quality estimation answer: %w", err)
+	}
+	for name, rating := range map[string]int{"accuracy": estimate.Accuracy, "fluency": estimate.Fluency, "terminology": estimate.Terminology} {
+		if rating < 1 || rating > 5 {
+			return nil, fmt.Errorf("quality estimation rating %s must be between 1 and 5, got %d", name, rating)
+		}
+	}
+	estimate.Score = estimate.combinedScore()
+	return &estimate, nil
+}
+
+// combinedScore maps the mean rating onto 0 to 1 and blends in the back-translation similarity if there is one
+func (e *QualityEstimate) combinedScore() float64 {
+	judge := (float64(e.Accuracy+e.Fluency+e.Terminology)/3 - 1) / 4
+	if e.BackTranslationSimilarity == nil {
+		return judge
+	}
+	similarity := math.Max(0, *e.BackTranslationSimilarity)
+	return judgeWeight*judge + (1-judgeWeight)*similarity
+}
+
+// CosineSimilarity returns the cosine of the angle between two vectors, 0 if either is empty or they differ in length
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
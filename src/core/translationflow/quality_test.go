@@ -0,0 +1,139 @@
+package translationflow_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"raggo/src/core/translationflow"
+)
+
+func TestParseQualityEstimate(t *testing.T) {
+	tests := []struct {
+		name      string
+		answer    string
+		wantScore float64
+		wantErr   bool
+	}{
+		{name: "flawless", answer: `{"accuracy": 5, "fluency": 5, "terminology": 5}`, wantScore: 1},
+		{name: "unusable", answer: `{"accuracy": 1, "fluency": 1, "terminology": 1, "comment": "wrong language"}`, wantScore: 0},
+		{name: "text around the JSON", answer: "Here is my rating:\n{\"accuracy\": 3, \"fluency\": 3, \"terminology\": 3}\nThanks", wantScore: 0.5},
+		{name: "rating out of range", answer: `{"accuracy": 6, "fluency": 5, "terminology": 5}`, wantErr: true},
+		{name: "missing rating", answer: `{"accuracy": 5, "fluency": 5}`, wantErr: true},
+		{name: "no JSON", answer: "The translation is good.", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := translationflow.ParseQualityEstimate(tt.answer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQualityEstimate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && math.Abs(got.Score-tt.wantScore) > 1e-9 {
+				t.Errorf("ParseQualityEstimate() score = %v, want %v", got.Score, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float64
+	}{
+		{name: "identical", a: []float32{1, 2, 3}, b: []float32{1, 2, 3}, want: 1},
+		{name: "orthogonal", a: []float32{1, 0}, b: []float32{0, 1}, want: 0},
+		{name: "opposite", a: []float32{1, 1}, b: []float32{-1, -1}, want: -1},
+		{name: "different length", a: []float32{1, 2}, b: []float32{1, 2, 3}, want: 0},
+		{name: "zero vector", a: []float32{0, 0}, b: []float32{1, 2}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := translationflow.CosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("CosineSimilarity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// judgeProvider rates every translation and translates back to a fixed text
+type judgeProvider struct {
+	rating          string
+	backTranslation string
+}
+
+func (p *judgeProvider) TextSplit(ctx context.Context, text string, chunkSize, chunkOverLap int) ([]string, error) {
+	return []string{text}, nil
+}
+
+func (p *judgeProvider) Reasoning(ctx context.Context, system string, prompt string) (string, error) {
+	if strings.Contains(prompt, "as literally as possible") {
+		return p.backTranslation, nil
+	}
+	return p.rating, nil
+}
+
+func (p *judgeProvider) TokenLength(ctx context.Context, text string) (int, error) {
+	return 10, nil
+}
+
+// failingEmbedder cannot reach its embedding model
+type failingEmbedder struct{}
+
+func (failingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return nil, errors.New("embedding model not found")
+}
+
+// wordEmbedder embeds a text as the counts of a few known words
+type wordEmbedder struct{}
+
+func (wordEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vector := make([]float32, 3)
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		switch strings.Trim(word, ".") {
+		case "press":
+			vector[0]++
+		case "button":
+			vector[1]++
+		case "cat":
+			vector[2]++
+		}
+	}
+	return vector, nil
+}
+
+func TestEstimateQuality(t *testing.T) {
+	tests := []struct {
+		name            string
+		embedder        translationflow.Embedder
+		backTranslation string
+		wantScore       float64
+		wantSimilarity  bool
+	}{
+		{name: "judge only", wantScore: 0.75},
+		{name: "faithful back-translation", embedder: wordEmbedder{}, backTranslation: "Press the button.", wantScore: 0.7*0.75 + 0.3, wantSimilarity: true},
+		{name: "unrelated back-translation", embedder: wordEmbedder{}, backTranslation: "A cat.", wantScore: 0.7 * 0.75, wantSimilarity: true},
+		{name: "failed embedding", embedder: failingEmbedder{}, backTranslation: "Press the button.", wantScore: 0.75},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &judgeProvider{rating: `{"accuracy": 4, "fluency": 4, "terminology": 4}`, backTranslation: tt.backTranslation}
+			tf := translationflow.NewTranslationFlow(provider, translationflow.WithEmbedder(tt.embedder))
+			got, err := tf.EstimateQuality(context.Background(), "Press the button.", "Drücken Sie die Taste.", "English", "German", "")
+			if err != nil {
+				t.Fatalf("EstimateQuality() error = %v", err)
+			}
+			if math.Abs(got.Score-tt.wantScore) > 1e-6 {
+				t.Errorf("EstimateQuality() score = %v, want %v", got.Score, tt.wantScore)
+			}
+			if (got.BackTranslationSimilarity != nil) != tt.wantSimilarity {
+				t.Errorf("EstimateQuality() back-translation similarity = %v, want set %v", got.BackTranslationSimilarity, tt.wantSimilarity)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package translationflow
+
+import (
+	"context"
+	"sync"
+)
+
+// StreamingProvider is an LLMProvider that passes on its answer while it is generated
+type StreamingProvider interface {
+	LLMProvider
+	ReasoningStream(ctx context.Context, system string, prompt string, onToken func(string)) (string, error)
+}
+
+// Event reports the progress of a stage of the translation
+type Event struct {
+	Stage string // StageInitial, StageReflection, StageImprovement or StageQualityEstimation
+	Part  int    // index of the part of a text that exceeds the token limit, 0 otherwise
+	Delta string // piece of the answer while the stage runs
+	Text  string // complete answer of the stage once it is done
+	Done  bool
+}
+
+// WithEventHandler reports the answer of every stage to handler, piece by piece if the provider of the stage streams.
+// Calls are serialized, so the handler need not be safe for concurrent use.
+func WithEventHandler(handler func(Event)) Option {
+	var mu sync.Mutex
+	return func(tf *TranslationFlow) {
+		tf.onEvent = func(event Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			handler(event)
+		}
+	}
+}
+
+// generate asks the provider for its answer, streaming it to the event handler if both are present
+func (tf *TranslationFlow) generate(ctx context.Context, provider LLMProvider, stage string, part int, system, prompt string) (string, error) {
+	if tf.onEvent == nil {
+		return provider.Reasoning(ctx, system, prompt)
+	}
+
+	var answer string
+	var err error
+	if streaming, ok := provider.(StreamingProvider); ok {
+		answer, err = streaming.ReasoningStream(ctx, system, prompt, func(token string) {
+			tf.onEvent(Event{Stage: stage, Part: part, Delta: token})
+		})
+	} else {
+		answer, err = provider.Reasoning(ctx, system, prompt)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	tf.onEvent(Event{Stage: stage, Part: part, Text: answer, Done: true})
+	return answer, nil
+}
@@ -0,0 +1,83 @@
+package translationflow_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"raggo/src/core/translationflow"
+)
+
+// streamingProvider answers every stage with a fixed text, streamed word by word
+type streamingProvider struct{}
+
+func (p *streamingProvider) TextSplit(ctx context.Context, text string, chunkSize, chunkOverLap int) ([]string, error) {
+	return []string{text}, nil
+}
+
+func (p *streamingProvider) Reasoning(ctx context.Context, system string, prompt string) (string, error) {
+	return p.ReasoningStream(ctx, system, prompt, func(string) {})
+}
+
+func (p *streamingProvider) ReasoningStream(ctx context.Context, system string, prompt string, onToken func(string)) (string, error) {
+	answer := "Hallo Welt"
+	for i, word := range strings.Fields(answer) {
+		if i > 0 {
+			word = " " + word
+		}
+		onToken(word)
+	}
+	return answer, nil
+}
+
+func (p *streamingProvider) TokenLength(ctx context.Context, text string) (int, error) {
+	return 10, nil
+}
+
+func TestEventHandler(t *testing.T) {
+	var events []translationflow.Event
+	tf := translationflow.NewTranslationFlow(&streamingProvider{},
+		translationflow.WithSkipReflection(true),
+		translationflow.WithEventHandler(func(event translationflow.Event) {
+			events = append(events, event)
+		}),
+	)
+
+	translation, err := tf.Translate(context.Background(), "Hello world", "English", "German", "")
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+	if translation != "Hallo Welt" {
+		t.Errorf("Translate() = %q, want %q", translation, "Hallo Welt")
+	}
+
+	want := []translationflow.Event{
+		{Stage: translationflow.StageInitial, Delta: "Hallo"},
+		{Stage: translationflow.StageInitial, Delta: " Welt"},
+		{Stage: translationflow.StageInitial, Text: "Hallo Welt", Done: true},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+}
+
+func TestEventHandlerReportsEveryStage(t *testing.T) {
+	var stages []string
+	tf := translationflow.NewTranslationFlow(&streamingProvider{},
+		translationflow.WithEventHandler(func(event translationflow.Event) {
+			if event.Done {
+				stages = append(stages, event.Stage)
+			}
+		}),
+	)
+
+	if _, err := tf.Translate(context.Background(), "Hello world", "English", "German", ""); err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+
+	want := []string{translationflow.StageInitial, translationflow.StageReflection, translationflow.StageImprovement}
+	if !reflect.DeepEqual(stages, want) {
+		t.Errorf("completed stages = %v, want %v", stages, want)
+	}
+}
@@ -0,0 +1,51 @@
+package translationflow
+
+import "strings"
+
+const (
+	FormalityFormal   = "formal"
+	FormalityInformal = "informal"
+)
+
+// StyleGuide describes how translations into a target locale should read
+type StyleGuide struct {
+	Formality    string // FormalityFormal, FormalityInformal or empty to follow the source text
+	Tone         string // free-form description, e.g. "friendly and concise"
+	Instructions string // any further rules, e.g. "use the metric system"
+}
+
+// IsEmpty reports whether the style guide has no rules
+func (s *StyleGuide) IsEmpty() bool {
+	return s == nil || (s.Formality == "" && s.Tone == "" && s.Instructions == "")
+}
+
+// Signature identifies the rules of the style guide so that translations following different style guides are
+// not mixed up. It is empty for a style guide without rules.
+func (s *StyleGuide) Signature() string {
+	if s.IsEmpty() {
+		return ""
+	}
+	return signature(s)
+}
+
+// String renders the style guide for the prompt templates, one rule per line
+func (s *StyleGuide) String() string {
+	if s.IsEmpty() {
+		return ""
+	}
+
+	var lines []string
+	switch s.Formality {
+	case FormalityFormal:
+		lines = append(lines, "Formality: formal. Address the reader formally, using the polite forms of the target language.")
+	case FormalityInformal:
+		lines = append(lines, "Formality: informal. Address the reader casually, using the familiar forms of the target language.")
+	}
+	if s.Tone != "" {
+		lines = append(lines, "Tone: "+s.Tone)
+	}
+	if s.Instructions != "" {
+		lines = append(lines, "Instructions: "+s.Instructions)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -1,13 +1,14 @@
 package translationflow
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"strings"
 	"text/template"
 	"time"
 
+	"golang.org/x/sync/errgroup"
+
 	"raggo/src/infrastructure/log"
 )
 
@@ -34,12 +35,32 @@ type TemplateData struct {
 	TranslationChunk string
 	Reflection       string
 	ReflectionChunk  string
+	Glossary         string // rendered glossary terms relevant to the text
+	References       string // rendered translations of similar texts from the translation memory
+	StyleGuide       string // rendered style guide of the target locale
+}
+
+// Reference is an earlier translation of a text similar to the one being translated
+type Reference struct {
+	Source      string
+	Translation string
+	Similarity  float64
 }
 
 type TranslationFlow struct {
 	llmProvider      LLMProvider
 	maxTokenPerChunk int
 	reasoningTimeout time.Duration
+	glossary         *Glossary
+	styleGuide       *StyleGuide
+	templates        *Templates
+	stageProviders   map[string]LLMProvider
+	skipReflection   bool
+	reviewRounds     int
+	limiter          *Limiter
+	concurrency      int
+	embedder         Embedder
+	onEvent          func(Event)
 }
 
 func NewTranslationFlow(llmProvider LLMProvider, opts ...Option) *TranslationFlow {
@@ -47,6 +68,10 @@ func NewTranslationFlow(llmProvider LLMProvider, opts ...Option) *TranslationFlo
 		llmProvider:      llmProvider,
 		maxTokenPerChunk: DefaultMaxTokenPerChunk,
 		reasoningTimeout: DefaultReasoningTimeout,
+		templates:        DefaultTemplates(),
+		stageProviders:   make(map[string]LLMProvider),
+		reviewRounds:     DefaultReviewRounds,
+		concurrency:      1,
 	}
 
 	for _, opt := range opts {
@@ -70,25 +95,113 @@ func WithReasoningTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithGlossary makes every translation step follow the terminology of the glossary
+func WithGlossary(glossary *Glossary) Option {
+	return func(tf *TranslationFlow) {
+		tf.glossary = glossary
+	}
+}
+
+// WithStyleGuide sets the formality, tone and further rules of the target locale
+func WithStyleGuide(styleGuide *StyleGuide) Option {
+	return func(tf *TranslationFlow) {
+		tf.styleGuide = styleGuide
+	}
+}
+
+// WithTemplates replaces the compiled-in templates, see NewTemplates
+func WithTemplates(templates *Templates) Option {
+	return func(tf *TranslationFlow) {
+		tf.templates = templates
+	}
+}
+
+// WithStageProvider runs a stage on its own provider instead of the provider of the flow
+func WithStageProvider(stage string, provider LLMProvider) Option {
+	return func(tf *TranslationFlow) {
+		tf.stageProviders[stage] = provider
+	}
+}
+
+// WithSkipReflection returns the initial translation without reflection and improvement
+func WithSkipReflection(skip bool) Option {
+	return func(tf *TranslationFlow) {
+		tf.skipReflection = skip
+	}
+}
+
+// WithReviewRounds sets how often the translation is reflected on and improved
+func WithReviewRounds(rounds int) Option {
+	return func(tf *TranslationFlow) {
+		tf.reviewRounds = rounds
+	}
+}
+
+// WithLimiter bounds the generation requests of the flow, usually with the limiter shared by all flows of the provider
+func WithLimiter(limiter *Limiter) Option {
+	return func(tf *TranslationFlow) {
+		tf.limiter = limiter
+	}
+}
+
+// WithConcurrency sets how many parts of a text that exceeds the token limit are translated at the same time
+func WithConcurrency(concurrency int) Option {
+	return func(tf *TranslationFlow) {
+		tf.concurrency = max(concurrency, 1)
+	}
+}
+
 func (tf *TranslationFlow) Translate(ctx context.Context, text string, sourceLanguage, targetLanguage, country string) (string, error) {
-	tokenLength, err := tf.llmProvider.TokenLength(ctx, text)
+	return tf.TranslateWithReferences(ctx, text, sourceLanguage, targetLanguage, country, nil)
+}
+
+// TranslateWithReferences translates the text like Translate and shows the references to the model as examples
+// of how similar texts were translated before. Fenced code blocks are kept out of the translation.
+func (tf *TranslationFlow) TranslateWithReferences(ctx context.Context, text string, sourceLanguage, targetLanguage, country string, references []Reference) (string, error) {
+	masked, codeBlocks := protectCodeBlocks(text)
+	if len(codeBlocks) > 0 && onlyPlaceholders(masked) {
+		// Nothing but code, which stays as it is
+		return text, nil
+	}
+
+	tokenLength, err := tf.llmProvider.TokenLength(ctx, masked)
 	if err != nil {
 		return "", fmt.Errorf("failed to get token length: %w", err)
 	}
 
+	var translation string
+	renderedReferences := renderReferences(references)
 	if tokenLength < tf.maxTokenPerChunk {
-		return tf.handleSingleChunkTranslation(ctx, text, sourceLanguage, targetLanguage, country)
+		translation, err = tf.handleSingleChunkTranslation(ctx, masked, sourceLanguage, targetLanguage, country, renderedReferences)
+	} else {
+		translation, err = tf.handleMultiChunkTranslation(ctx, masked, sourceLanguage, targetLanguage, country, tokenLength, renderedReferences)
 	}
-	return tf.handleMultiChunkTranslation(ctx, text, sourceLanguage, targetLanguage, country, tokenLength)
+	if err != nil {
+		return "", err
+	}
+	return restoreCodeBlocks(translation, codeBlocks)
+}
+
+// renderReferences formats the references for the prompt templates
+func renderReferences(references []Reference) string {
+	var sb strings.Builder
+	for _, reference := range references {
+		sb.WriteString("<SOURCE>" + reference.Source + "</SOURCE>\n")
+		sb.WriteString("<TRANSLATION>" + reference.Translation + "</TRANSLATION>\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
 }
 
 // handleSingleChunkTranslation processes a text that fits within token limits
-func (tf *TranslationFlow) handleSingleChunkTranslation(ctx context.Context, text, sourceLanguage, targetLanguage, country string) (string, error) {
+func (tf *TranslationFlow) handleSingleChunkTranslation(ctx context.Context, text, sourceLanguage, targetLanguage, country, references string) (string, error) {
 	data := TemplateData{
 		SourceLang: sourceLanguage,
 		TargetLang: targetLanguage,
 		Country:    country,
 		SourceText: text,
+		Glossary:   tf.glossary.Relevant(text).String(),
+		References: references,
+		StyleGuide: tf.styleGuide.String(),
 	}
 
 	// Step 1: Get initial translation
@@ -97,43 +210,66 @@ func (tf *TranslationFlow) handleSingleChunkTranslation(ctx context.Context, tex
 		return "", err
 	}
 	data.Translation1 = translation1
+	if tf.skipReflection {
+		return translation1, nil
+	}
 
-	// Step 2: Get reflection
-	reflection, err := tf.getTranslationReflection(ctx, data)
-	if err != nil {
-		return "", err
+	for round := 0; round < tf.reviewRounds; round++ {
+		// Step 2: Get reflection
+		reflection, err := tf.getTranslationReflection(ctx, data)
+		if err != nil {
+			return "", err
+		}
+		data.Reflection = reflection
+
+		// Step 3: Get improved translation, which the next round reviews again
+		improvedTranslation, err := tf.getImprovedTranslation(ctx, data)
+		if err != nil {
+			return "", err
+		}
+		data.Translation1 = improvedTranslation
 	}
-	data.Reflection = reflection
 
-	// Step 3: Get improved translation
-	return tf.getImprovedTranslation(ctx, data)
+	return data.Translation1, nil
 }
 
 // handleMultiChunkTranslation processes a text that needs to be split into chunks
-func (tf *TranslationFlow) handleMultiChunkTranslation(ctx context.Context, text, sourceLanguage, targetLanguage, country string, tokenLength int) (string, error) {
+func (tf *TranslationFlow) handleMultiChunkTranslation(ctx context.Context, text, sourceLanguage, targetLanguage, country string, tokenLength int, references string) (string, error) {
 	chunkSize := CalculateChunkSize(tokenLength, tf.maxTokenPerChunk)
 	chunks, err := tf.llmProvider.TextSplit(ctx, text, chunkSize, 0)
 	if err != nil {
 		return "", fmt.Errorf("failed to split text: %w", err)
 	}
 
-	var translatedChunks []string
+	// Translate the parts concurrently, each into its own slot to keep their order
+	translatedChunks := make([]string, len(chunks))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(tf.concurrency)
 	for i, chunk := range chunks {
-		taggedText := tf.createTaggedText(text, chunk)
-
-		data := TemplateData{
-			SourceLang:       sourceLanguage,
-			TargetLang:       targetLanguage,
-			Country:          country,
-			TaggedText:       taggedText,
-			ChunkToTranslate: chunk,
-		}
-
-		translatedChunk, err := tf.processChunk(ctx, data, i)
-		if err != nil {
-			return "", err
-		}
-		translatedChunks = append(translatedChunks, translatedChunk)
+		g.Go(func() error {
+			taggedText := tf.createTaggedText(text, chunk)
+
+			data := TemplateData{
+				SourceLang:       sourceLanguage,
+				TargetLang:       targetLanguage,
+				Country:          country,
+				TaggedText:       taggedText,
+				ChunkToTranslate: chunk,
+				Glossary:         tf.glossary.Relevant(chunk).String(),
+				References:       references,
+				StyleGuide:       tf.styleGuide.String(),
+			}
+
+			translatedChunk, err := tf.processChunk(gctx, data, i)
+			if err != nil {
+				return err
+			}
+			translatedChunks[i] = translatedChunk
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return "", err
 	}
 
 	return strings.Join(translatedChunks, " "), nil
@@ -147,40 +283,49 @@ func (tf *TranslationFlow) processChunk(ctx context.Context, data TemplateData,
 		return "", err
 	}
 	data.TranslationChunk = translationChunk
+	if tf.skipReflection {
+		return translationChunk, nil
+	}
 
-	// Step 2: Get reflection for chunk
-	reflectionChunk, err := tf.getMultiChunkReflection(ctx, data, chunkIndex)
-	if err != nil {
-		return "", err
+	for round := 0; round < tf.reviewRounds; round++ {
+		// Step 2: Get reflection for chunk
+		reflectionChunk, err := tf.getMultiChunkReflection(ctx, data, chunkIndex)
+		if err != nil {
+			return "", err
+		}
+		data.ReflectionChunk = reflectionChunk
+
+		// Step 3: Get improved translation for chunk, which the next round reviews again
+		improvedChunk, err := tf.getMultiChunkImprovedTranslation(ctx, data, chunkIndex)
+		if err != nil {
+			return "", err
+		}
+		data.TranslationChunk = improvedChunk
 	}
-	data.ReflectionChunk = reflectionChunk
 
-	// Step 3: Get improved translation for chunk
-	return tf.getMultiChunkImprovedTranslation(ctx, data, chunkIndex)
+	return data.TranslationChunk, nil
 }
 
 // Template execution helpers
-func (tf *TranslationFlow) executeTemplates(systemTmpl, promptTmpl string, data TemplateData) (string, string, error) {
-	var systemBuf, promptBuf bytes.Buffer
-
-	sysT := template.Must(template.New("system").Parse(systemTmpl))
-	if err := sysT.Execute(&systemBuf, data); err != nil {
+func (tf *TranslationFlow) executeTemplates(systemName, promptName string, data TemplateData) (string, string, error) {
+	system, err := tf.templates.execute(systemName, data)
+	if err != nil {
 		return "", "", fmt.Errorf("failed to execute system template: %w", err)
 	}
 
-	prmptT := template.Must(template.New("prompt").Parse(promptTmpl))
-	if err := prmptT.Execute(&promptBuf, data); err != nil {
+	prompt, err := tf.templates.execute(promptName, data)
+	if err != nil {
 		return "", "", fmt.Errorf("failed to execute prompt template: %w", err)
 	}
 
-	return systemBuf.String(), promptBuf.String(), nil
+	return system, prompt, nil
 }
 
 // Single chunk translation helpers
 func (tf *TranslationFlow) getInitialTranslation(ctx context.Context, data TemplateData) (string, error) {
 	system, prompt, err := tf.executeTemplates(
-		OneChunkInitialTranslationSystemMessageTmpl,
-		OneChunkInitialTranslationPromptTmpl,
+		TmplOneChunkInitialSystem,
+		TmplOneChunkInitialPrompt,
 		data,
 	)
 	if err != nil {
@@ -188,20 +333,39 @@ func (tf *TranslationFlow) getInitialTranslation(ctx context.Context, data Templ
 	}
 
 	log.Debug("initial translation", "system", system, "prompt", prompt)
-	translation, err2 := tf.reasoning(ctx, err, system, prompt)
-	if err2 != nil {
-		return "", err2
+	translation, err := tf.reasoning(ctx, StageInitial, system, prompt)
+	if err != nil {
+		return "", err
 	}
 	log.Debug("initial translation result", "translation", translation)
 	return translation, nil
 }
 
-func (tf *TranslationFlow) reasoning(ctx context.Context, err error, system string, prompt string) (string, error) {
+// reasoning sends the prompt to the provider of the stage
+func (tf *TranslationFlow) reasoning(ctx context.Context, stage string, system string, prompt string) (string, error) {
+	return tf.reasoningPart(ctx, stage, 0, system, prompt)
+}
+
+// reasoningPart sends the prompt for a part of a text that exceeds the token limit to the provider of the stage
+func (tf *TranslationFlow) reasoningPart(ctx context.Context, stage string, part int, system string, prompt string) (string, error) {
+	provider, ok := tf.stageProviders[stage]
+	if !ok {
+		provider = tf.llmProvider
+	}
+
+	// Wait for the limiter before the timeout starts, so queued requests do not time out
+	if tf.limiter != nil {
+		if err := tf.limiter.Acquire(ctx); err != nil {
+			return "", fmt.Errorf("failed to wait for provider: %w", err)
+		}
+		defer tf.limiter.Release()
+	}
+
 	timeoutCtx, cancel := context.WithTimeout(ctx, tf.reasoningTimeout)
 	defer cancel()
-	translation, err := tf.llmProvider.Reasoning(timeoutCtx, system, prompt)
+	translation, err := tf.generate(timeoutCtx, provider, stage, part, system, prompt)
 	if err != nil {
-		log.Error(err, "failed to llm reason")
+		log.Error(err, "failed to llm reason", "stage", stage)
 		return "", fmt.Errorf("failed to get reasoning: %w", err)
 	}
 	return translation, nil
@@ -209,8 +373,8 @@ func (tf *TranslationFlow) reasoning(ctx context.Context, err error, system stri
 
 func (tf *TranslationFlow) getTranslationReflection(ctx context.Context, data TemplateData) (string, error) {
 	system, prompt, err := tf.executeTemplates(
-		OneChunkReflectOnTranslationSystemMessageTmpl,
-		OneChunkReflectOnTranslationPromptTmpl,
+		TmplOneChunkReflectionSystem,
+		reflectionPromptTmpl(data, TmplOneChunkReflectionCountryPrompt, TmplOneChunkReflectionPrompt),
 		data,
 	)
 	if err != nil {
@@ -218,18 +382,27 @@ func (tf *TranslationFlow) getTranslationReflection(ctx context.Context, data Te
 	}
 
 	log.Debug("reflection", "system", system, "prompt", prompt)
-	reflection, err2 := tf.reasoning(ctx, err, system, prompt)
-	if err2 != nil {
-		return "", err2
+	reflection, err := tf.reasoning(ctx, StageReflection, system, prompt)
+	if err != nil {
+		return "", err
 	}
 	log.Debug("reflection result", "reflection", reflection)
 	return reflection, nil
 }
 
+// reflectionPromptTmpl returns the country template when a country is given, so the reflection
+// critiques the translation against the language as it is spoken there
+func reflectionPromptTmpl(data TemplateData, withCountryTmpl, defaultTmpl string) string {
+	if data.Country != "" {
+		return withCountryTmpl
+	}
+	return defaultTmpl
+}
+
 func (tf *TranslationFlow) getImprovedTranslation(ctx context.Context, data TemplateData) (string, error) {
 	system, prompt, err := tf.executeTemplates(
-		OneChunkImprovementTranslationSystemMessageTmpl,
-		OneChunkImprovementTranslationPromptTmpl,
+		TmplOneChunkImprovementSystem,
+		TmplOneChunkImprovementPrompt,
 		data,
 	)
 	if err != nil {
@@ -237,9 +410,9 @@ func (tf *TranslationFlow) getImprovedTranslation(ctx context.Context, data Temp
 	}
 
 	log.Debug("improvement", "system", system, "prompt", prompt)
-	improvedTranslation, err2 := tf.reasoning(ctx, err, system, prompt)
-	if err2 != nil {
-		return "", err2
+	improvedTranslation, err := tf.reasoning(ctx, StageImprovement, system, prompt)
+	if err != nil {
+		return "", err
 	}
 	log.Debug("improvement result", "translation", improvedTranslation)
 	return improvedTranslation, nil
@@ -257,8 +430,8 @@ func (tf *TranslationFlow) createTaggedText(fullText, currentChunk string) strin
 
 func (tf *TranslationFlow) getMultiChunkInitialTranslation(ctx context.Context, data TemplateData, chunkIndex int) (string, error) {
 	system, prompt, err := tf.executeTemplates(
-		MultiChunkTranslationSystemMessageTmpl,
-		MultiChunkTranslationPromptTmpl,
+		TmplMultiChunkInitialSystem,
+		TmplMultiChunkInitialPrompt,
 		data,
 	)
 	if err != nil {
@@ -266,9 +439,9 @@ func (tf *TranslationFlow) getMultiChunkInitialTranslation(ctx context.Context,
 	}
 
 	log.Debug("multi-chunk translation", "system", system, "prompt", prompt, "chunk_index", chunkIndex)
-	translation, err2 := tf.reasoning(ctx, err, system, prompt)
-	if err2 != nil {
-		return "", err2
+	translation, err := tf.reasoningPart(ctx, StageInitial, chunkIndex, system, prompt)
+	if err != nil {
+		return "", err
 	}
 	log.Debug("multi-chunk translation result", "translation", translation, "chunk_index", chunkIndex)
 	return translation, nil
@@ -276,8 +449,8 @@ func (tf *TranslationFlow) getMultiChunkInitialTranslation(ctx context.Context,
 
 func (tf *TranslationFlow) getMultiChunkReflection(ctx context.Context, data TemplateData, chunkIndex int) (string, error) {
 	system, prompt, err := tf.executeTemplates(
-		MultiChunkReflectionSystemMessageTmpl,
-		MultiChunkReflectionPromptTmpl,
+		TmplMultiChunkReflectionSystem,
+		reflectionPromptTmpl(data, TmplMultiChunkReflectionCountryPrompt, TmplMultiChunkReflectionPrompt),
 		data,
 	)
 	if err != nil {
@@ -285,9 +458,9 @@ func (tf *TranslationFlow) getMultiChunkReflection(ctx context.Context, data Tem
 	}
 
 	log.Debug("multi-chunk reflection", "system", system, "prompt", prompt, "chunk_index", chunkIndex)
-	reflection, err2 := tf.reasoning(ctx, err, system, prompt)
-	if err2 != nil {
-		return "", err2
+	reflection, err := tf.reasoningPart(ctx, StageReflection, chunkIndex, system, prompt)
+	if err != nil {
+		return "", err
 	}
 	log.Debug("multi-chunk reflection result", "reflection", reflection, "chunk_index", chunkIndex)
 	return reflection, nil
@@ -295,8 +468,8 @@ func (tf *TranslationFlow) getMultiChunkReflection(ctx context.Context, data Tem
 
 func (tf *TranslationFlow) getMultiChunkImprovedTranslation(ctx context.Context, data TemplateData, chunkIndex int) (string, error) {
 	system, prompt, err := tf.executeTemplates(
-		MultiChunkImprovementSystemMessageTmpl,
-		MultiChunkImprovementPromptTmpl,
+		TmplMultiChunkImprovementSystem,
+		TmplMultiChunkImprovementPrompt,
 		data,
 	)
 	if err != nil {
@@ -304,9 +477,9 @@ func (tf *TranslationFlow) getMultiChunkImprovedTranslation(ctx context.Context,
 	}
 
 	log.Debug("multi-chunk improvement", "system", system, "prompt", prompt, "chunk_index", chunkIndex)
-	improvedTranslation, err2 := tf.reasoning(ctx, err, system, prompt)
-	if err2 != nil {
-		return "", err2
+	improvedTranslation, err := tf.reasoningPart(ctx, StageImprovement, chunkIndex, system, prompt)
+	if err != nil {
+		return "", err
 	}
 	log.Debug("multi-chunk improvement result", "translation", improvedTranslation, "chunk_index", chunkIndex)
 	return improvedTranslation, nil
@@ -317,7 +490,18 @@ func (tf *TranslationFlow) CreateTaggedTextForTest(fullText, currentChunk string
 }
 
 func (tf *TranslationFlow) ExecuteTemplatesForTest(systemTmpl, promptTmpl string, data TemplateData) (string, string, error) {
-	return tf.executeTemplates(systemTmpl, promptTmpl, data)
+	templates := &Templates{templates: make(map[string]*template.Template)}
+	for name, text := range map[string]string{"system": systemTmpl, "prompt": promptTmpl} {
+		tmpl, err := parseTemplate(name, text)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse %s template: %w", name, err)
+		}
+		templates.templates[name] = tmpl
+	}
+
+	testFlow := *tf
+	testFlow.templates = templates
+	return testFlow.executeTemplates("system", "prompt", data)
 }
 
 // CalculateChunkSize calculates the size of each chunk based on the total token count and limit.
@@ -1,6 +1,8 @@
 package translationflow_test
 
 import (
+	"context"
+	"strings"
 	"testing"
 
 	"raggo/src/core/translationflow"
@@ -119,3 +121,83 @@ func TestCalculateChunkSize(t *testing.T) {
 		})
 	}
 }
+
+// recordingProvider answers every prompt with a fixed text and records the prompts it was given
+type recordingProvider struct {
+	tokenLength int
+	chunks      []string
+	prompts     []string
+}
+
+func (p *recordingProvider) TextSplit(ctx context.Context, text string, chunkSize, chunkOverLap int) ([]string, error) {
+	return p.chunks, nil
+}
+
+func (p *recordingProvider) Reasoning(ctx context.Context, system string, prompt string) (string, error) {
+	p.prompts = append(p.prompts, prompt)
+	return "translated", nil
+}
+
+func (p *recordingProvider) TokenLength(ctx context.Context, text string) (int, error) {
+	return p.tokenLength, nil
+}
+
+func TestTranslateLocale(t *testing.T) {
+	styleGuide := &translationflow.StyleGuide{Formality: translationflow.FormalityFormal, Tone: "friendly"}
+
+	tests := []struct {
+		name         string
+		provider     *recordingProvider
+		country      string
+		styleGuide   *translationflow.StyleGuide
+		wantCountry  bool
+		wantStyle    bool
+		wantReasoned int
+	}{
+		{
+			name:         "single chunk with country",
+			provider:     &recordingProvider{tokenLength: 10},
+			country:      "Taiwan",
+			wantCountry:  true,
+			wantReasoned: 3,
+		},
+		{
+			name:         "single chunk without country",
+			provider:     &recordingProvider{tokenLength: 10},
+			wantReasoned: 3,
+		},
+		{
+			name:         "multi chunk with country and style guide",
+			provider:     &recordingProvider{tokenLength: 200, chunks: []string{"Hello", "World"}},
+			country:      "Taiwan",
+			styleGuide:   styleGuide,
+			wantCountry:  true,
+			wantStyle:    true,
+			wantReasoned: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf := translationflow.NewTranslationFlow(tt.provider, translationflow.WithStyleGuide(tt.styleGuide))
+			if _, err := tf.Translate(context.Background(), "Hello World", "English", "Chinese", tt.country); err != nil {
+				t.Fatalf("Translate() error = %v", err)
+			}
+
+			if len(tt.provider.prompts) != tt.wantReasoned {
+				t.Fatalf("Translate() reasoned %d times, want %d", len(tt.provider.prompts), tt.wantReasoned)
+			}
+			for i, prompt := range tt.provider.prompts {
+				isReflection := i%3 == 1
+				gotCountry := strings.Contains(prompt, "colloquially spoken in "+tt.country)
+				if isReflection && gotCountry != tt.wantCountry {
+					t.Errorf("reflection prompt %d mentions country = %v, want %v", i, gotCountry, tt.wantCountry)
+				}
+				gotStyle := strings.Contains(prompt, "<STYLE_GUIDE>") && strings.Contains(prompt, "Tone: friendly")
+				if gotStyle != tt.wantStyle {
+					t.Errorf("prompt %d contains style guide = %v, want %v", i, gotStyle, tt.wantStyle)
+				}
+			}
+		})
+	}
+}
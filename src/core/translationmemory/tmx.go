@@ -0,0 +1,100 @@
+package translationmemory
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+const tmxDateFormat = "20060102T150405Z"
+
+type tmxDocument struct {
+	XMLName xml.Name  `xml:"tmx"`
+	Version string    `xml:"version,attr"`
+	Header  tmxHeader `xml:"header"`
+	Units   []tmxUnit `xml:"body>tu"`
+}
+
+type tmxHeader struct {
+	CreationTool        string `xml:"creationtool,attr"`
+	CreationToolVersion string `xml:"creationtoolversion,attr"`
+	DataType            string `xml:"datatype,attr"`
+	SegType             string `xml:"segtype,attr"`
+	AdminLang           string `xml:"adminlang,attr"`
+	SrcLang             string `xml:"srclang,attr"`
+	OTMF                string `xml:"o-tmf,attr"`
+}
+
+type tmxUnit struct {
+	CreationDate string       `xml:"creationdate,attr,omitempty"`
+	ChangeDate   string       `xml:"changedate,attr,omitempty"`
+	Props        []tmxProp    `xml:"prop"`
+	Variants     []tmxVariant `xml:"tuv"`
+}
+
+type tmxProp struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type tmxVariant struct {
+	Lang    string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Segment string `xml:"seg"`
+}
+
+// WriteTMX writes the entries as a TMX 1.4 document. Country and model of every entry are kept as properties.
+// srcLang is the source language of the document, "*all*" if the entries have different source languages.
+func WriteTMX(w io.Writer, srcLang string, entries []Entry) error {
+	doc := tmxDocument{
+		Version: "1.4",
+		Header: tmxHeader{
+			CreationTool:        "raggo",
+			CreationToolVersion: "1.0",
+			DataType:            "plaintext",
+			SegType:             "paragraph",
+			AdminLang:           "en",
+			SrcLang:             srcLang,
+			OTMF:                "raggo",
+		},
+	}
+
+	for _, entry := range entries {
+		unit := tmxUnit{
+			Variants: []tmxVariant{
+				{Lang: entry.SourceLanguage, Segment: entry.SourceText},
+				{Lang: entry.TargetLanguage, Segment: entry.TargetText},
+			},
+		}
+		if !entry.CreatedAt.IsZero() {
+			unit.CreationDate = entry.CreatedAt.UTC().Format(tmxDateFormat)
+		}
+		if !entry.UpdatedAt.IsZero() {
+			unit.ChangeDate = entry.UpdatedAt.UTC().Format(tmxDateFormat)
+		}
+		if entry.Country != "" {
+			unit.Props = append(unit.Props, tmxProp{Type: "x-country", Value: entry.Country})
+		}
+		if entry.Model != "" {
+			unit.Props = append(unit.Props, tmxProp{Type: "x-model", Value: entry.Model})
+		}
+		for _, prop := range []tmxProp{
+			{Type: "x-pipeline", Value: entry.Pipeline},
+			{Type: "x-glossary", Value: entry.Glossary},
+			{Type: "x-style-guide", Value: entry.StyleGuide},
+		} {
+			if prop.Value != "" {
+				unit.Props = append(unit.Props, prop)
+			}
+		}
+		doc.Units = append(doc.Units, unit)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
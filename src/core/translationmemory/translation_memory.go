@@ -0,0 +1,234 @@
+package translationmemory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/snowflake"
+
+	"raggo/src/core/translationflow"
+	"raggo/src/storage/contenthash"
+)
+
+const (
+	DefaultSimilarityThreshold = 0.75
+	DefaultMaxSuggestions      = 3
+	defaultCandidateLimit      = 500
+	candidateLengthTolerance   = 0.3 // candidates may be 30% shorter or longer than the source text
+)
+
+// Key identifies the translations that can be reused for each other: those of the same language pair and country
+// by the same model, made by the same pipeline following the same glossary and style guide
+type Key struct {
+	SourceLanguage string
+	TargetLanguage string
+	Country        string
+	Model          string
+	Pipeline       string // signature of the pipeline, empty for the default pipeline
+	Glossary       string // signature of the glossary, empty without one
+	StyleGuide     string // signature of the style guide, empty without one
+}
+
+// Entry is a translated text stored in the translation memory
+type Entry struct {
+	ID             int64     `json:"id"`
+	SourceHash     string    `json:"source_hash"`
+	SourceLanguage string    `json:"source_language"`
+	TargetLanguage string    `json:"target_language"`
+	Country        string    `json:"country"`
+	Model          string    `json:"model"`
+	Pipeline       string    `json:"pipeline,omitempty"`
+	Glossary       string    `json:"glossary,omitempty"`
+	StyleGuide     string    `json:"style_guide,omitempty"`
+	SourceText     string    `json:"source_text"`
+	TargetText     string    `json:"target_text"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Key returns the key the entry is stored under
+func (e *Entry) Key() Key {
+	return Key{
+		SourceLanguage: e.SourceLanguage,
+		TargetLanguage: e.TargetLanguage,
+		Country:        e.Country,
+		Model:          e.Model,
+		Pipeline:       e.Pipeline,
+		Glossary:       e.Glossary,
+		StyleGuide:     e.StyleGuide,
+	}
+}
+
+// PostgresRepository defines the interface for PostgreSQL operations
+type PostgresRepository interface {
+	// Get returns the entry of the source hash for the key, or nil if there is none
+	Get(ctx context.Context, sourceHash string, key Key) (*Entry, error)
+	// Save inserts the entry or updates the translation of an existing entry with the same hash and key
+	Save(ctx context.Context, entry *Entry) error
+	// ListCandidates returns entries of the language pair and country whose source text length is within the bounds
+	ListCandidates(ctx context.Context, sourceLanguage, targetLanguage, country string, minLength, maxLength, limit int) ([]Entry, error)
+	// List returns the entries matching every non-empty field of the key
+	List(ctx context.Context, key Key) ([]Entry, error)
+	// Delete removes the entry
+	Delete(ctx context.Context, id int64) error
+}
+
+// Service stores finished translations and looks up exact and fuzzy matches for new texts
+type Service struct {
+	postgresRepo        PostgresRepository
+	snowflake           *snowflake.Node
+	similarityThreshold float64
+	maxSuggestions      int
+}
+
+func NewService(postgresRepo PostgresRepository, opts ...Option) (*Service, error) {
+	// Initialize snowflake node
+	node, err := snowflake.NewNode(6) // Node number 6 for translation memory entries
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snowflake node: %v", err)
+	}
+
+	s := &Service{
+		postgresRepo:        postgresRepo,
+		snowflake:           node,
+		similarityThreshold: DefaultSimilarityThreshold,
+		maxSuggestions:      DefaultMaxSuggestions,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+type Option func(s *Service)
+
+func WithSimilarityThreshold(threshold float64) Option {
+	return func(s *Service) {
+		s.similarityThreshold = threshold
+	}
+}
+
+func WithMaxSuggestions(maxSuggestions int) Option {
+	return func(s *Service) {
+		s.maxSuggestions = maxSuggestions
+	}
+}
+
+// Lookup returns the stored translation of exactly this text, or nil if it was never translated for the key
+func (s *Service) Lookup(ctx context.Context, key Key, source string) (*Entry, error) {
+	return s.postgresRepo.Get(ctx, contenthash.Sum([]byte(source)), key)
+}
+
+// Suggest returns translations of similar texts of the same language pair and country, most similar first.
+// Translations of any model are considered since they only serve as examples in the prompt.
+func (s *Service) Suggest(ctx context.Context, key Key, source string) ([]translationflow.Reference, error) {
+	length := len([]rune(source))
+	minLength := int(float64(length) * (1 - candidateLengthTolerance))
+	maxLength := int(float64(length)*(1+candidateLengthTolerance)) + 1
+
+	candidates, err := s.postgresRepo.ListCandidates(ctx, key.SourceLanguage, key.TargetLanguage, key.Country, minLength, maxLength, defaultCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return FindSimilar(source, candidates, s.similarityThreshold, s.maxSuggestions), nil
+}
+
+// Store records the translation of the source text for the key
+func (s *Service) Store(ctx context.Context, key Key, source, translation string) error {
+	return s.postgresRepo.Save(ctx, &Entry{
+		ID:             s.snowflake.Generate().Int64(),
+		SourceHash:     contenthash.Sum([]byte(source)),
+		SourceLanguage: key.SourceLanguage,
+		TargetLanguage: key.TargetLanguage,
+		Country:        key.Country,
+		Model:          key.Model,
+		Pipeline:       key.Pipeline,
+		Glossary:       key.Glossary,
+		StyleGuide:     key.StyleGuide,
+		SourceText:     source,
+		TargetText:     translation,
+	})
+}
+
+// Forget removes the translation of the source text for the key if it is still the given translation,
+// so that a rejected translation is not reused while a newer one is kept
+func (s *Service) Forget(ctx context.Context, key Key, source, translation string) error {
+	entry, err := s.Lookup(ctx, key, source)
+	if err != nil {
+		return err
+	}
+	if entry == nil || entry.TargetText != translation {
+		return nil
+	}
+	return s.postgresRepo.Delete(ctx, entry.ID)
+}
+
+// List returns the entries matching every non-empty field of the key
+func (s *Service) List(ctx context.Context, key Key) ([]Entry, error) {
+	return s.postgresRepo.List(ctx, key)
+}
+
+// FindSimilar returns the candidates whose source text is at least threshold similar to the source,
+// most similar first and at most limit of them. Exact matches are skipped since they are reused directly.
+func FindSimilar(source string, candidates []Entry, threshold float64, limit int) []translationflow.Reference {
+	var references []translationflow.Reference
+	for _, candidate := range candidates {
+		if candidate.SourceText == source {
+			continue
+		}
+		similarity := Similarity(source, candidate.SourceText)
+		if similarity < threshold {
+			continue
+		}
+		references = append(references, translationflow.Reference{
+			Source:      candidate.SourceText,
+			Translation: candidate.TargetText,
+			Similarity:  similarity,
+		})
+	}
+
+	sort.SliceStable(references, func(i, j int) bool {
+		return references[i].Similarity > references[j].Similarity
+	})
+	if len(references) > limit {
+		references = references[:limit]
+	}
+	return references
+}
+
+// Similarity returns the Dice coefficient of the character bigrams of both texts, between 0 and 1.
+// Character bigrams work for languages with and without spaces between words.
+func Similarity(a, b string) float64 {
+	bigramsA, bigramsB := bigrams(a), bigrams(b)
+	total := 0
+	for _, count := range bigramsA {
+		total += count
+	}
+	for _, count := range bigramsB {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	shared := 0
+	for bigram, countA := range bigramsA {
+		shared += min(countA, bigramsB[bigram])
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+func bigrams(text string) map[string]int {
+	runes := []rune(strings.ToLower(strings.Join(strings.Fields(text), " ")))
+	counts := make(map[string]int)
+	for i := 0; i+1 < len(runes); i++ {
+		counts[string(runes[i:i+2])]++
+	}
+	return counts
+}
@@ -0,0 +1,198 @@
+package translationmemory_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"raggo/src/core/translationmemory"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{name: "identical", a: "Press the power button.", b: "Press the power button.", min: 1, max: 1},
+		{name: "case and spacing ignored", a: "Press the  POWER button.", b: "press the power button.", min: 1, max: 1},
+		{name: "near duplicate", a: "Press the power button for 3 seconds.", b: "Press the power button for 5 seconds.", min: 0.9, max: 0.99},
+		{name: "unrelated", a: "Press the power button.", b: "The warranty lasts two years.", min: 0, max: 0.3},
+		{name: "chinese", a: "請按下電源按鈕三秒鐘", b: "請按下電源按鈕五秒鐘", min: 0.7, max: 0.9},
+		{name: "empty", a: "", b: "", min: 0, max: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := translationmemory.Similarity(tt.a, tt.b)
+			if got < tt.min || got > tt.max {
+				t.Errorf("Similarity(%q, %q) = %v, want between %v and %v", tt.a, tt.b, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestFindSimilar(t *testing.T) {
+	candidates := []translationmemory.Entry{
+		{SourceText: "Press the power button for 3 seconds.", TargetText: "按住電源鍵 3 秒。"},
+		{SourceText: "Press the power button for 5 seconds.", TargetText: "按住電源鍵 5 秒。"},
+		{SourceText: "Press the power button for 10 seconds to reset.", TargetText: "按住電源鍵 10 秒以重設。"},
+		{SourceText: "The warranty lasts two years.", TargetText: "保固期為兩年。"},
+	}
+
+	got := translationmemory.FindSimilar("Press the power button for 5 seconds.", candidates, 0.75, 2)
+	if len(got) != 2 {
+		t.Fatalf("FindSimilar() returned %d references, want 2", len(got))
+	}
+	if got[0].Source != "Press the power button for 3 seconds." {
+		t.Errorf("FindSimilar()[0].Source = %q, want the closest non-identical candidate", got[0].Source)
+	}
+	if got[0].Similarity < got[1].Similarity {
+		t.Errorf("FindSimilar() is not sorted by similarity: %v < %v", got[0].Similarity, got[1].Similarity)
+	}
+}
+
+func TestWriteTMX(t *testing.T) {
+	entries := []translationmemory.Entry{
+		{
+			SourceLanguage: "en",
+			TargetLanguage: "zh-TW",
+			Country:        "TW",
+			Model:          "llama3.3",
+			SourceText:     "Fish & Chips <deluxe>",
+			TargetText:     "炸魚薯條",
+			CreatedAt:      time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := translationmemory.WriteTMX(&buf, "en", entries); err != nil {
+		t.Fatalf("WriteTMX() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`<tmx version="1.4">`,
+		`srclang="en"`,
+		`<tu creationdate="20240501T083000Z">`,
+		`<prop type="x-country">TW</prop>`,
+		`<tuv xml:lang="en">`,
+		`<seg>Fish &amp; Chips &lt;deluxe&gt;</seg>`,
+		`<tuv xml:lang="zh-TW">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteTMX() output misses %q:\n%s", want, out)
+		}
+	}
+}
+
+// memoryRepository keeps the entries of the translation memory in a map by source hash
+type memoryRepository struct {
+	entries map[string]translationmemory.Entry
+}
+
+func (r *memoryRepository) Get(ctx context.Context, sourceHash string, key translationmemory.Key) (*translationmemory.Entry, error) {
+	entry, ok := r.entries[sourceHash]
+	if !ok || entry.Key() != key {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+func (r *memoryRepository) Save(ctx context.Context, entry *translationmemory.Entry) error {
+	r.entries[entry.SourceHash] = *entry
+	return nil
+}
+
+func (r *memoryRepository) ListCandidates(ctx context.Context, sourceLanguage, targetLanguage, country string, minLength, maxLength, limit int) ([]translationmemory.Entry, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) List(ctx context.Context, key translationmemory.Key) ([]translationmemory.Entry, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, id int64) error {
+	for hash, entry := range r.entries {
+		if entry.ID == id {
+			delete(r.entries, hash)
+		}
+	}
+	return nil
+}
+
+func TestForget(t *testing.T) {
+	ctx := context.Background()
+	key := translationmemory.Key{SourceLanguage: "en", TargetLanguage: "de"}
+
+	tests := []struct {
+		name       string
+		forget     string
+		wantStored bool
+	}{
+		{name: "rejected translation is removed", forget: "Hallo Welt", wantStored: false},
+		{name: "newer translation is kept", forget: "Servus Welt", wantStored: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := translationmemory.NewService(&memoryRepository{entries: make(map[string]translationmemory.Entry)})
+			if err != nil {
+				t.Fatalf("NewService() error = %v", err)
+			}
+			if err := service.Store(ctx, key, "Hello world", "Hallo Welt"); err != nil {
+				t.Fatalf("Store() error = %v", err)
+			}
+
+			if err := service.Forget(ctx, key, "Hello world", tt.forget); err != nil {
+				t.Fatalf("Forget() error = %v", err)
+			}
+			entry, err := service.Lookup(ctx, key, "Hello world")
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if (entry != nil) != tt.wantStored {
+				t.Errorf("Lookup() after Forget() = %v, want stored %v", entry, tt.wantStored)
+			}
+		})
+	}
+}
+
+func TestLookupKeepsGlossariesAndStyleGuidesApart(t *testing.T) {
+	ctx := context.Background()
+	key := translationmemory.Key{SourceLanguage: "en", TargetLanguage: "de", Model: "llama3.3", Glossary: "3f2a9c1d0b7e"}
+
+	tests := []struct {
+		name      string
+		lookup    translationmemory.Key
+		wantFound bool
+	}{
+		{name: "same glossary", lookup: key, wantFound: true},
+		{name: "without the glossary", lookup: translationmemory.Key{SourceLanguage: "en", TargetLanguage: "de", Model: "llama3.3"}, wantFound: false},
+		{name: "other glossary", lookup: translationmemory.Key{SourceLanguage: "en", TargetLanguage: "de", Model: "llama3.3", Glossary: "8d41e07a6c55"}, wantFound: false},
+		{name: "with a style guide", lookup: translationmemory.Key{SourceLanguage: "en", TargetLanguage: "de", Model: "llama3.3", Glossary: "3f2a9c1d0b7e", StyleGuide: "c09b7d5e2f14"}, wantFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := translationmemory.NewService(&memoryRepository{entries: make(map[string]translationmemory.Entry)})
+			if err != nil {
+				t.Fatalf("NewService() error = %v", err)
+			}
+			if err := service.Store(ctx, key, "Hello world", "Hallo Welt"); err != nil {
+				t.Fatalf("Store() error = %v", err)
+			}
+
+			entry, err := service.Lookup(ctx, tt.lookup, "Hello world")
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if (entry != nil) != tt.wantFound {
+				t.Errorf("Lookup() = %v, want found %v", entry, tt.wantFound)
+			}
+		})
+	}
+}
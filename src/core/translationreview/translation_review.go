@@ -0,0 +1,196 @@
+package translationreview
+
+import (
+	"context"
+	"fmt"
+
+	"raggo/src/core/document"
+	"raggo/src/core/translationmemory"
+	"raggo/src/infrastructure/log"
+	"raggo/src/storage/postgres/chunkctrl"
+	"raggo/src/storage/postgres/translatedchunkctrl"
+	"raggo/src/storage/postgres/translatedresourcectrl"
+)
+
+// ObjectStorage is the part of minioctrl.MinioService the review uses
+type ObjectStorage interface {
+	GetObject(ctx context.Context, bucketName, objectName string) ([]byte, error)
+	PutObject(ctx context.Context, bucketName, objectName string, data []byte) error
+	GetBucketAndObjectFromURL(minioURL string) (string, string)
+}
+
+// ChunkService is the part of chunkctrl.ChunkService the review uses
+type ChunkService interface {
+	GetByID(ctx context.Context, id int64) (*chunkctrl.Chunk, error)
+	GetByResourceID(ctx context.Context, resourceID int64) ([]chunkctrl.Chunk, error)
+}
+
+// TranslatedChunkService is the part of translatedchunkctrl.TranslatedChunkService the review uses
+type TranslatedChunkService interface {
+	GetByID(ctx context.Context, id int64) (*translatedchunkctrl.TranslatedChunk, error)
+	GetByTranslatedResourceID(ctx context.Context, translatedResourceID int64) ([]translatedchunkctrl.TranslatedChunk, error)
+	SaveRevision(ctx context.Context, id int64, revisionMinioURL, author string) error
+	SetReview(ctx context.Context, id int64, status, reviewer, comment string) error
+}
+
+// Service lets translators revise, approve and reject translated chunks.
+// The machine draft of a chunk is never overwritten; revisions are stored next to it.
+type Service struct {
+	minioService       ObjectStorage
+	chunkService       ChunkService
+	translatedChunkSvc TranslatedChunkService
+	translationMemory  *translationmemory.Service
+}
+
+func NewService(
+	minioService ObjectStorage,
+	chunkService ChunkService,
+	translatedChunkSvc TranslatedChunkService,
+	translationMemory *translationmemory.Service,
+) *Service {
+	return &Service{
+		minioService:       minioService,
+		chunkService:       chunkService,
+		translatedChunkSvc: translatedChunkSvc,
+		translationMemory:  translationMemory,
+	}
+}
+
+// Revise stores the revision of a translator. The chunk waits for approval again before the revision is used.
+func (s *Service) Revise(ctx context.Context, tc *translatedchunkctrl.TranslatedChunk, text, author string) (*translatedchunkctrl.TranslatedChunk, error) {
+	bucket, objectName := s.minioService.GetBucketAndObjectFromURL(tc.MinioURL)
+	revisionObjectName := objectName + "_revision"
+	if err := s.minioService.PutObject(ctx, bucket, revisionObjectName, []byte(text)); err != nil {
+		return nil, fmt.Errorf("failed to save revision: %w", err)
+	}
+
+	if err := s.translatedChunkSvc.SaveRevision(ctx, tc.ID, fmt.Sprintf("%s/%s", bucket, revisionObjectName), author); err != nil {
+		return nil, err
+	}
+	return s.translatedChunkSvc.GetByID(ctx, tc.ID)
+}
+
+// Approve accepts the translation of a chunk, including its revision if there is one.
+// An approved revision replaces the machine translation in the translation memory,
+// and the translated resource is regenerated with it.
+func (s *Service) Approve(ctx context.Context, tr *translatedresourcectrl.TranslatedResource, tc *translatedchunkctrl.TranslatedChunk, reviewer, comment string) (*translatedchunkctrl.TranslatedChunk, error) {
+	if err := s.translatedChunkSvc.SetReview(ctx, tc.ID, translatedchunkctrl.ReviewStatusApproved, reviewer, comment); err != nil {
+		return nil, err
+	}
+
+	if tc.RevisionMinioURL != "" {
+		original, source, revision, err := s.sourceAndText(ctx, tc, tc.RevisionMinioURL)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.translationMemory.Store(ctx, memoryKey(tr, tc, original), source, revision); err != nil {
+			return nil, fmt.Errorf("failed to store revision in translation memory: %w", err)
+		}
+	}
+
+	if err := s.Regenerate(ctx, tr); err != nil {
+		return nil, err
+	}
+	return s.translatedChunkSvc.GetByID(ctx, tc.ID)
+}
+
+// Reject discards the translation of a chunk. It is left out of the translated resource,
+// removed from the translation memory and translated again by the next run of the translation job.
+func (s *Service) Reject(ctx context.Context, tr *translatedresourcectrl.TranslatedResource, tc *translatedchunkctrl.TranslatedChunk, reviewer, comment string) (*translatedchunkctrl.TranslatedChunk, error) {
+	if err := s.translatedChunkSvc.SetReview(ctx, tc.ID, translatedchunkctrl.ReviewStatusRejected, reviewer, comment); err != nil {
+		return nil, err
+	}
+
+	original, source, draft, err := s.sourceAndText(ctx, tc, tc.MinioURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.translationMemory.Forget(ctx, memoryKey(tr, tc, original), source, draft); err != nil {
+		return nil, fmt.Errorf("failed to remove translation from translation memory: %w", err)
+	}
+
+	if err := s.Regenerate(ctx, tr); err != nil {
+		return nil, err
+	}
+	return s.translatedChunkSvc.GetByID(ctx, tc.ID)
+}
+
+// Regenerate rebuilds the translated resource from its chunks in document order. Approved chunks contribute
+// their revision, other chunks their machine draft, and rejected or untranslated chunks are left out.
+func (s *Service) Regenerate(ctx context.Context, tr *translatedresourcectrl.TranslatedResource) error {
+	chunks, err := s.chunkService.GetByResourceID(ctx, tr.OriginalResourceID)
+	if err != nil {
+		return err
+	}
+	translatedChunks, err := s.translatedChunkSvc.GetByTranslatedResourceID(ctx, tr.ID)
+	if err != nil {
+		return err
+	}
+	byOriginal := make(map[int64]translatedchunkctrl.TranslatedChunk, len(translatedChunks))
+	for _, tc := range translatedChunks {
+		byOriginal[tc.OriginalChunkID] = tc
+	}
+
+	var parts []document.Part
+	for _, chunk := range chunks {
+		tc, ok := byOriginal[chunk.ID]
+		if !ok || tc.IsRejected() {
+			continue
+		}
+		text, err := s.read(ctx, tc.FinalMinioURL())
+		if err != nil {
+			return fmt.Errorf("failed to read translated chunk %s: %w", tc.ChunkID, err)
+		}
+		parts = append(parts, document.Part{Kind: chunk.Kind, Content: text})
+	}
+
+	_, markdown := document.Assemble(parts)
+	bucket, objectName := s.minioService.GetBucketAndObjectFromURL(tr.MinioURL)
+	if err := s.minioService.PutObject(ctx, bucket, objectName, []byte(markdown)); err != nil {
+		return fmt.Errorf("failed to save translated resource: %w", err)
+	}
+
+	log.Info("Regenerated translated resource", "translated_resource_id", tr.ID, "chunks", len(parts))
+	return nil
+}
+
+// sourceAndText returns the source chunk of a translated chunk with its content and the translation stored at minioURL
+func (s *Service) sourceAndText(ctx context.Context, tc *translatedchunkctrl.TranslatedChunk, minioURL string) (*chunkctrl.Chunk, string, string, error) {
+	original, err := s.chunkService.GetByID(ctx, tc.OriginalChunkID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if original == nil {
+		return nil, "", "", fmt.Errorf("source chunk %d not found", tc.OriginalChunkID)
+	}
+
+	source, err := s.read(ctx, original.MinioURL)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to read source chunk: %w", err)
+	}
+	text, err := s.read(ctx, minioURL)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to read translated chunk: %w", err)
+	}
+	return original, source, text, nil
+}
+
+func (s *Service) read(ctx context.Context, minioURL string) (string, error) {
+	bucket, objectName := s.minioService.GetBucketAndObjectFromURL(minioURL)
+	content, err := s.minioService.GetObject(ctx, bucket, objectName)
+	return string(content), err
+}
+
+// memoryKey returns the translation memory key the translation job stored the translation of a chunk under,
+// so an approved revision is reused by the next translation of the same text and a rejected draft is not.
+func memoryKey(tr *translatedresourcectrl.TranslatedResource, tc *translatedchunkctrl.TranslatedChunk, original *chunkctrl.Chunk) translationmemory.Key {
+	sourceLanguage := tc.SourceLanguage
+	if sourceLanguage == "" {
+		// Chunks translated before their source language was recorded are keyed by the detected language if any
+		sourceLanguage = tr.SourceLanguage
+		if original.Language != "" {
+			sourceLanguage = original.Language
+		}
+	}
+	return tr.MemoryKey(sourceLanguage)
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -43,6 +44,7 @@ type GenerateRequest struct {
 	System  string                 `json:"system"`
 	Prompt  string                 `json:"prompt"`
 	Stream  bool                   `json:"stream"`
+	Images  []string               `json:"images,omitempty"` // base64 encoded images for multimodal models
 	Options map[string]interface{} `json:"options,omitempty"`
 }
 
@@ -128,14 +130,45 @@ func (c *Client) GetEmbedding(ctx context.Context, model string, text string) ([
 
 // Generate performs model generation with the given prompt
 func (c *Client) Generate(ctx context.Context, model, system, prompt string, options map[string]interface{}) (string, error) {
-	reqBody := GenerateRequest{
+	return c.generate(ctx, GenerateRequest{
 		Model:   model,
 		System:  system,
 		Prompt:  prompt,
 		Stream:  true,
 		Options: options,
+	}, nil)
+}
+
+// GenerateStream performs model generation like Generate and passes every piece of the response to onToken as it arrives
+func (c *Client) GenerateStream(ctx context.Context, model, system, prompt string, options map[string]interface{}, onToken func(string)) (string, error) {
+	return c.generate(ctx, GenerateRequest{
+		Model:   model,
+		System:  system,
+		Prompt:  prompt,
+		Stream:  true,
+		Options: options,
+	}, onToken)
+}
+
+// GenerateWithImages performs generation with a vision-capable model, attaching the given images to the prompt
+func (c *Client) GenerateWithImages(ctx context.Context, model, system, prompt string, images [][]byte, options map[string]interface{}) (string, error) {
+	encoded := make([]string, len(images))
+	for i, image := range images {
+		encoded[i] = base64.StdEncoding.EncodeToString(image)
 	}
 
+	return c.generate(ctx, GenerateRequest{
+		Model:   model,
+		System:  system,
+		Prompt:  prompt,
+		Stream:  true,
+		Images:  encoded,
+		Options: options,
+	}, nil)
+}
+
+// generate sends the request and collects the streamed response, passing each piece to onToken unless it is nil
+func (c *Client) generate(ctx context.Context, reqBody GenerateRequest, onToken func(string)) (string, error) {
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
 		return "", fmt.Errorf("error marshaling request: %w", err)
@@ -196,6 +229,9 @@ func (c *Client) Generate(ctx context.Context, model, system, prompt string, opt
 
 		//log.Debug("received response chunk", "response", response.Response, "done", response.Done)
 		fullResponse.WriteString(response.Response)
+		if onToken != nil && response.Response != "" {
+			onToken(response.Response)
+		}
 
 		if response.Truncated {
 			log.Error(fmt.Errorf("response was truncated by the model"), "response was truncated by the model")
@@ -38,6 +38,22 @@ func (o *OllamaProvider) Reasoning(ctx context.Context, system string, prompt st
 	})
 }
 
+// ReasoningStream answers like Reasoning and passes the answer to onToken while it is generated
+func (o *OllamaProvider) ReasoningStream(ctx context.Context, system string, prompt string, onToken func(string)) (string, error) {
+	return o.ollamaClient.GenerateStream(ctx, o.modelName, system, prompt, map[string]interface{}{
+		"temperature": 0.7,
+		"top_p":       0.9,
+		"num_ctx":     8192,
+	}, onToken)
+}
+
+func (o *OllamaProvider) DescribeImage(ctx context.Context, prompt string, image []byte) (string, error) {
+	return o.ollamaClient.GenerateWithImages(ctx, o.modelName, "", prompt, [][]byte{image}, map[string]interface{}{
+		"temperature": 0.2,
+		"num_ctx":     4096,
+	})
+}
+
 func (o *OllamaProvider) TokenLength(ctx context.Context, text string) (int, error) {
 	return o.ollamaClient.CountTokens(ctx, o.modelName, text)
 }
@@ -48,3 +64,20 @@ func NewOllamaProvider(ollamaClient *Client, modelName string) *OllamaProvider {
 		modelName:    modelName,
 	}
 }
+
+// OllamaEmbedder embeds texts with an Ollama embedding model
+type OllamaEmbedder struct {
+	ollamaClient *Client
+	modelName    string
+}
+
+func (o *OllamaEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return o.ollamaClient.GetEmbedding(ctx, o.modelName, text)
+}
+
+func NewOllamaEmbedder(ollamaClient *Client, modelName string) *OllamaEmbedder {
+	return &OllamaEmbedder{
+		ollamaClient: ollamaClient,
+		modelName:    modelName,
+	}
+}
@@ -0,0 +1,111 @@
+package unstructured
+
+import (
+	"sort"
+	"strings"
+)
+
+// ReadingOrder places the images extracted by a separate partitioning run between the elements of the document
+// and returns the 1-based position of every element and every image in reading order. An image comes before the
+// first element of a later page, or of its own page that starts below it when both have coordinates; an image
+// whose page is unknown comes last. The elements keep their order.
+func ReadingOrder(elements, images []UnstructuredElement) (elementOrders, imageOrders []int) {
+	// The index of the element every image is placed before, len(elements) for the end of the document
+	before := make([]int, len(images))
+	for i, image := range images {
+		before[i] = len(elements)
+		if image.Metadata.PageNumber == 0 {
+			continue
+		}
+		for j, element := range elements {
+			if element.follows(image) {
+				before[i] = j
+				break
+			}
+		}
+	}
+	byPosition := make([]int, len(images))
+	for i := range byPosition {
+		byPosition[i] = i
+	}
+	sort.SliceStable(byPosition, func(a, b int) bool {
+		return before[byPosition[a]] < before[byPosition[b]]
+	})
+
+	elementOrders = make([]int, len(elements))
+	imageOrders = make([]int, len(images))
+	order, next := 0, 0
+	for j := 0; j <= len(elements); j++ {
+		for next < len(byPosition) && before[byPosition[next]] == j {
+			order++
+			imageOrders[byPosition[next]] = order
+			next++
+		}
+		if j < len(elements) {
+			order++
+			elementOrders[j] = order
+		}
+	}
+	return elementOrders, imageOrders
+}
+
+// follows reports whether the element comes after the image in reading order
+func (e UnstructuredElement) follows(image UnstructuredElement) bool {
+	page := e.Metadata.PageNumber
+	switch {
+	case page == 0 || page < image.Metadata.PageNumber:
+		return false
+	case page > image.Metadata.PageNumber:
+		return true
+	}
+	top, ok := e.Metadata.Coordinates.top()
+	imageTop, imageOK := image.Metadata.Coordinates.top()
+	return ok && imageOK && top > imageTop
+}
+
+// top returns the uppermost point of the bounding box, reporting false without coordinates
+func (c Coordinates) top() (float64, bool) {
+	top, ok := 0.0, false
+	for _, point := range c.Points {
+		if len(point) < 2 {
+			continue
+		}
+		if !ok || point[1] < top {
+			top, ok = point[1], true
+		}
+	}
+	return top, ok
+}
+
+// SurroundingText returns the text of the elements right before and after the given position in reading order,
+// as returned by ReadingOrder, separated by a blank line. Tables and elements without text are skipped.
+// Each text is cut to maxRunes runes, keeping the part closest to the position.
+func SurroundingText(elements []UnstructuredElement, elementOrders []int, order int, maxRunes int) string {
+	var before, after string
+	for j, element := range elements {
+		if strings.TrimSpace(element.Text) == "" || element.IsTable() {
+			continue
+		}
+		if elementOrders[j] < order {
+			before = element.Text
+			continue
+		}
+		after = element.Text
+		break
+	}
+
+	var parts []string
+	if runes := []rune(before); len(runes) > maxRunes {
+		before = string(runes[len(runes)-maxRunes:])
+	}
+	if before != "" {
+		parts = append(parts, before)
+	}
+	if runes := []rune(after); len(runes) > maxRunes {
+		after = string(runes[:maxRunes])
+	}
+	if after != "" {
+		parts = append(parts, after)
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,121 @@
+package unstructured_test
+
+import (
+	"reflect"
+	"testing"
+
+	"raggo/src/infrastructure/integrations/unstructured"
+)
+
+func element(page int, top float64) unstructured.UnstructuredElement {
+	e := unstructured.UnstructuredElement{Metadata: unstructured.Metadata{PageNumber: page}}
+	if top >= 0 {
+		e.Metadata.Coordinates.Points = [][]float64{{10, top}, {10, top + 50}, {200, top + 50}, {200, top}}
+	}
+	return e
+}
+
+func TestReadingOrder(t *testing.T) {
+	tests := []struct {
+		name         string
+		elements     []unstructured.UnstructuredElement
+		images       []unstructured.UnstructuredElement
+		wantElements []int
+		wantImages   []int
+	}{
+		{
+			name:         "images go before the next page",
+			elements:     []unstructured.UnstructuredElement{element(1, -1), element(2, -1), element(3, -1)},
+			images:       []unstructured.UnstructuredElement{element(2, -1), element(1, -1)},
+			wantElements: []int{1, 3, 5},
+			wantImages:   []int{4, 2},
+		},
+		{
+			name:         "images are placed by coordinates within their page",
+			elements:     []unstructured.UnstructuredElement{element(1, 100), element(1, 400), element(2, 100)},
+			images:       []unstructured.UnstructuredElement{element(1, 250)},
+			wantElements: []int{1, 3, 4},
+			wantImages:   []int{2},
+		},
+		{
+			name:         "images without a page come last",
+			elements:     []unstructured.UnstructuredElement{element(1, -1), element(2, -1)},
+			images:       []unstructured.UnstructuredElement{element(0, -1), element(1, -1)},
+			wantElements: []int{1, 3},
+			wantImages:   []int{4, 2},
+		},
+		{
+			name:         "no images",
+			elements:     []unstructured.UnstructuredElement{element(1, -1), element(1, -1)},
+			wantElements: []int{1, 2},
+			wantImages:   []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotElements, gotImages := unstructured.ReadingOrder(tt.elements, tt.images)
+			if !reflect.DeepEqual(gotElements, tt.wantElements) {
+				t.Errorf("element orders = %v, want %v", gotElements, tt.wantElements)
+			}
+			if !reflect.DeepEqual(gotImages, tt.wantImages) {
+				t.Errorf("image orders = %v, want %v", gotImages, tt.wantImages)
+			}
+		})
+	}
+}
+
+func TestSurroundingText(t *testing.T) {
+	text := func(s string) unstructured.UnstructuredElement {
+		return unstructured.UnstructuredElement{Type: "NarrativeText", Text: s}
+	}
+	elements := []unstructured.UnstructuredElement{
+		text("Introduction"),
+		text("The pipeline has three stages."),
+		{Type: unstructured.ElementTypeTable, Text: "a b c", Metadata: unstructured.Metadata{TextAsHTML: "<table><tr><td>a</td><td>b</td><td>c</td></tr></table>"}},
+		text("  "),
+		text("Figure 1: the stages of the pipeline"),
+		text("Conclusion"),
+	}
+	elementOrders := []int{1, 2, 4, 5, 7, 8}
+
+	tests := []struct {
+		name     string
+		order    int
+		maxRunes int
+		want     string
+	}{
+		{
+			name:     "tables and empty elements are skipped",
+			order:    6,
+			maxRunes: 100,
+			want:     "The pipeline has three stages.\n\nFigure 1: the stages of the pipeline",
+		},
+		{
+			name:     "texts are cut next to the figure",
+			order:    3,
+			maxRunes: 7,
+			want:     "stages.\n\nFigure ",
+		},
+		{
+			name:     "figure at the start",
+			order:    0,
+			maxRunes: 100,
+			want:     "Introduction",
+		},
+		{
+			name:     "figure at the end",
+			order:    9,
+			maxRunes: 100,
+			want:     "Conclusion",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unstructured.SurroundingText(elements, elementOrders, tt.order, tt.maxRunes); got != tt.want {
+				t.Errorf("SurroundingText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package unstructured
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// TableHTMLToMarkdown converts the HTML table produced by Unstructured into a GitHub flavored Markdown table.
+// The first row is used as the header row. Cells spanning multiple columns are padded with empty cells
+// so every row has the same number of columns.
+func TableHTMLToMarkdown(tableHTML string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(tableHTML))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse table html: %v", err)
+	}
+
+	var rows [][]string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			rows = append(rows, collectRowCells(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if len(rows) == 0 {
+		return "", fmt.Errorf("no table rows found")
+	}
+
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if columns == 0 {
+		return "", fmt.Errorf("no table cells found")
+	}
+
+	var sb strings.Builder
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+func collectRowCells(tr *html.Node) []string {
+	var cells []string
+	for c := tr.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || (c.Data != "td" && c.Data != "th") {
+			continue
+		}
+		cells = append(cells, escapeMarkdownCell(nodeText(c)))
+		for i := 1; i < colspan(c); i++ {
+			cells = append(cells, "")
+		}
+	}
+	return cells
+}
+
+func colspan(n *html.Node) int {
+	for _, attr := range n.Attr {
+		if attr.Key == "colspan" {
+			if span, err := strconv.Atoi(attr.Val); err == nil && span > 1 {
+				return span
+			}
+		}
+	}
+	return 1
+}
+
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func escapeMarkdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
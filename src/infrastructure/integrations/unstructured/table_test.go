@@ -0,0 +1,50 @@
+package unstructured_test
+
+import (
+	"testing"
+
+	"raggo/src/infrastructure/integrations/unstructured"
+)
+
+func TestTableHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "header and body",
+			html: "<table><thead><tr><th>Model</th><th>Voltage</th></tr></thead><tbody><tr><td>A1</td><td>12V</td></tr></tbody></table>",
+			want: "| Model | Voltage |\n| --- | --- |\n| A1 | 12V |",
+		},
+		{
+			name: "colspan and ragged rows",
+			html: "<table><tr><td colspan=\"2\">Spec</td><td>Note</td></tr><tr><td>x</td></tr></table>",
+			want: "| Spec |  | Note |\n| --- | --- | --- |\n| x |  |  |",
+		},
+		{
+			name: "pipes are escaped",
+			html: "<table><tr><td>a|b</td></tr></table>",
+			want: "| a\\|b |\n| --- |",
+		},
+		{
+			name:    "no rows",
+			html:    "<p>not a table</p>",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unstructured.TableHTMLToMarkdown(tt.html)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TableHTMLToMarkdown() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("TableHTMLToMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
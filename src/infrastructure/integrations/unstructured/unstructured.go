@@ -2,6 +2,7 @@ package unstructured
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -22,11 +23,51 @@ type UnstructuredElement struct {
 }
 
 type Metadata struct {
-	Filename    string      `json:"filename,omitempty"`
-	Filetype    string      `json:"filetype,omitempty"`
-	PageNumber  int         `json:"page_number,omitempty"`
-	Coordinates Coordinates `json:"coordinates,omitempty"`
-	TableHTML   string      `json:"table_html,omitempty"`
+	Filename      string      `json:"filename,omitempty"`
+	Filetype      string      `json:"filetype,omitempty"`
+	PageNumber    int         `json:"page_number,omitempty"`
+	Coordinates   Coordinates `json:"coordinates,omitempty"`
+	TableHTML     string      `json:"table_html,omitempty"`
+	TextAsHTML    string      `json:"text_as_html,omitempty"`
+	ImageBase64   string      `json:"image_base64,omitempty"`
+	ImageMimeType string      `json:"image_mime_type,omitempty"`
+}
+
+// Element types emitted by Unstructured for tables and figures
+const (
+	ElementTypeTable      = "Table"
+	ElementTypeTableChunk = "TableChunk"
+	ElementTypeImage      = "Image"
+)
+
+// IsImage reports whether the element is an image with its content extracted
+func (e UnstructuredElement) IsImage() bool {
+	return e.Type == ElementTypeImage && e.Metadata.ImageBase64 != ""
+}
+
+// ImageBytes decodes the extracted image content of an image element
+func (e UnstructuredElement) ImageBytes() ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(e.Metadata.ImageBase64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+	return data, nil
+}
+
+// IsTable reports whether the element is a table with a usable HTML representation
+func (e UnstructuredElement) IsTable() bool {
+	if e.Type != ElementTypeTable && e.Type != ElementTypeTableChunk {
+		return false
+	}
+	return e.TableHTML() != ""
+}
+
+// TableHTML returns the HTML representation of a table element
+func (e UnstructuredElement) TableHTML() string {
+	if e.Metadata.TextAsHTML != "" {
+		return e.Metadata.TextAsHTML
+	}
+	return e.Metadata.TableHTML
 }
 
 type Coordinates struct {
@@ -40,7 +81,43 @@ func NewUnstructuredService(baseURL string) *UnstructuredService {
 	}
 }
 
+type formField struct {
+	name  string
+	value string
+}
+
 func (s *UnstructuredService) ConvertPDFToText(filename string, content []byte) ([]UnstructuredElement, error) {
+	return s.partition(filename, content, []formField{
+		{name: "chunking_strategy", value: "by_title"},
+		{name: "max_characters", value: "5000"},
+		{name: "combine_under_n_chars", value: "3500"},
+		{name: "pdf_infer_table_structure", value: "true"},
+		{name: "output_format", value: "application/json"},
+	})
+}
+
+// ExtractImages partitions the PDF with the hi_res strategy and returns only the image elements,
+// each carrying its base64 encoded content in the metadata.
+func (s *UnstructuredService) ExtractImages(filename string, content []byte) ([]UnstructuredElement, error) {
+	elements, err := s.partition(filename, content, []formField{
+		{name: "strategy", value: "hi_res"},
+		{name: "extract_image_block_types", value: `["Image"]`},
+		{name: "output_format", value: "application/json"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var images []UnstructuredElement
+	for _, element := range elements {
+		if element.IsImage() {
+			images = append(images, element)
+		}
+	}
+	return images, nil
+}
+
+func (s *UnstructuredService) partition(filename string, content []byte, fields []formField) ([]UnstructuredElement, error) {
 	var requestBody bytes.Buffer
 	multipartWriter := multipart.NewWriter(&requestBody)
 
@@ -56,28 +133,14 @@ func (s *UnstructuredService) ConvertPDFToText(filename string, content []byte)
 	}
 
 	// Write additional fields
-	if err := multipartWriter.WriteField("chunking_strategy", "by_title"); err != nil {
-		log.Printf("Failed to write chunking strategy: %v", err)
-		return nil, fmt.Errorf("failed to write chunking strategy: %v", err)
-	}
-
-	if err := multipartWriter.WriteField("max_characters", "5000"); err != nil {
-		log.Printf("Failed to write max characters: %v", err)
-		return nil, fmt.Errorf("failed to write max characters: %v", err)
-	}
-
-	if err := multipartWriter.WriteField("combine_under_n_chars", "3500"); err != nil {
-		log.Printf("Failed to write output format: %v", err)
-		return nil, fmt.Errorf("failed to write output format: %v", err)
-	}
-
-	if err := multipartWriter.WriteField("output_format", "application/json"); err != nil {
-		log.Printf("Failed to write output format: %v", err)
-		return nil, fmt.Errorf("failed to write output format: %v", err)
+	for _, field := range fields {
+		if err := multipartWriter.WriteField(field.name, field.value); err != nil {
+			log.Printf("Failed to write %s: %v", field.name, err)
+			return nil, fmt.Errorf("failed to write %s: %v", field.name, err)
+		}
 	}
 
 	multipartWriter.Close()
-
 	// Create request
 	httpReq, err := http.NewRequest("POST", s.baseURL+"/general/v0/general", &requestBody)
 	if err != nil {
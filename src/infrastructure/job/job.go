@@ -14,8 +14,15 @@ const (
 	JobStatusRunning   JobStatus = "running"
 	JobStatusCompleted JobStatus = "completed"
 	JobStatusFailed    JobStatus = "failed"
+	// JobStatusCompletedWithErrors marks a job that finished but could not process every item, see PartialResult
+	JobStatusCompletedWithErrors JobStatus = "completed_with_errors"
 )
 
+// PartialResult is implemented by task results that can report errors of a job that still completed
+type PartialResult interface {
+	HasErrors() bool
+}
+
 // Job represents a background job
 type Job struct {
 	ID        int             `json:"id"`
@@ -23,6 +30,7 @@ type Job struct {
 	Payload   json.RawMessage `json:"payload"`
 	Status    JobStatus       `json:"status"`
 	Error     *string         `json:"error,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"` // task specific outcome, e.g. glossary violations of a translation
 	CreatedAt time.Time       `json:"created_at"`
 	UpdatedAt time.Time       `json:"updated_at"`
 }
@@ -32,4 +40,5 @@ type JobRepository interface {
 	Create(ctx context.Context, taskType string, payload json.RawMessage) (*Job, error)
 	Get(ctx context.Context, id int) (*Job, error)
 	UpdateStatus(ctx context.Context, id int, status JobStatus, err *string) error
+	UpdateResult(ctx context.Context, id int, result json.RawMessage) error
 }
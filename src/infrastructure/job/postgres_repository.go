@@ -60,3 +60,16 @@ func (r *PostgresJobRepository) UpdateStatus(ctx context.Context, id int, status
 
 	return nil
 }
+
+func (r *PostgresJobRepository) UpdateResult(ctx context.Context, id int, result json.RawMessage) error {
+	res := r.db.WithContext(ctx).Model(&Job{}).Where("id = ?", id).Update("result", result)
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return errors.New("job not found")
+	}
+
+	return nil
+}
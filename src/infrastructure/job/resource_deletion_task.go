@@ -0,0 +1,45 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"raggo/src/core/resourcedeletion"
+	"raggo/src/infrastructure/log"
+)
+
+const TaskTypeResourceDeletion = "resource_deletion"
+
+type ResourceDeletionPayload struct {
+	ResourceID int64 `json:"resource_id"`
+}
+
+type ResourceDeletionTask struct {
+	resourceDeletionService *resourcedeletion.Service
+}
+
+func NewResourceDeletionTask(resourceDeletionService *resourcedeletion.Service) *ResourceDeletionTask {
+	return &ResourceDeletionTask{
+		resourceDeletionService: resourceDeletionService,
+	}
+}
+
+// HandleResourceDeletionTask deletes the resource and returns the executed deletion plan
+func (task *ResourceDeletionTask) HandleResourceDeletionTask(ctx context.Context, payload json.RawMessage) (*resourcedeletion.Plan, error) {
+	var deletionPayload ResourceDeletionPayload
+	if err := json.Unmarshal(payload, &deletionPayload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal resource deletion payload: %w", err)
+	}
+
+	plan, err := task.resourceDeletionService.Delete(ctx, deletionPayload.ResourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete resource: %w", err)
+	}
+	if plan == nil {
+		// Already deleted, e.g. by a previous attempt of this job
+		log.Info("Resource to delete not found", "resource_id", deletionPayload.ResourceID)
+	}
+
+	return plan, nil
+}
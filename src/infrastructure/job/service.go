@@ -3,6 +3,7 @@ package job
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/ThreeDotsLabs/watermill"
@@ -14,8 +15,12 @@ type JobService struct {
 	repo            JobRepository
 	logger          watermill.LoggerAdapter
 	translationTask *TranslationTask
+	deletionTask    *ResourceDeletionTask
 }
 
+// ErrNotRetryable is returned when retrying a job that is not a finished translation job with failed chunks
+var ErrNotRetryable = errors.New("job cannot be retried")
+
 type JobMessage struct {
 	JobID    int             `json:"job_id"`
 	TaskType string          `json:"task_type"`
@@ -31,12 +36,14 @@ func NewJobService(
 	repo JobRepository,
 	logger watermill.LoggerAdapter,
 	translator *TranslationTask,
+	deletionTask *ResourceDeletionTask,
 ) *JobService {
 	return &JobService{
 		publisher:       publisher,
 		repo:            repo,
 		logger:          logger,
 		translationTask: translator,
+		deletionTask:    deletionTask,
 	}
 }
 
@@ -93,7 +100,18 @@ func (s *JobService) ProcessJobMessage(msg *message.Message) error {
 	}
 
 	// Process the job based on task type
-	err = s.processJob(ctx, job)
+	result, err := s.processJob(ctx, job)
+
+	// Store the outcome of the task, which also tells what went wrong if the task failed
+	if result != nil {
+		resultBytes, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			return fmt.Errorf("failed to marshal job result: %w", marshalErr)
+		}
+		if updateErr := s.repo.UpdateResult(ctx, job.ID, resultBytes); updateErr != nil {
+			return fmt.Errorf("failed to update job result: %w", updateErr)
+		}
+	}
 
 	if err != nil {
 		// Update status to failed
@@ -106,30 +124,80 @@ func (s *JobService) ProcessJobMessage(msg *message.Message) error {
 		return fmt.Errorf("failed to process job: %w", err)
 	}
 
-	// Update status to completed
-	if err := s.repo.UpdateStatus(ctx, job.ID, JobStatusCompleted, nil); err != nil {
-		return fmt.Errorf("failed to update job status to completed: %w", err)
+	// Update status to completed, or completed with errors if the task skipped some of its work
+	status := JobStatusCompleted
+	if partial, ok := result.(PartialResult); ok && partial.HasErrors() {
+		status = JobStatusCompletedWithErrors
+	}
+	if err := s.repo.UpdateStatus(ctx, job.ID, status, nil); err != nil {
+		return fmt.Errorf("failed to update job status to %s: %w", status, err)
 	}
 
 	return nil
 }
 
-// processJob handles different types of jobs
-func (s *JobService) processJob(ctx context.Context, job *Job) error {
+// GetJob returns the job with its status and result, or nil if it does not exist
+func (s *JobService) GetJob(ctx context.Context, id int) (*Job, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// RetryFailedChunks enqueues a new translation job with the payload of the given one. Chunks the earlier job
+// translated are checkpoints that are kept, so only the chunks that failed are translated again.
+// It returns nil if the job does not exist.
+func (s *JobService) RetryFailedChunks(ctx context.Context, id int) (*Job, error) {
+	job, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	if job == nil {
+		return nil, nil
+	}
+	if job.TaskType != TaskTypeTranslation {
+		return nil, fmt.Errorf("%w: job %d is a %s job", ErrNotRetryable, id, job.TaskType)
+	}
+	if job.Status != JobStatusCompletedWithErrors && job.Status != JobStatusFailed {
+		return nil, fmt.Errorf("%w: job %d is %s", ErrNotRetryable, id, job.Status)
+	}
+
+	var payload TranslationPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal translation payload: %w", err)
+	}
+	payload.Restart = false
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal translation payload: %w", err)
+	}
+	return s.EnqueueJob(ctx, TaskTypeTranslation, payloadBytes)
+}
+
+// processJob handles different types of jobs and returns the result to store with the job
+func (s *JobService) processJob(ctx context.Context, job *Job) (any, error) {
 	switch job.TaskType {
 	case "test":
 		var payload TestPayload
 		if err := json.Unmarshal(job.Payload, &payload); err != nil {
-			return fmt.Errorf("failed to unmarshal test payload: %w", err)
+			return nil, fmt.Errorf("failed to unmarshal test payload: %w", err)
 		}
 		s.logger.Info("Test job executed", watermill.LogFields{
 			"job_id": job.ID,
 			"print":  payload.Print,
 		})
-		return nil
+		return nil, nil
 	case TaskTypeTranslation:
-		return s.translationTask.HandleTranslationTask(ctx, job.Payload)
+		result, err := s.translationTask.HandleTranslationTask(ctx, job.Payload)
+		if result == nil {
+			return nil, err
+		}
+		return result, err
+	case TaskTypeResourceDeletion:
+		plan, err := s.deletionTask.HandleResourceDeletionTask(ctx, job.Payload)
+		if err != nil || plan == nil {
+			return nil, err
+		}
+		return plan, nil
 	default:
-		return fmt.Errorf("unknown task type: %s", job.TaskType)
+		return nil, fmt.Errorf("unknown task type: %s", job.TaskType)
 	}
 }
@@ -7,43 +7,180 @@ import (
 	"strconv"
 	"strings"
 
+	"golang.org/x/sync/errgroup"
+
+	"raggo/src/core/document"
+	"raggo/src/core/languagedetect"
 	"raggo/src/core/translationflow"
+	"raggo/src/core/translationmemory"
 	"raggo/src/infrastructure/integrations/ollama"
 	"raggo/src/infrastructure/log"
 	"raggo/src/storage/minioctrl"
 	"raggo/src/storage/postgres/chunkctrl"
+	"raggo/src/storage/postgres/glossaryctrl"
 	"raggo/src/storage/postgres/resourcectrl"
 	"raggo/src/storage/postgres/translatedchunkctrl"
 	"raggo/src/storage/postgres/translatedresourcectrl"
+	"raggo/src/storage/postgres/translationprofilectrl"
 )
 
 const TaskTypeTranslation = "translation"
 
+// Failure policies decide what happens to chunks that cannot be translated
+const (
+	FailurePolicyFail       = "fail"        // fail the job; a retry resumes with the failed chunks
+	FailurePolicySkip       = "skip"        // leave the chunks out of the translated document
+	FailurePolicyKeepSource = "keep_source" // put the source text of the chunks into the translated document
+)
+
 type TranslationPayload struct {
+	// SourceLanguage is languagedetect.Auto to detect the language of every chunk
 	SourceLanguage   string `json:"source_language"`
 	TargetLanguage   string `json:"target_language"`
 	Country          string `json:"country"`
 	TargetResourceID string `json:"target_resource_id"`
 	UseService       string `json:"use_service"`
 	UseModel         string `json:"use_model"`
+	GlossaryID       string `json:"glossary_id,omitempty"`
+	// Pipeline defines the translation stages; if empty, the named Profile or else the default pipeline is used
+	Profile  string                          `json:"profile,omitempty"`
+	Pipeline *translationflow.PipelineConfig `json:"pipeline,omitempty"`
+	// Restart purges the earlier translation into the same language and country by the same model instead of resuming it
+	Restart bool `json:"restart,omitempty"`
+	// FailurePolicy is one of the FailurePolicy constants, FailurePolicySkip if empty
+	FailurePolicy string `json:"failure_policy,omitempty"`
+}
+
+// TranslationResult is stored as the result of a translation job
+type TranslationResult struct {
+	TranslatedResourceID int64                    `json:"translated_resource_id"`
+	GlossaryViolations   []ChunkGlossaryViolation `json:"glossary_violations,omitempty"`
+	MemoryHits           int                      `json:"memory_hits"`    // chunks reused from the translation memory without calling the model
+	ResumedChunks        int                      `json:"resumed_chunks"` // chunks translated by an earlier attempt of the job
+	FailedChunks         []FailedChunk            `json:"failed_chunks,omitempty"`
+	FlaggedChunks        []FlaggedChunk           `json:"flagged_chunks,omitempty"` // chunks whose estimated quality needs a human review
+	// With an automatic source language, the number of chunks per detected language
+	// and the chunks already in the target language, which are kept as they are
+	DetectedLanguages  map[string]int `json:"detected_languages,omitempty"`
+	SameLanguageChunks int            `json:"same_language_chunks,omitempty"`
+}
+
+// HasErrors reports whether chunks could not be translated
+func (r *TranslationResult) HasErrors() bool {
+	return len(r.FailedChunks) > 0
+}
+
+// FailedChunk is a chunk that could not be translated
+type FailedChunk struct {
+	ChunkID string `json:"chunk_id"`
+	Error   string `json:"error"`
+}
+
+// FlaggedChunk is a chunk whose translation scored below the quality threshold
+type FlaggedChunk struct {
+	ChunkID      string  `json:"chunk_id"`
+	QualityScore float64 `json:"quality_score"`
+}
+
+// QualityEstimationConfig controls the quality estimation of newly translated chunks
+type QualityEstimationConfig struct {
+	Enabled        bool
+	Threshold      float64 // chunks scoring below are flagged for review
+	EmbeddingModel string  // model comparing source and back-translation, empty to rely on the judge alone
+}
+
+// ChunkGlossaryViolation is a glossary rule that the translation of a chunk does not follow
+type ChunkGlossaryViolation struct {
+	ChunkID string `json:"chunk_id"`
+	translationflow.Violation
+}
+
+// ResourceService is the part of resourcectrl.ResourceService a translation task uses
+type ResourceService interface {
+	GetByID(ctx context.Context, id int64) (*resourcectrl.Resource, error)
+}
+
+// ChunkService is the part of chunkctrl.ChunkService a translation task uses
+type ChunkService interface {
+	GetByResourceID(ctx context.Context, resourceID int64) ([]chunkctrl.Chunk, error)
+	UpdateLanguage(ctx context.Context, id int64, language string, confidence float64) error
+}
+
+// TranslatedResourceService is the part of translatedresourcectrl.TranslatedResourceService a translation task uses
+type TranslatedResourceService interface {
+	Create(ctx context.Context, originalResourceID int64, filename, minioURL, sourceLang, targetLang, country, model string) (*translatedresourcectrl.TranslatedResource, error)
+	FindVariant(ctx context.Context, originalID int64, targetLang, country, model string) (*translatedresourcectrl.TranslatedResource, error)
+	UpdateSourceLanguage(ctx context.Context, id int64, sourceLang string) error
+	UpdateSignatures(ctx context.Context, id int64, pipeline, glossary, styleGuide string) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// TranslatedChunkService is the part of translatedchunkctrl.TranslatedChunkService a translation task uses
+type TranslatedChunkService interface {
+	Create(ctx context.Context, translatedResourceID, originalChunkID int64, chunkID, minioURL, sourceLanguage string) (*translatedchunkctrl.TranslatedChunk, error)
+	GetByTranslatedResourceID(ctx context.Context, translatedResourceID int64) ([]translatedchunkctrl.TranslatedChunk, error)
+	UpdateQuality(ctx context.Context, id int64, estimate *translationflow.QualityEstimate, flagged bool) error
+	Delete(ctx context.Context, id int64) error
+	DeleteByTranslatedResourceID(ctx context.Context, translatedResourceID int64) error
+}
+
+// ObjectStorage is the part of minioctrl.MinioService a translation task uses
+type ObjectStorage interface {
+	EnsureBucketExists(ctx context.Context, bucketName string) error
+	GetObject(ctx context.Context, bucketName, objectName string) ([]byte, error)
+	PutObject(ctx context.Context, bucketName, objectName string, data []byte) error
+	DeleteObject(ctx context.Context, bucketName, objectName string) error
+	GetBucketAndObjectFromURL(minioURL string) (string, string)
+}
+
+// GlossaryService is the part of glossaryctrl.GlossaryService a translation task uses
+type GlossaryService interface {
+	GetByID(ctx context.Context, id int64) (*glossaryctrl.Glossary, error)
+	Load(ctx context.Context, glossaryID int64) (*translationflow.Glossary, error)
+}
+
+// StyleGuideService is the part of styleguidectrl.StyleGuideService a translation task uses
+type StyleGuideService interface {
+	Load(ctx context.Context, targetLanguage, country string) (*translationflow.StyleGuide, error)
+}
+
+// TranslationProfileService is the part of translationprofilectrl.TranslationProfileService a translation task uses
+type TranslationProfileService interface {
+	GetByName(ctx context.Context, name string) (*translationprofilectrl.TranslationProfile, error)
 }
 
 type TranslationTask struct {
-	resourceService       *resourcectrl.ResourceService
-	chunkService          *chunkctrl.ChunkService
-	translatedResourceSvc *translatedresourcectrl.TranslatedResourceService
-	translatedChunkSvc    *translatedchunkctrl.TranslatedChunkService
-	minioService          *minioctrl.MinioService
+	resourceService       ResourceService
+	chunkService          ChunkService
+	translatedResourceSvc TranslatedResourceService
+	translatedChunkSvc    TranslatedChunkService
+	minioService          ObjectStorage
 	ollamaClient          *ollama.Client
+	glossaryService       GlossaryService
+	translationMemory     *translationmemory.Service
+	styleGuideService     StyleGuideService
+	profileService        TranslationProfileService
+	templateOverrides     map[string]string // overrides of every pipeline, e.g. loaded from a template directory
+	ollamaLimiter         *translationflow.Limiter
+	chunkConcurrency      int
+	quality               QualityEstimationConfig
 }
 
 func NewTranslationTask(
-	resourceService *resourcectrl.ResourceService,
-	chunkService *chunkctrl.ChunkService,
-	translatedResourceSvc *translatedresourcectrl.TranslatedResourceService,
-	translatedChunkSvc *translatedchunkctrl.TranslatedChunkService,
-	minioService *minioctrl.MinioService,
+	resourceService ResourceService,
+	chunkService ChunkService,
+	translatedResourceSvc TranslatedResourceService,
+	translatedChunkSvc TranslatedChunkService,
+	minioService ObjectStorage,
 	ollamaClient *ollama.Client,
+	glossaryService GlossaryService,
+	translationMemory *translationmemory.Service,
+	styleGuideService StyleGuideService,
+	profileService TranslationProfileService,
+	templateOverrides map[string]string,
+	ollamaLimiter *translationflow.Limiter,
+	chunkConcurrency int,
+	quality QualityEstimationConfig,
 ) *TranslationTask {
 	return &TranslationTask{
 		resourceService:       resourceService,
@@ -52,125 +189,550 @@ func NewTranslationTask(
 		translatedChunkSvc:    translatedChunkSvc,
 		minioService:          minioService,
 		ollamaClient:          ollamaClient,
+		glossaryService:       glossaryService,
+		translationMemory:     translationMemory,
+		styleGuideService:     styleGuideService,
+		profileService:        profileService,
+		templateOverrides:     templateOverrides,
+		ollamaLimiter:         ollamaLimiter,
+		chunkConcurrency:      max(chunkConcurrency, 1),
+		quality:               quality,
 	}
 }
 
-func (task *TranslationTask) HandleTranslationTask(ctx context.Context, payload json.RawMessage) error {
+func (task *TranslationTask) HandleTranslationTask(ctx context.Context, payload json.RawMessage) (*TranslationResult, error) {
 	// decode payload
 	var translationPayload TranslationPayload
 	if err := json.Unmarshal(payload, &translationPayload); err != nil {
-		return fmt.Errorf("failed to unmarshal translation payload: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal translation payload: %w", err)
+	}
+
+	// default and validate failure policy
+	switch translationPayload.FailurePolicy {
+	case "":
+		translationPayload.FailurePolicy = FailurePolicySkip
+	case FailurePolicyFail, FailurePolicySkip, FailurePolicyKeepSource:
+	default:
+		return nil, fmt.Errorf("unknown failure policy: %s", translationPayload.FailurePolicy)
 	}
 
 	// find resource
 	resourceID, err := strconv.ParseInt(translationPayload.TargetResourceID, 10, 64)
 	if err != nil {
-		return fmt.Errorf("invalid resource ID: %w", err)
+		return nil, fmt.Errorf("invalid resource ID: %w", err)
 	}
 	resource, err := task.resourceService.GetByID(ctx, resourceID)
 	if err != nil {
-		return fmt.Errorf("failed to get resource: %w", err)
+		return nil, fmt.Errorf("failed to get resource: %w", err)
 	}
 	if resource == nil {
-		return fmt.Errorf("resource not found: %s", translationPayload.TargetResourceID)
+		return nil, fmt.Errorf("resource not found: %s", translationPayload.TargetResourceID)
+	}
+
+	// Load the glossary the translation has to follow
+	glossary, err := task.loadGlossary(ctx, translationPayload.GlossaryID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the style guide of the target locale
+	styleGuide, err := task.styleGuideService.Load(ctx, translationPayload.TargetLanguage, translationPayload.Country)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the pipeline and compile its templates before anything is changed
+	pipeline, err := task.loadPipeline(ctx, translationPayload)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := translationflow.NewTemplates(mergeTemplates(task.templateOverrides, pipeline.Templates))
+	if err != nil {
+		return nil, fmt.Errorf("invalid translation templates: %w", err)
 	}
 
 	// Ensure minio buckets exist
 	if err := task.minioService.EnsureBucketExists(ctx, minioctrl.TranslatedResourcesBucket); err != nil {
-		return fmt.Errorf("failed to ensure translated resources bucket exists: %w", err)
+		return nil, fmt.Errorf("failed to ensure translated resources bucket exists: %w", err)
 	}
 	if err := task.minioService.EnsureBucketExists(ctx, minioctrl.TranslatedChunksBucket); err != nil {
-		return fmt.Errorf("failed to ensure translated chunks bucket exists: %w", err)
+		return nil, fmt.Errorf("failed to ensure translated chunks bucket exists: %w", err)
+	}
+
+	// Resume the translation of an earlier attempt unless a fresh start is requested.
+	// Translations into other languages, countries or by other models are kept side by side.
+	variant := variantName(translationPayload.TargetLanguage, translationPayload.Country, translationPayload.UseModel)
+	translatedResource, err := task.translatedResourceSvc.FindVariant(ctx, resource.ID, translationPayload.TargetLanguage, translationPayload.Country, translationPayload.UseModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing translation: %w", err)
+	}
+	if translatedResource != nil && translationPayload.Restart {
+		if err := task.purgeTranslation(ctx, *translatedResource); err != nil {
+			return nil, fmt.Errorf("failed to purge existing translation: %w", err)
+		}
+		translatedResource = nil
+	}
+	if translatedResource == nil {
+		translatedResource, err = task.translatedResourceSvc.Create(
+			ctx,
+			resource.ID,
+			fmt.Sprintf("%s_translated_%s", resource.Filename, variant),
+			fmt.Sprintf("%s/%s_translated_%s", minioctrl.TranslatedResourcesBucket, resource.MinioURL, variant),
+			translationPayload.SourceLanguage,
+			translationPayload.TargetLanguage,
+			translationPayload.Country,
+			translationPayload.UseModel,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create translated resource: %w", err)
+		}
 	}
 
-	// Clean up existing translations
-	if err := task.cleanupExistingTranslations(ctx, resourceID); err != nil {
-		return fmt.Errorf("failed to cleanup existing translations: %w", err)
+	// Checkpoints of an earlier attempt with another pipeline, glossary or style guide are not mixed with this run
+	signaturesChanged := translatedResource.PipelineSignature != pipeline.Signature() ||
+		translatedResource.GlossarySignature != glossary.Signature() ||
+		translatedResource.StyleGuideSignature != styleGuide.Signature()
+
+	// Record how this run translates, so that reviews find its translations in the translation memory
+	translatedResource.PipelineSignature = pipeline.Signature()
+	translatedResource.GlossarySignature = glossary.Signature()
+	translatedResource.StyleGuideSignature = styleGuide.Signature()
+	if err := task.translatedResourceSvc.UpdateSignatures(ctx, translatedResource.ID, translatedResource.PipelineSignature, translatedResource.GlossarySignature, translatedResource.StyleGuideSignature); err != nil {
+		return nil, err
 	}
 
-	// Create translated resource
-	translatedResource, err := task.translatedResourceSvc.Create(
-		ctx,
-		resource.ID,
-		fmt.Sprintf("%s_translated_%s", resource.Filename, translationPayload.TargetLanguage),
-		fmt.Sprintf("%s/%s_translated_%s", minioctrl.TranslatedResourcesBucket, resource.MinioURL, translationPayload.TargetLanguage),
-		translationPayload.SourceLanguage,
-		translationPayload.TargetLanguage,
-		translationPayload.Country,
-	)
+	// Chunks translated by an earlier attempt are checkpoints that are not translated again
+	translatedChunks, err := task.translatedChunkSvc.GetByTranslatedResourceID(ctx, translatedResource.ID)
 	if err != nil {
-		return fmt.Errorf("failed to create translated resource: %w", err)
+		return nil, fmt.Errorf("failed to get translated chunks: %w", err)
+	}
+	if signaturesChanged && len(translatedChunks) > 0 {
+		log.Info("Discarding checkpoints translated with other signatures", "translated_resource_id", translatedResource.ID, "chunks", len(translatedChunks))
+	}
+	checkpoints := make(map[int64]translatedchunkctrl.TranslatedChunk, len(translatedChunks))
+	for _, tc := range translatedChunks {
+		if signaturesChanged || tc.IsRejected() {
+			// The chunk was translated another way or a reviewer rejected the translation, so it is translated again
+			if err := task.discardTranslatedChunk(ctx, tc); err != nil {
+				return nil, fmt.Errorf("failed to discard rejected translated chunk: %w", err)
+			}
+			continue
+		}
+		checkpoints[tc.OriginalChunkID] = tc
 	}
 
 	// find chunks
 	chunks, err := task.chunkService.GetByResourceID(ctx, resource.ID)
 	if err != nil {
-		return fmt.Errorf("failed to get chunks: %w", err)
+		return nil, fmt.Errorf("failed to get chunks: %w", err)
 	}
 
-	// Create translation flow with ollama provider
-	provider := ollama.NewOllamaProvider(task.ollamaClient, translationPayload.UseModel)
-	translationFlow := translationflow.NewTranslationFlow(provider)
-
-	// Translate each chunk and collect translations
-	var allTranslations []string
-	for _, chunk := range chunks {
-		// Get chunk content from MinioURL
-		bucket, objectName := task.minioService.GetBucketAndObjectFromURL(chunk.MinioURL)
-		chunkContent, err := task.minioService.GetObject(ctx, bucket, objectName)
+	// Detect the language of every chunk if the source language is left to detection
+	var sourceLanguages map[int64]string
+	if translationPayload.SourceLanguage == languagedetect.Auto {
+		var dominant string
+		sourceLanguages, dominant, err = task.detectLanguages(ctx, chunks)
 		if err != nil {
-			return fmt.Errorf("failed to get chunk content: %w", err)
+			return nil, err
+		}
+		if dominant != "" {
+			if err := task.translatedResourceSvc.UpdateSourceLanguage(ctx, translatedResource.ID, dominant); err != nil {
+				return nil, err
+			}
 		}
+	}
 
-		// Try to translate chunk with retries
-		translatedContent, err := task.translateChunkWithRetry(
-			ctx,
-			translationFlow,
-			chunk,
-			string(chunkContent),
-			translationPayload.SourceLanguage,
-			translationPayload.TargetLanguage,
-			translationPayload.Country,
-		)
-		if err != nil {
-			// Log warning and continue with other chunks
+	// Create translation flow with ollama provider, using the model of the pipeline for each stage
+	provider := ollama.NewOllamaProvider(task.ollamaClient, translationPayload.UseModel)
+	flowOptions := []translationflow.Option{
+		translationflow.WithGlossary(glossary),
+		translationflow.WithStyleGuide(styleGuide),
+		translationflow.WithTemplates(templates),
+		translationflow.WithSkipReflection(pipeline.SkipReflection),
+		translationflow.WithLimiter(task.ollamaLimiter),
+		translationflow.WithConcurrency(task.chunkConcurrency),
+	}
+	if pipeline.ReviewRounds > 0 {
+		flowOptions = append(flowOptions, translationflow.WithReviewRounds(pipeline.ReviewRounds))
+	}
+	for stage, model := range pipeline.Models {
+		flowOptions = append(flowOptions, translationflow.WithStageProvider(stage, ollama.NewOllamaProvider(task.ollamaClient, model)))
+	}
+	if task.quality.Enabled && task.quality.EmbeddingModel != "" {
+		flowOptions = append(flowOptions, translationflow.WithEmbedder(ollama.NewOllamaEmbedder(task.ollamaClient, task.quality.EmbeddingModel)))
+	}
+	translationFlow := translationflow.NewTranslationFlow(provider, flowOptions...)
+
+	result := &TranslationResult{TranslatedResourceID: translatedResource.ID}
+	if sourceLanguages != nil {
+		result.DetectedLanguages = make(map[string]int)
+		for _, language := range sourceLanguages {
+			result.DetectedLanguages[language]++
+		}
+	}
+	// Only translations made the same way are reused: drafts are kept apart from fully reviewed translations,
+	// and translations that followed another glossary or style guide from those following these
+	memoryKey := translatedResource.MemoryKey(translationPayload.SourceLanguage)
+
+	// Translate the chunks concurrently, each into its own slot so that they are collected in document order
+	translations := make([]chunkTranslation, len(chunks))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(task.chunkConcurrency)
+	for i, chunk := range chunks {
+		g.Go(func() error {
+			// Get chunk content from MinioURL
+			bucket, objectName := task.minioService.GetBucketAndObjectFromURL(chunk.MinioURL)
+			chunkContent, err := task.minioService.GetObject(gctx, bucket, objectName)
+			if err != nil {
+				return fmt.Errorf("failed to get chunk content: %w", err)
+			}
+
+			// Translate from the detected language of the chunk
+			key := memoryKey
+			sameLanguage := false
+			if sourceLanguages != nil {
+				key.SourceLanguage = sourceLanguages[chunk.ID]
+				sameLanguage = languagedetect.Same(key.SourceLanguage, translationPayload.TargetLanguage)
+			}
+
+			// Resume from the checkpoint of a chunk that was already translated
+			if checkpoint, ok := checkpoints[chunk.ID]; ok {
+				translatedBucket, translatedObjectName := task.minioService.GetBucketAndObjectFromURL(checkpoint.FinalMinioURL())
+				translatedContent, err := task.minioService.GetObject(gctx, translatedBucket, translatedObjectName)
+				if err != nil {
+					return fmt.Errorf("failed to get translated chunk content: %w", err)
+				}
+				translations[i] = chunkTranslation{
+					source:       string(chunkContent),
+					translated:   string(translatedContent),
+					resumed:      true,
+					sameLanguage: sameLanguage,
+				}
+				return nil
+			}
+
+			// A chunk already in the target language is kept as it is
+			if sameLanguage {
+				if _, err := task.saveTranslatedChunk(gctx, translatedResource.ID, chunk, objectName, variant, key.SourceLanguage, string(chunkContent)); err != nil {
+					return err
+				}
+				translations[i] = chunkTranslation{
+					source:       string(chunkContent),
+					translated:   string(chunkContent),
+					sameLanguage: true,
+				}
+				return nil
+			}
+
+			// Reuse an identical translation from the translation memory, or translate with similar ones as references
+			translatedContent, fromMemory, err := task.translateChunk(gctx, translationFlow, chunk, string(chunkContent), key)
+			translations[i] = chunkTranslation{
+				source:     string(chunkContent),
+				translated: translatedContent,
+				fromMemory: fromMemory,
+				err:        err,
+			}
+			if err != nil {
+				return nil
+			}
+
+			// Checkpoint the chunk right away, so a retried job does not translate it again
+			translatedChunk, err := task.saveTranslatedChunk(gctx, translatedResource.ID, chunk, objectName, variant, key.SourceLanguage, translatedContent)
+			if err != nil {
+				return err
+			}
+
+			// Score fresh translations; those from the translation memory were scored when they were made
+			if !fromMemory {
+				translations[i].quality = task.estimateQuality(gctx, translationFlow, chunk, translatedChunk.ID, string(chunkContent), translatedContent, key)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	// Collect the translations in document order
+	var parts []document.Part
+	for i, chunk := range chunks {
+		translation := translations[i]
+		if translation.err != nil {
+			// Record the failure and continue with other chunks
 			log.Info("Failed to translate chunk after retries",
 				"chunk_id", chunk.ChunkID,
-				"error", err.Error())
+				"error", translation.err.Error())
+			result.FailedChunks = append(result.FailedChunks, FailedChunk{
+				ChunkID: chunk.ChunkID,
+				Error:   translation.err.Error(),
+			})
+			if translationPayload.FailurePolicy == FailurePolicyKeepSource {
+				parts = append(parts, document.Part{Kind: chunk.Kind, Content: translation.source})
+			}
 			continue
 		}
-
-		// Save translated chunk to minio
-		translatedObjectName := fmt.Sprintf("%s_translated_%s", objectName, translationPayload.TargetLanguage)
-		if err := task.minioService.PutObject(ctx, minioctrl.TranslatedChunksBucket, translatedObjectName, []byte(translatedContent)); err != nil {
-			return fmt.Errorf("failed to save translated chunk content: %w", err)
+		if translation.fromMemory {
+			result.MemoryHits++
+		}
+		if translation.resumed {
+			result.ResumedChunks++
+		}
+		if translation.sameLanguage {
+			// Nothing was translated, so there is nothing to score or verify
+			result.SameLanguageChunks++
+			parts = append(parts, document.Part{Kind: chunk.Kind, Content: translation.translated})
+			continue
+		}
+		if translation.quality != nil && translation.quality.Score < task.quality.Threshold {
+			result.FlaggedChunks = append(result.FlaggedChunks, FlaggedChunk{
+				ChunkID:      chunk.ChunkID,
+				QualityScore: translation.quality.Score,
+			})
 		}
 
-		// Create translated chunk record
-		translatedMinioURL := fmt.Sprintf("%s/%s", minioctrl.TranslatedChunksBucket, translatedObjectName)
-		_, err = task.translatedChunkSvc.Create(
-			ctx,
-			translatedResource.ID,
-			chunk.ID,
-			chunk.ChunkID+"_translated",
-			translatedMinioURL,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to save translated chunk record: %w", err)
+		// Report terminology that does not follow the glossary
+		for _, violation := range glossary.Verify(translation.source, translation.translated) {
+			result.GlossaryViolations = append(result.GlossaryViolations, ChunkGlossaryViolation{
+				ChunkID:   chunk.ChunkID,
+				Violation: violation,
+			})
 		}
 
 		// Collect translation for complete document
-		allTranslations = append(allTranslations, translatedContent)
+		parts = append(parts, document.Part{Kind: chunk.Kind, Content: translation.translated})
+	}
+
+	// The translated chunks are checkpoints, so a retry of the failed job only translates the failed chunks
+	if len(result.FailedChunks) > 0 && translationPayload.FailurePolicy == FailurePolicyFail {
+		return result, fmt.Errorf("failed to translate %d of %d chunks", len(result.FailedChunks), len(chunks))
 	}
 
-	// Combine all translations and save to translated resource
-	completeTranslation := strings.Join(allTranslations, "\n")
+	// Combine all translations into a Markdown document, keeping the chunks as separate blocks
+	_, completeTranslation := document.Assemble(parts)
 	bucket, objectName := task.minioService.GetBucketAndObjectFromURL(translatedResource.MinioURL)
 	if err := task.minioService.PutObject(ctx, bucket, objectName, []byte(completeTranslation)); err != nil {
-		return fmt.Errorf("failed to save complete translated content: %w", err)
+		return nil, fmt.Errorf("failed to save complete translated content: %w", err)
 	}
 
-	return nil
+	return result, nil
+}
+
+// loadGlossary returns the glossary with the given ID, or nil if no glossary is requested
+func (task *TranslationTask) loadGlossary(ctx context.Context, glossaryID string) (*translationflow.Glossary, error) {
+	if glossaryID == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.ParseInt(glossaryID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glossary ID: %w", err)
+	}
+	glossary, err := task.glossaryService.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get glossary: %w", err)
+	}
+	if glossary == nil {
+		return nil, fmt.Errorf("glossary not found: %s", glossaryID)
+	}
+
+	return task.glossaryService.Load(ctx, id)
+}
+
+// variantName names the objects of a translation variant, e.g. "ja_JP_llama3.3" for the Japanese translation by llama3.3
+func variantName(targetLanguage, country, model string) string {
+	parts := []string{targetLanguage}
+	if country != "" {
+		parts = append(parts, country)
+	}
+	parts = append(parts, model)
+
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, strings.Join(parts, "_"))
+}
+
+// chunkTranslation is the outcome of translating one chunk
+type chunkTranslation struct {
+	source     string
+	translated string
+	fromMemory bool
+	resumed    bool // the chunk was translated by an earlier attempt of the job
+	// sameLanguage reports that the chunk is already in the target language and was kept as it is
+	sameLanguage bool
+	quality      *translationflow.QualityEstimate // nil if the quality was not estimated
+	err          error                            // the chunk could not be translated and is skipped
+}
+
+// detectLanguages detects and records the language of every chunk. Chunks whose language cannot be determined,
+// e.g. because they are too short, are taken to be in the dominant language of the resource.
+// It returns the source language per chunk ID and the dominant language, empty if no language was determined.
+func (task *TranslationTask) detectLanguages(ctx context.Context, chunks []chunkctrl.Chunk) (map[int64]string, string, error) {
+	detections := make([]languagedetect.Detection, len(chunks))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(task.chunkConcurrency)
+	for i, chunk := range chunks {
+		g.Go(func() error {
+			bucket, objectName := task.minioService.GetBucketAndObjectFromURL(chunk.MinioURL)
+			content, err := task.minioService.GetObject(gctx, bucket, objectName)
+			if err != nil {
+				return fmt.Errorf("failed to get chunk content: %w", err)
+			}
+
+			detections[i] = languagedetect.Detect(string(content))
+			if err := task.chunkService.UpdateLanguage(gctx, chunk.ID, detections[i].Language, detections[i].Confidence); err != nil {
+				return err
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, "", err
+	}
+
+	dominant := languagedetect.Dominant(detections)
+	languages := make(map[int64]string, len(chunks))
+	for i, chunk := range chunks {
+		switch {
+		case detections[i].Language != "":
+			languages[chunk.ID] = detections[i].Language
+		case dominant != "":
+			languages[chunk.ID] = dominant
+		default:
+			// Nothing could be detected, so the model is left to recognize the language itself
+			languages[chunk.ID] = languagedetect.Auto
+		}
+	}
+
+	log.Info("Detected chunk languages", "chunks", len(chunks), "dominant", dominant)
+	return languages, dominant, nil
+}
+
+// saveTranslatedChunk stores the translation of the chunk from the source language and records it as done
+func (task *TranslationTask) saveTranslatedChunk(ctx context.Context, translatedResourceID int64, chunk chunkctrl.Chunk, objectName, variant, sourceLanguage, translatedContent string) (*translatedchunkctrl.TranslatedChunk, error) {
+	// Save translated chunk to minio
+	translatedObjectName := fmt.Sprintf("%s_translated_%s", objectName, variant)
+	if err := task.minioService.PutObject(ctx, minioctrl.TranslatedChunksBucket, translatedObjectName, []byte(translatedContent)); err != nil {
+		return nil, fmt.Errorf("failed to save translated chunk content: %w", err)
+	}
+
+	// Create translated chunk record
+	translatedMinioURL := fmt.Sprintf("%s/%s", minioctrl.TranslatedChunksBucket, translatedObjectName)
+	translatedChunk, err := task.translatedChunkSvc.Create(
+		ctx,
+		translatedResourceID,
+		chunk.ID,
+		chunk.ChunkID+"_translated",
+		translatedMinioURL,
+		sourceLanguage,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save translated chunk record: %w", err)
+	}
+	return translatedChunk, nil
+}
+
+// estimateQuality scores the translation of a chunk and flags it for review if it scores below the threshold.
+// Quality estimation is advisory, so failures are logged and leave the chunk unscored.
+func (task *TranslationTask) estimateQuality(
+	ctx context.Context,
+	translationFlow *translationflow.TranslationFlow,
+	chunk chunkctrl.Chunk,
+	translatedChunkID int64,
+	source, translation string,
+	key translationmemory.Key,
+) *translationflow.QualityEstimate {
+	if !task.quality.Enabled {
+		return nil
+	}
+
+	estimate, err := translationFlow.EstimateQuality(ctx, source, translation, key.SourceLanguage, key.TargetLanguage, key.Country)
+	if err != nil {
+		log.Info("Failed to estimate translation quality",
+			"chunk_id", chunk.ChunkID,
+			"error", err.Error())
+		return nil
+	}
+
+	if err := task.translatedChunkSvc.UpdateQuality(ctx, translatedChunkID, estimate, estimate.Score < task.quality.Threshold); err != nil {
+		log.Info("Failed to store translation quality",
+			"chunk_id", chunk.ChunkID,
+			"error", err.Error())
+	}
+	return estimate
+}
+
+// translateChunk returns the translation memory entry of the content if there is one.
+// Otherwise it translates the content with the most similar memory entries as references and stores the result.
+// The returned bool reports whether the translation came from the translation memory.
+func (task *TranslationTask) translateChunk(
+	ctx context.Context,
+	translationFlow *translationflow.TranslationFlow,
+	chunk chunkctrl.Chunk,
+	content string,
+	key translationmemory.Key,
+) (string, bool, error) {
+	entry, err := task.translationMemory.Lookup(ctx, key, content)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to look up translation memory: %w", err)
+	}
+	if entry != nil {
+		return entry.TargetText, true, nil
+	}
+
+	references, err := task.translationMemory.Suggest(ctx, key, content)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get translation memory suggestions: %w", err)
+	}
+
+	translatedContent, err := task.translateChunkWithRetry(ctx, translationFlow, chunk, content, key, references)
+	if err != nil {
+		return "", false, err
+	}
+
+	if err := task.translationMemory.Store(ctx, key, content, translatedContent); err != nil {
+		log.Info("Failed to store translation in translation memory",
+			"chunk_id", chunk.ChunkID,
+			"error", err.Error())
+	}
+
+	return translatedContent, false, nil
+}
+
+// loadPipeline returns the pipeline of the payload, the pipeline of its profile or the default pipeline
+func (task *TranslationTask) loadPipeline(ctx context.Context, payload TranslationPayload) (*translationflow.PipelineConfig, error) {
+	if payload.Pipeline != nil {
+		if err := payload.Pipeline.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid pipeline: %w", err)
+		}
+		return payload.Pipeline, nil
+	}
+	if payload.Profile == "" {
+		return &translationflow.PipelineConfig{}, nil
+	}
+
+	profile, err := task.profileService.GetByName(ctx, payload.Profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get translation profile: %w", err)
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("translation profile not found: %s", payload.Profile)
+	}
+	return &profile.Pipeline, nil
+}
+
+// mergeTemplates returns the base template overrides with the pipeline overrides applied on top
+func mergeTemplates(base, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	for name, text := range base {
+		merged[name] = text
+	}
+	for name, text := range overrides {
+		merged[name] = text
+	}
+	return merged
 }
 
 func (task *TranslationTask) translateChunkWithRetry(
@@ -178,20 +740,20 @@ func (task *TranslationTask) translateChunkWithRetry(
 	translationFlow *translationflow.TranslationFlow,
 	chunk chunkctrl.Chunk,
 	content string,
-	sourceLanguage string,
-	targetLanguage string,
-	country string,
+	key translationmemory.Key,
+	references []translationflow.Reference,
 ) (string, error) {
 	maxRetries := 3
 	var lastErr error
 
 	for i := 0; i < maxRetries; i++ {
-		translatedContent, err := translationFlow.Translate(
+		translatedContent, err := translationFlow.TranslateWithReferences(
 			ctx,
 			content,
-			sourceLanguage,
-			targetLanguage,
-			country,
+			key.SourceLanguage,
+			key.TargetLanguage,
+			key.Country,
+			references,
 		)
 		if err == nil {
 			return translatedContent, nil
@@ -206,43 +768,56 @@ func (task *TranslationTask) translateChunkWithRetry(
 	return "", fmt.Errorf("failed after %d attempts: %w", maxRetries, lastErr)
 }
 
-func (task *TranslationTask) cleanupExistingTranslations(ctx context.Context, resourceID int64) error {
-	// Get existing translated resources
-	translatedResources, err := task.translatedResourceSvc.GetByOriginalID(ctx, resourceID)
-	if err != nil {
-		return fmt.Errorf("failed to get existing translated resources: %w", err)
+// discardTranslatedChunk deletes a translated chunk with its revision
+func (task *TranslationTask) discardTranslatedChunk(ctx context.Context, tc translatedchunkctrl.TranslatedChunk) error {
+	if err := task.deleteTranslatedChunkObjects(ctx, tc); err != nil {
+		return err
 	}
+	return task.translatedChunkSvc.Delete(ctx, tc.ID)
+}
 
-	for _, tr := range translatedResources {
-		// Get translated chunks to clean up
-		translatedChunks, err := task.translatedChunkSvc.GetByTranslatedResourceID(ctx, tr.ID)
-		if err != nil {
-			return fmt.Errorf("failed to get translated chunks: %w", err)
+func (task *TranslationTask) deleteTranslatedChunkObjects(ctx context.Context, tc translatedchunkctrl.TranslatedChunk) error {
+	for _, minioURL := range []string{tc.MinioURL, tc.RevisionMinioURL} {
+		if minioURL == "" {
+			continue
 		}
-
-		// Delete translated chunks from minio
-		for _, tc := range translatedChunks {
-			bucket, objectName := task.minioService.GetBucketAndObjectFromURL(tc.MinioURL)
-			if err := task.minioService.DeleteObject(ctx, bucket, objectName); err != nil {
-				return fmt.Errorf("failed to delete translated chunk object: %w", err)
-			}
+		bucket, objectName := task.minioService.GetBucketAndObjectFromURL(minioURL)
+		if err := task.minioService.DeleteObject(ctx, bucket, objectName); err != nil {
+			return fmt.Errorf("failed to delete translated chunk object: %w", err)
 		}
+	}
+	return nil
+}
 
-		// Delete translated chunks records
-		if err := task.translatedChunkSvc.DeleteByTranslatedResourceID(ctx, tr.ID); err != nil {
-			return fmt.Errorf("failed to delete translated chunks records: %w", err)
-		}
+// purgeTranslation deletes the translated resource with its translated chunks
+func (task *TranslationTask) purgeTranslation(ctx context.Context, tr translatedresourcectrl.TranslatedResource) error {
+	// Get translated chunks to clean up
+	translatedChunks, err := task.translatedChunkSvc.GetByTranslatedResourceID(ctx, tr.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get translated chunks: %w", err)
+	}
 
-		// Delete translated resource from minio
-		bucket, objectName := task.minioService.GetBucketAndObjectFromURL(tr.MinioURL)
-		if err := task.minioService.DeleteObject(ctx, bucket, objectName); err != nil {
-			return fmt.Errorf("failed to delete translated resource object: %w", err)
+	// Delete translated chunks and their revisions from minio
+	for _, tc := range translatedChunks {
+		if err := task.deleteTranslatedChunkObjects(ctx, tc); err != nil {
+			return err
 		}
 	}
 
-	// Delete translated resources records
-	if err := task.translatedResourceSvc.DeleteByOriginalID(ctx, resourceID); err != nil {
-		return fmt.Errorf("failed to delete translated resources records: %w", err)
+	// Delete translated chunks records
+	if err := task.translatedChunkSvc.DeleteByTranslatedResourceID(ctx, tr.ID); err != nil {
+		return fmt.Errorf("failed to delete translated chunks records: %w", err)
+	}
+
+	// Delete translated resource from minio
+	bucket, objectName := task.minioService.GetBucketAndObjectFromURL(tr.MinioURL)
+	if err := task.minioService.DeleteObject(ctx, bucket, objectName); err != nil {
+		return fmt.Errorf("failed to delete translated resource object: %w", err)
+	}
+
+	// Delete translated resource record
+	if err := task.translatedResourceSvc.Delete(ctx, tr.ID); err != nil {
+		return fmt.Errorf("failed to delete translated resource record: %w", err)
 	}
 
 	return nil
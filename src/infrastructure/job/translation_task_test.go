@@ -0,0 +1,823 @@
+package job_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+
+	"raggo/src/core/translationflow"
+	"raggo/src/core/translationmemory"
+	"raggo/src/core/translationreview"
+	"raggo/src/infrastructure/integrations/ollama"
+	"raggo/src/infrastructure/job"
+	"raggo/src/storage/postgres/chunkctrl"
+	"raggo/src/storage/postgres/resourcectrl"
+	"raggo/src/storage/postgres/translatedchunkctrl"
+	"raggo/src/storage/postgres/translatedresourcectrl"
+)
+
+// The source texts of the test document are dissimilar, so the translation memory never suggests one for another
+var testChunks = []struct {
+	source      string
+	translation string
+}{
+	{"Hello world", "Hola mundo"},
+	{"The broken sentence", "La frase rota"},
+	{"Goodbye", "Adiós"},
+}
+
+func TestHandleTranslationTaskFailurePolicies(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		wantErr      bool
+		wantFailed   []string
+		wantDocument string
+	}{
+		{
+			name:         "skip leaves the failed chunk out",
+			wantFailed:   []string{"chunk-2"},
+			wantDocument: "Hola mundo\n\nAdiós",
+		},
+		{
+			name:         "keep_source keeps the source of the failed chunk",
+			policy:       job.FailurePolicyKeepSource,
+			wantFailed:   []string{"chunk-2"},
+			wantDocument: "Hola mundo\n\nThe broken sentence\n\nAdiós",
+		},
+		{
+			name:       "fail fails the job",
+			policy:     job.FailurePolicyFail,
+			wantErr:    true,
+			wantFailed: []string{"chunk-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTranslationEnv(t)
+			env.model.fail("The broken sentence")
+
+			result, err := env.task.HandleTranslationTask(context.Background(), env.payload(t, tt.policy))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HandleTranslationTask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result == nil {
+				t.Fatal("HandleTranslationTask() returned no result")
+			}
+			if got := failedChunkIDs(result); !reflect.DeepEqual(got, tt.wantFailed) {
+				t.Errorf("FailedChunks = %v, want %v", got, tt.wantFailed)
+			}
+			if !result.HasErrors() {
+				t.Error("HasErrors() = false, want true")
+			}
+
+			document, ok := env.storage.object(env.translatedResourceURL(t))
+			if tt.wantErr {
+				if ok {
+					t.Errorf("translated document = %q, want none for a failed job", document)
+				}
+				return
+			}
+			if document != tt.wantDocument {
+				t.Errorf("translated document = %q, want %q", document, tt.wantDocument)
+			}
+		})
+	}
+}
+
+func TestHandleTranslationTaskUnknownFailurePolicy(t *testing.T) {
+	env := newTranslationEnv(t)
+
+	if _, err := env.task.HandleTranslationTask(context.Background(), env.payload(t, "retry")); err == nil {
+		t.Fatal("HandleTranslationTask() error = nil, want an error for an unknown failure policy")
+	}
+	if len(env.model.requests()) != 0 {
+		t.Error("the model was called for a payload with an unknown failure policy")
+	}
+}
+
+func TestHandleTranslationTaskResumesFromCheckpoints(t *testing.T) {
+	env := newTranslationEnv(t)
+	env.model.fail("The broken sentence")
+
+	if _, err := env.task.HandleTranslationTask(context.Background(), env.payload(t, job.FailurePolicyFail)); err == nil {
+		t.Fatal("first attempt succeeded, want it to fail on the broken chunk")
+	}
+
+	// The retry translates only the chunk that failed
+	env.model.fail()
+	env.model.reset()
+	result, err := env.task.HandleTranslationTask(context.Background(), env.payload(t, job.FailurePolicyFail))
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if result.ResumedChunks != 2 || result.HasErrors() {
+		t.Errorf("retry result = %+v, want 2 resumed chunks and no failures", result)
+	}
+	for _, source := range env.model.requests() {
+		if source != "The broken sentence" {
+			t.Errorf("retry translated %q again", source)
+		}
+	}
+
+	document, _ := env.storage.object(env.translatedResourceURL(t))
+	if want := "Hola mundo\n\nLa frase rota\n\nAdiós"; document != want {
+		t.Errorf("translated document = %q, want %q", document, want)
+	}
+}
+
+func TestHandleTranslationTaskDiscardsCheckpointsOfAnotherPipeline(t *testing.T) {
+	env := newTranslationEnv(t)
+	env.model.fail("The broken sentence")
+
+	if _, err := env.task.HandleTranslationTask(context.Background(), env.payload(t, job.FailurePolicyFail)); err == nil {
+		t.Fatal("first attempt succeeded, want it to fail on the broken chunk")
+	}
+
+	// The retry runs another pipeline, so the chunks of the first attempt are translated again
+	env.model.fail()
+	env.model.reset()
+	var payload job.TranslationPayload
+	if err := json.Unmarshal(env.payload(t, job.FailurePolicyFail), &payload); err != nil {
+		t.Fatal(err)
+	}
+	payload.Pipeline.Models = map[string]string{translationflow.StageInitial: "qwen2.5"}
+	retry, _ := json.Marshal(payload)
+
+	result, err := env.task.HandleTranslationTask(context.Background(), retry)
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if result.ResumedChunks != 0 || result.MemoryHits != 0 || result.HasErrors() {
+		t.Errorf("retry result = %+v, want no resumed chunks, no memory hits and no failures", result)
+	}
+	translated := env.model.requests()
+	sort.Strings(translated)
+	if want := []string{"Goodbye", "Hello world", "The broken sentence"}; !reflect.DeepEqual(translated, want) {
+		t.Errorf("retry translated %v, want %v", translated, want)
+	}
+}
+
+func TestHandleTranslationTaskReusesTranslationMemory(t *testing.T) {
+	env := newTranslationEnv(t)
+	if _, err := env.task.HandleTranslationTask(context.Background(), env.payload(t, "")); err != nil {
+		t.Fatalf("HandleTranslationTask() error = %v", err)
+	}
+
+	// A restart purges the checkpoints, so every chunk comes from the translation memory
+	env.model.reset()
+	var payload job.TranslationPayload
+	if err := json.Unmarshal(env.payload(t, ""), &payload); err != nil {
+		t.Fatal(err)
+	}
+	payload.Restart = true
+	restart, _ := json.Marshal(payload)
+
+	result, err := env.task.HandleTranslationTask(context.Background(), restart)
+	if err != nil {
+		t.Fatalf("HandleTranslationTask() error = %v", err)
+	}
+	if result.MemoryHits != len(testChunks) || result.ResumedChunks != 0 {
+		t.Errorf("result = %+v, want %d memory hits and no resumed chunks", result, len(testChunks))
+	}
+	if got := env.model.requests(); len(got) != 0 {
+		t.Errorf("the model translated %v, want every chunk from the translation memory", got)
+	}
+}
+
+func TestRejectedTranslationIsNotReused(t *testing.T) {
+	env := newTranslationEnv(t)
+	ctx := context.Background()
+	if _, err := env.task.HandleTranslationTask(ctx, env.payload(t, "")); err != nil {
+		t.Fatalf("HandleTranslationTask() error = %v", err)
+	}
+
+	tr, err := env.translatedResources.FindVariant(ctx, 1, "Spanish", "", "qwen2.5")
+	if err != nil || tr == nil {
+		t.Fatalf("translated resource not found: %v", err)
+	}
+	translatedChunks, _ := env.translatedChunks.GetByTranslatedResourceID(ctx, tr.ID)
+	var rejected *translatedchunkctrl.TranslatedChunk
+	for _, tc := range translatedChunks {
+		if tc.ChunkID == "chunk-1_translated" {
+			rejected = &tc
+		}
+	}
+	if rejected == nil {
+		t.Fatal("translated chunk-1 not found")
+	}
+
+	review := translationreview.NewService(env.storage, &fakeChunks{chunks: env.chunks}, env.translatedChunks, env.translationMemory)
+	if _, err := review.Reject(ctx, tr, rejected, "reviewer", "wrong greeting"); err != nil {
+		t.Fatalf("Reject() error = %v", err)
+	}
+
+	// The next run translates the rejected chunk again instead of reusing the rejected draft
+	env.model.translate("Hello world", "¡Hola, mundo!")
+	env.model.reset()
+	result, err := env.task.HandleTranslationTask(ctx, env.payload(t, ""))
+	if err != nil {
+		t.Fatalf("HandleTranslationTask() error = %v", err)
+	}
+	if result.MemoryHits != 0 || result.ResumedChunks != 2 {
+		t.Errorf("result = %+v, want no memory hits and 2 resumed chunks", result)
+	}
+	if got := env.model.requests(); !reflect.DeepEqual(got, []string{"Hello world"}) {
+		t.Errorf("the model translated %v, want only the rejected chunk", got)
+	}
+	document, _ := env.storage.object(tr.MinioURL)
+	if want := "¡Hola, mundo!\n\nLa frase rota\n\nAdiós"; document != want {
+		t.Errorf("translated document = %q, want %q", document, want)
+	}
+}
+
+func TestProcessJobMessageStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		failing    []string
+		policy     string
+		wantStatus job.JobStatus
+		wantErr    bool
+		wantResult bool
+	}{
+		{
+			name:       "every chunk translated",
+			wantStatus: job.JobStatusCompleted,
+			wantResult: true,
+		},
+		{
+			name:       "skipped chunks",
+			failing:    []string{"The broken sentence"},
+			policy:     job.FailurePolicySkip,
+			wantStatus: job.JobStatusCompletedWithErrors,
+			wantResult: true,
+		},
+		{
+			name:       "kept source chunks",
+			failing:    []string{"The broken sentence"},
+			policy:     job.FailurePolicyKeepSource,
+			wantStatus: job.JobStatusCompletedWithErrors,
+			wantResult: true,
+		},
+		{
+			name:       "failed chunks with the fail policy",
+			failing:    []string{"The broken sentence"},
+			policy:     job.FailurePolicyFail,
+			wantStatus: job.JobStatusFailed,
+			wantErr:    true,
+			wantResult: true,
+		},
+		{
+			name:       "invalid payload",
+			policy:     "retry",
+			wantStatus: job.JobStatusFailed,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTranslationEnv(t)
+			env.model.fail(tt.failing...)
+			jobs := &fakeJobRepository{jobs: make(map[int]*job.Job)}
+			created, err := jobs.Create(context.Background(), job.TaskTypeTranslation, env.payload(t, tt.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			service := job.NewJobService(nil, jobs, watermill.NopLogger{}, env.task, nil)
+
+			msgPayload, _ := json.Marshal(job.JobMessage{JobID: created.ID, TaskType: created.TaskType, Payload: created.Payload})
+			err = service.ProcessJobMessage(message.NewMessage(watermill.NewUUID(), msgPayload))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProcessJobMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got, _ := jobs.Get(context.Background(), created.ID)
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if (got.Result != nil) != tt.wantResult {
+				t.Errorf("result = %s, want a result %v", got.Result, tt.wantResult)
+			}
+			if tt.wantErr && got.Error == nil {
+				t.Error("the error of the failed job was not stored")
+			}
+		})
+	}
+}
+
+func failedChunkIDs(result *job.TranslationResult) []string {
+	var ids []string
+	for _, failed := range result.FailedChunks {
+		ids = append(ids, failed.ChunkID)
+	}
+	return ids
+}
+
+// translationEnv runs translation tasks of a resource with the test chunks against fake services
+type translationEnv struct {
+	task                *job.TranslationTask
+	model               *fakeModel
+	storage             *fakeStorage
+	memory              *fakeMemoryRepository
+	translationMemory   *translationmemory.Service
+	translatedResources *fakeTranslatedResources
+	translatedChunks    *fakeTranslatedChunks
+	chunks              []chunkctrl.Chunk
+}
+
+func newTranslationEnv(t *testing.T) *translationEnv {
+	t.Helper()
+
+	env := &translationEnv{
+		model:               newFakeModel(),
+		storage:             &fakeStorage{objects: make(map[string]string)},
+		memory:              &fakeMemoryRepository{},
+		translatedResources: &fakeTranslatedResources{resources: make(map[int64]*translatedresourcectrl.TranslatedResource)},
+		translatedChunks:    &fakeTranslatedChunks{chunks: make(map[int64]*translatedchunkctrl.TranslatedChunk)},
+	}
+	for i, chunk := range testChunks {
+		env.model.translate(chunk.source, chunk.translation)
+		minioURL := fmt.Sprintf("chunks/doc/chunk-%d", i+1)
+		env.storage.objects[minioURL] = chunk.source
+		env.chunks = append(env.chunks, chunkctrl.Chunk{
+			ID:         int64(i + 1),
+			ResourceID: 1,
+			ChunkID:    fmt.Sprintf("chunk-%d", i+1),
+			MinioURL:   minioURL,
+			Order:      i + 1,
+			Kind:       "text",
+		})
+	}
+
+	server := httptest.NewServer(env.model)
+	t.Cleanup(server.Close)
+
+	memory, err := translationmemory.NewService(env.memory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.translationMemory = memory
+	env.task = job.NewTranslationTask(
+		&fakeResources{},
+		&fakeChunks{chunks: env.chunks},
+		env.translatedResources,
+		env.translatedChunks,
+		env.storage,
+		ollama.NewClient(server.URL, server.Client()),
+		nil,
+		memory,
+		&fakeStyleGuides{},
+		nil,
+		nil,
+		translationflow.NewLimiter(0, 0),
+		2,
+		job.QualityEstimationConfig{},
+	)
+	return env
+}
+
+func (env *translationEnv) payload(t *testing.T, policy string) json.RawMessage {
+	t.Helper()
+	payload, err := json.Marshal(job.TranslationPayload{
+		SourceLanguage:   "English",
+		TargetLanguage:   "Spanish",
+		TargetResourceID: "1",
+		UseModel:         "qwen2.5",
+		Pipeline:         &translationflow.PipelineConfig{SkipReflection: true},
+		FailurePolicy:    policy,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func (env *translationEnv) translatedResourceURL(t *testing.T) string {
+	t.Helper()
+	tr, err := env.translatedResources.FindVariant(context.Background(), 1, "Spanish", "", "qwen2.5")
+	if err != nil || tr == nil {
+		t.Fatalf("translated resource not found: %v", err)
+	}
+	return tr.MinioURL
+}
+
+// fakeModel serves the generate endpoint of Ollama, translating every known source text found in the prompt
+type fakeModel struct {
+	mu           sync.Mutex
+	translations map[string]string
+	failing      map[string]bool
+	translated   []string // source texts of the answered prompts
+}
+
+func newFakeModel() *fakeModel {
+	return &fakeModel{translations: make(map[string]string), failing: make(map[string]bool)}
+}
+
+// translate makes the model answer prompts with the source text with the translation
+func (m *fakeModel) translate(source, translation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.translations[source] = translation
+}
+
+// fail makes the model fail on prompts with the given source texts only
+func (m *fakeModel) fail(sources ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failing = make(map[string]bool)
+	for _, source := range sources {
+		m.failing[source] = true
+	}
+}
+
+func (m *fakeModel) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.translated = nil
+}
+
+func (m *fakeModel) requests() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.translated...)
+}
+
+func (m *fakeModel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req ollama.GenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for source, translation := range m.translations {
+		if !strings.Contains(req.Prompt, source) {
+			continue
+		}
+		m.translated = append(m.translated, source)
+		if m.failing[source] {
+			fmt.Fprintln(w, `{"error":"model failed"}`)
+			return
+		}
+		response, _ := json.Marshal(ollama.GenerateResponse{Model: req.Model, Response: translation, Done: true})
+		fmt.Fprintln(w, string(response))
+		return
+	}
+	http.Error(w, "unknown prompt", http.StatusBadRequest)
+}
+
+type fakeStorage struct {
+	mu      sync.Mutex
+	objects map[string]string // content by bucket/object
+}
+
+func (s *fakeStorage) object(minioURL string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.objects[minioURL]
+	return content, ok
+}
+
+func (s *fakeStorage) EnsureBucketExists(ctx context.Context, bucketName string) error {
+	return nil
+}
+
+func (s *fakeStorage) GetObject(ctx context.Context, bucketName, objectName string) ([]byte, error) {
+	content, ok := s.object(bucketName + "/" + objectName)
+	if !ok {
+		return nil, fmt.Errorf("object %s/%s not found", bucketName, objectName)
+	}
+	return []byte(content), nil
+}
+
+func (s *fakeStorage) PutObject(ctx context.Context, bucketName, objectName string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[bucketName+"/"+objectName] = string(data)
+	return nil
+}
+
+func (s *fakeStorage) DeleteObject(ctx context.Context, bucketName, objectName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, bucketName+"/"+objectName)
+	return nil
+}
+
+func (s *fakeStorage) GetBucketAndObjectFromURL(minioURL string) (string, string) {
+	bucket, object, ok := strings.Cut(minioURL, "/")
+	if !ok {
+		return "", ""
+	}
+	return bucket, object
+}
+
+type fakeResources struct{}
+
+func (r *fakeResources) GetByID(ctx context.Context, id int64) (*resourcectrl.Resource, error) {
+	if id != 1 {
+		return nil, nil
+	}
+	return &resourcectrl.Resource{ID: 1, Filename: "doc.pdf", MinioURL: "resources/doc.pdf"}, nil
+}
+
+type fakeChunks struct {
+	chunks []chunkctrl.Chunk
+}
+
+func (c *fakeChunks) GetByResourceID(ctx context.Context, resourceID int64) ([]chunkctrl.Chunk, error) {
+	return c.chunks, nil
+}
+
+func (c *fakeChunks) GetByID(ctx context.Context, id int64) (*chunkctrl.Chunk, error) {
+	for _, chunk := range c.chunks {
+		if chunk.ID == id {
+			return &chunk, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *fakeChunks) UpdateLanguage(ctx context.Context, id int64, language string, confidence float64) error {
+	return nil
+}
+
+type fakeTranslatedResources struct {
+	mu        sync.Mutex
+	resources map[int64]*translatedresourcectrl.TranslatedResource
+	nextID    int64
+}
+
+func (r *fakeTranslatedResources) Create(ctx context.Context, originalResourceID int64, filename, minioURL, sourceLang, targetLang, country, model string) (*translatedresourcectrl.TranslatedResource, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	tr := &translatedresourcectrl.TranslatedResource{
+		ID:                 r.nextID,
+		OriginalResourceID: originalResourceID,
+		Filename:           filename,
+		MinioURL:           minioURL,
+		SourceLanguage:     sourceLang,
+		TargetLanguage:     targetLang,
+		Country:            country,
+		Model:              model,
+	}
+	r.resources[tr.ID] = tr
+	copied := *tr
+	return &copied, nil
+}
+
+func (r *fakeTranslatedResources) FindVariant(ctx context.Context, originalID int64, targetLang, country, model string) (*translatedresourcectrl.TranslatedResource, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, tr := range r.resources {
+		if tr.OriginalResourceID == originalID && tr.TargetLanguage == targetLang && tr.Country == country && tr.Model == model {
+			copied := *tr
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeTranslatedResources) UpdateSourceLanguage(ctx context.Context, id int64, sourceLang string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if tr, ok := r.resources[id]; ok {
+		tr.SourceLanguage = sourceLang
+	}
+	return nil
+}
+
+func (r *fakeTranslatedResources) UpdateSignatures(ctx context.Context, id int64, pipeline, glossary, styleGuide string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if tr, ok := r.resources[id]; ok {
+		tr.PipelineSignature = pipeline
+		tr.GlossarySignature = glossary
+		tr.StyleGuideSignature = styleGuide
+	}
+	return nil
+}
+
+func (r *fakeTranslatedResources) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.resources, id)
+	return nil
+}
+
+type fakeTranslatedChunks struct {
+	mu     sync.Mutex
+	chunks map[int64]*translatedchunkctrl.TranslatedChunk
+	nextID int64
+}
+
+func (c *fakeTranslatedChunks) Create(ctx context.Context, translatedResourceID, originalChunkID int64, chunkID, minioURL, sourceLanguage string) (*translatedchunkctrl.TranslatedChunk, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	tc := &translatedchunkctrl.TranslatedChunk{
+		ID:                   c.nextID,
+		TranslatedResourceID: translatedResourceID,
+		OriginalChunkID:      originalChunkID,
+		ChunkID:              chunkID,
+		MinioURL:             minioURL,
+		SourceLanguage:       sourceLanguage,
+		ReviewStatus:         translatedchunkctrl.ReviewStatusPending,
+	}
+	c.chunks[tc.ID] = tc
+	copied := *tc
+	return &copied, nil
+}
+
+func (c *fakeTranslatedChunks) GetByTranslatedResourceID(ctx context.Context, translatedResourceID int64) ([]translatedchunkctrl.TranslatedChunk, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var chunks []translatedchunkctrl.TranslatedChunk
+	for _, tc := range c.chunks {
+		if tc.TranslatedResourceID == translatedResourceID {
+			chunks = append(chunks, *tc)
+		}
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].ID < chunks[j].ID })
+	return chunks, nil
+}
+
+func (c *fakeTranslatedChunks) GetByID(ctx context.Context, id int64) (*translatedchunkctrl.TranslatedChunk, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tc, ok := c.chunks[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *tc
+	return &copied, nil
+}
+
+func (c *fakeTranslatedChunks) SaveRevision(ctx context.Context, id int64, revisionMinioURL, author string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.chunks[id].RevisionMinioURL = revisionMinioURL
+	c.chunks[id].RevisedBy = author
+	c.chunks[id].ReviewStatus = translatedchunkctrl.ReviewStatusPending
+	return nil
+}
+
+func (c *fakeTranslatedChunks) SetReview(ctx context.Context, id int64, status, reviewer, comment string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.chunks[id].ReviewStatus = status
+	c.chunks[id].ReviewedBy = reviewer
+	c.chunks[id].ReviewComment = comment
+	return nil
+}
+
+func (c *fakeTranslatedChunks) UpdateQuality(ctx context.Context, id int64, estimate *translationflow.QualityEstimate, flagged bool) error {
+	return nil
+}
+
+func (c *fakeTranslatedChunks) Delete(ctx context.Context, id int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.chunks, id)
+	return nil
+}
+
+func (c *fakeTranslatedChunks) DeleteByTranslatedResourceID(ctx context.Context, translatedResourceID int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, tc := range c.chunks {
+		if tc.TranslatedResourceID == translatedResourceID {
+			delete(c.chunks, id)
+		}
+	}
+	return nil
+}
+
+type fakeStyleGuides struct{}
+
+func (s *fakeStyleGuides) Load(ctx context.Context, targetLanguage, country string) (*translationflow.StyleGuide, error) {
+	return nil, nil
+}
+
+type fakeMemoryRepository struct {
+	mu      sync.Mutex
+	entries []translationmemory.Entry
+}
+
+func (r *fakeMemoryRepository) Get(ctx context.Context, sourceHash string, key translationmemory.Key) (*translationmemory.Entry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, entry := range r.entries {
+		if entry.SourceHash == sourceHash && entry.Key() == key {
+			return &entry, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeMemoryRepository) Save(ctx context.Context, entry *translationmemory.Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.entries {
+		if r.entries[i].SourceHash == entry.SourceHash && r.entries[i].Key() == entry.Key() {
+			r.entries[i].TargetText = entry.TargetText
+			return nil
+		}
+	}
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+func (r *fakeMemoryRepository) ListCandidates(ctx context.Context, sourceLanguage, targetLanguage, country string, minLength, maxLength, limit int) ([]translationmemory.Entry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var candidates []translationmemory.Entry
+	for _, entry := range r.entries {
+		length := len([]rune(entry.SourceText))
+		if entry.SourceLanguage == sourceLanguage && entry.TargetLanguage == targetLanguage && entry.Country == country &&
+			length >= minLength && length <= maxLength {
+			candidates = append(candidates, entry)
+		}
+	}
+	return candidates, nil
+}
+
+func (r *fakeMemoryRepository) List(ctx context.Context, key translationmemory.Key) ([]translationmemory.Entry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var entries []translationmemory.Entry
+	for _, entry := range r.entries {
+		if entry.Key() == key {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (r *fakeMemoryRepository) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, entry := range r.entries {
+		if entry.ID == id {
+			r.entries = append(r.entries[:i], r.entries[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+type fakeJobRepository struct {
+	mu     sync.Mutex
+	jobs   map[int]*job.Job
+	nextID int
+}
+
+func (r *fakeJobRepository) Create(ctx context.Context, taskType string, payload json.RawMessage) (*job.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	j := &job.Job{ID: r.nextID, TaskType: taskType, Payload: payload, Status: job.JobStatusPending}
+	r.jobs[j.ID] = j
+	copied := *j
+	return &copied, nil
+}
+
+func (r *fakeJobRepository) Get(ctx context.Context, id int) (*job.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *j
+	return &copied, nil
+}
+
+func (r *fakeJobRepository) UpdateStatus(ctx context.Context, id int, status job.JobStatus, err *string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[id].Status = status
+	r.jobs[id].Error = err
+	return nil
+}
+
+func (r *fakeJobRepository) UpdateResult(ctx context.Context, id int, result json.RawMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[id].Result = result
+	return nil
+}
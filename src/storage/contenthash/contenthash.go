@@ -0,0 +1,12 @@
+package contenthash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Sum returns the hex encoded SHA-256 hash of the given content
+func Sum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
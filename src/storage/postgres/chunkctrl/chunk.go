@@ -9,14 +9,39 @@ import (
 	"gorm.io/gorm"
 )
 
+// Chunk kinds
+const (
+	KindText  = "text"
+	KindTable = "table"
+	KindImage = "image"
+)
+
 type Chunk struct {
-	ID         int64     `gorm:"primaryKey" json:"id"`
-	ResourceID int64     `gorm:"not null" json:"resource_id"`
-	ChunkID    string    `gorm:"not null" json:"chunk_id"`
-	MinioURL   string    `gorm:"not null;column:minio_url" json:"minio_url"` // bucket name + object name
-	Order      int       `gorm:"not null;column:chunk_order" json:"order"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID                    int64     `gorm:"primaryKey" json:"id"`
+	ResourceID            int64     `gorm:"not null" json:"resource_id"`
+	ChunkID               string    `gorm:"not null" json:"chunk_id"`
+	MinioURL              string    `gorm:"not null;column:minio_url" json:"minio_url"` // bucket name + object name
+	Order                 int       `gorm:"not null;column:chunk_order" json:"order"`
+	PageNumber            int       `gorm:"not null" json:"page_number,omitempty"`   // page of the source document the chunk starts on, 0 if unknown
+	ContentHash           string    `gorm:"column:content_hash" json:"content_hash"` // SHA-256 of the content at MinioURL, of the image at ImageMinioURL for image chunks
+	Kind                  string    `gorm:"not null;default:text" json:"kind"`
+	TableHTMLMinioURL     string    `gorm:"column:table_html_minio_url" json:"table_html_minio_url,omitempty"`
+	TableMarkdownMinioURL string    `gorm:"column:table_markdown_minio_url" json:"table_markdown_minio_url,omitempty"`
+	ImageMinioURL         string    `gorm:"column:image_minio_url" json:"image_minio_url,omitempty"`
+	Language              string    `gorm:"not null" json:"language,omitempty"` // ISO 639-1 code detected for the chunk, empty if not detected
+	LanguageConfidence    *float64  `json:"language_confidence,omitempty"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// IsTable reports whether the chunk holds a table extracted from the source document
+func (c *Chunk) IsTable() bool {
+	return c.Kind == KindTable
+}
+
+// IsImage reports whether the chunk holds a figure extracted from the source document
+func (c *Chunk) IsImage() bool {
+	return c.Kind == KindImage
 }
 
 type ChunkService struct {
@@ -37,13 +62,16 @@ func NewChunkService(db *gorm.DB) (*ChunkService, error) {
 	}, nil
 }
 
-func (s *ChunkService) Create(ctx context.Context, resourceID int64, chunkID string, minioURL string, order int) (*Chunk, error) {
+func (s *ChunkService) Create(ctx context.Context, resourceID int64, chunkID string, minioURL, contentHash string, order, pageNumber int) (*Chunk, error) {
 	chunk := &Chunk{
-		ID:         s.snowflake.Generate().Int64(),
-		ResourceID: resourceID,
-		ChunkID:    chunkID,
-		MinioURL:   minioURL,
-		Order:      order,
+		ID:          s.snowflake.Generate().Int64(),
+		ResourceID:  resourceID,
+		ChunkID:     chunkID,
+		MinioURL:    minioURL,
+		ContentHash: contentHash,
+		Order:       order,
+		PageNumber:  pageNumber,
+		Kind:        KindText,
 	}
 
 	result := s.db.WithContext(ctx).Create(chunk)
@@ -54,15 +82,82 @@ func (s *ChunkService) Create(ctx context.Context, resourceID int64, chunkID str
 	return chunk, nil
 }
 
+// CreateTable creates a table chunk. minioURL points to the flattened text of the table,
+// htmlURL and markdownURL point to the structured representations.
+func (s *ChunkService) CreateTable(ctx context.Context, resourceID int64, chunkID string, minioURL, contentHash, htmlURL, markdownURL string, order, pageNumber int) (*Chunk, error) {
+	chunk := &Chunk{
+		ID:                    s.snowflake.Generate().Int64(),
+		ResourceID:            resourceID,
+		ChunkID:               chunkID,
+		MinioURL:              minioURL,
+		ContentHash:           contentHash,
+		Order:                 order,
+		PageNumber:            pageNumber,
+		Kind:                  KindTable,
+		TableHTMLMinioURL:     htmlURL,
+		TableMarkdownMinioURL: markdownURL,
+	}
+
+	result := s.db.WithContext(ctx).Create(chunk)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to create table chunk: %v", result.Error)
+	}
+
+	return chunk, nil
+}
+
+// CreateImage creates an image chunk. minioURL points to the caption of the image,
+// imageURL points to the extracted image itself.
+func (s *ChunkService) CreateImage(ctx context.Context, resourceID int64, chunkID string, minioURL, contentHash, imageURL string, order, pageNumber int) (*Chunk, error) {
+	chunk := &Chunk{
+		ID:            s.snowflake.Generate().Int64(),
+		ResourceID:    resourceID,
+		ChunkID:       chunkID,
+		MinioURL:      minioURL,
+		ContentHash:   contentHash,
+		Order:         order,
+		PageNumber:    pageNumber,
+		Kind:          KindImage,
+		ImageMinioURL: imageURL,
+	}
+
+	result := s.db.WithContext(ctx).Create(chunk)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to create image chunk: %v", result.Error)
+	}
+
+	return chunk, nil
+}
+
 func (s *ChunkService) GetByResourceID(ctx context.Context, resourceID int64) ([]Chunk, error) {
 	var chunks []Chunk
-	result := s.db.WithContext(ctx).Where("resource_id = ?", resourceID).Find(&chunks)
+	result := s.db.WithContext(ctx).Where("resource_id = ?", resourceID).Order("chunk_order ASC").Find(&chunks)
 	if result.Error != nil {
 		return nil, fmt.Errorf("failed to get chunks: %v", result.Error)
 	}
 	return chunks, nil
 }
 
+// ListByResourceID returns a page of the chunks of a resource in document order and the total number of chunks
+func (s *ChunkService) ListByResourceID(ctx context.Context, resourceID int64, limit, offset int) ([]Chunk, int64, error) {
+	var total int64
+	if err := s.db.WithContext(ctx).Model(&Chunk{}).Where("resource_id = ?", resourceID).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count chunks: %v", err)
+	}
+
+	var chunks []Chunk
+	result := s.db.WithContext(ctx).
+		Where("resource_id = ?", resourceID).
+		Order("chunk_order ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&chunks)
+	if result.Error != nil {
+		return nil, 0, fmt.Errorf("failed to list chunks: %v", result.Error)
+	}
+	return chunks, total, nil
+}
+
 func (s *ChunkService) DeleteByResourceID(ctx context.Context, resourceID int64) error {
 	result := s.db.WithContext(ctx).Where("resource_id = ?", resourceID).Delete(&Chunk{})
 	if result.Error != nil {
@@ -71,6 +166,43 @@ func (s *ChunkService) DeleteByResourceID(ctx context.Context, resourceID int64)
 	return nil
 }
 
+// UpdatePosition moves an unchanged chunk to its position in a newer version of the resource
+func (s *ChunkService) UpdatePosition(ctx context.Context, id int64, chunkID string, order, pageNumber int) error {
+	result := s.db.WithContext(ctx).Model(&Chunk{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"chunk_id":    chunkID,
+		"chunk_order": order,
+		"page_number": pageNumber,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update chunk position: %v", result.Error)
+	}
+	return nil
+}
+
+// UpdateLanguage records the language detected for a chunk
+func (s *ChunkService) UpdateLanguage(ctx context.Context, id int64, language string, confidence float64) error {
+	result := s.db.WithContext(ctx).Model(&Chunk{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"language":            language,
+		"language_confidence": confidence,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update chunk language: %v", result.Error)
+	}
+	return nil
+}
+
+func (s *ChunkService) DeleteByIDs(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	result := s.db.WithContext(ctx).Where("id IN ?", ids).Delete(&Chunk{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete chunks: %v", result.Error)
+	}
+	return nil
+}
+
 func (s *ChunkService) GetByID(ctx context.Context, id int64) (*Chunk, error) {
 	var chunk Chunk
 	result := s.db.WithContext(ctx).First(&chunk, id)
@@ -0,0 +1,186 @@
+package conversationctrl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/snowflake"
+	"gorm.io/gorm"
+
+	"raggo/src/core/knowledgebase"
+)
+
+// Conversation is a chat with a knowledge base whose history is kept for follow-up questions
+type Conversation struct {
+	ID              int64     `gorm:"primaryKey" json:"id"`
+	KnowledgeBaseID int64     `gorm:"not null" json:"knowledge_base_id"`
+	Title           string    `gorm:"not null" json:"title"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Message is a question or an answer of a conversation. A question keeps the standalone query it was
+// rewritten into for retrieval, an answer the sources it cites.
+type Message struct {
+	ID              int64                  `gorm:"primaryKey" json:"id"`
+	ConversationID  int64                  `gorm:"not null" json:"conversation_id"`
+	Role            string                 `gorm:"not null" json:"role"`
+	Content         string                 `gorm:"not null" json:"content"`
+	StandaloneQuery string                 `gorm:"not null" json:"standalone_query,omitempty"`
+	CitationsRaw    string                 `gorm:"column:citations;type:jsonb;not null" json:"-"`
+	Citations       []knowledgebase.Source `gorm:"-" json:"citations,omitempty"`
+	CreatedAt       time.Time              `json:"created_at"`
+}
+
+func (Message) TableName() string {
+	return "conversation_messages"
+}
+
+type ConversationService struct {
+	db        *gorm.DB
+	snowflake *snowflake.Node
+}
+
+func NewConversationService(db *gorm.DB) (*ConversationService, error) {
+	// Initialize snowflake node
+	node, err := snowflake.NewNode(9) // Node number 9 for conversations and their messages
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snowflake node: %v", err)
+	}
+
+	return &ConversationService{
+		db:        db,
+		snowflake: node,
+	}, nil
+}
+
+func (s *ConversationService) Create(ctx context.Context, knowledgeBaseID int64, title string) (*Conversation, error) {
+	conversation := &Conversation{
+		ID:              s.snowflake.Generate().Int64(),
+		KnowledgeBaseID: knowledgeBaseID,
+		Title:           title,
+	}
+	if err := s.db.WithContext(ctx).Create(conversation).Error; err != nil {
+		return nil, fmt.Errorf("failed to create conversation: %v", err)
+	}
+	return conversation, nil
+}
+
+func (s *ConversationService) GetByID(ctx context.Context, id int64) (*Conversation, error) {
+	var conversation Conversation
+	result := s.db.WithContext(ctx).First(&conversation, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get conversation: %v", result.Error)
+	}
+	return &conversation, nil
+}
+
+// List returns the conversations with a knowledge base, the most recently active first
+func (s *ConversationService) List(ctx context.Context, knowledgeBaseID int64, limit, offset int) ([]Conversation, error) {
+	var conversations []Conversation
+	result := s.db.WithContext(ctx).
+		Where("knowledge_base_id = ?", knowledgeBaseID).
+		Order("updated_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&conversations)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list conversations: %v", result.Error)
+	}
+	return conversations, nil
+}
+
+// Delete removes the conversation together with its messages
+func (s *ConversationService) Delete(ctx context.Context, id int64) error {
+	if err := s.db.WithContext(ctx).Delete(&Conversation{}, id).Error; err != nil {
+		return fmt.Errorf("failed to delete conversation: %v", err)
+	}
+	return nil
+}
+
+// ListMessages returns the messages of a conversation, oldest first
+func (s *ConversationService) ListMessages(ctx context.Context, conversationID int64) ([]Message, error) {
+	var messages []Message
+	result := s.db.WithContext(ctx).
+		Where("conversation_id = ?", conversationID).
+		Order("created_at ASC, id ASC").
+		Find(&messages)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list conversation messages: %v", result.Error)
+	}
+
+	for i := range messages {
+		if err := json.Unmarshal([]byte(messages[i].CitationsRaw), &messages[i].Citations); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal citations of message %d: %v", messages[i].ID, err)
+		}
+	}
+	return messages, nil
+}
+
+// AddExchange stores a question and its answer in one transaction, so that the history never holds a question
+// without an answer. A conversation without a title is named after its first question.
+func (s *ConversationService) AddExchange(ctx context.Context, conversation *Conversation, question, standaloneQuery, answer string, citations []knowledgebase.Source) (*Message, error) {
+	citationsRaw, err := json.Marshal(citations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal citations: %v", err)
+	}
+	if citations == nil {
+		citationsRaw = []byte("[]")
+	}
+
+	questionMessage := &Message{
+		ID:              s.snowflake.Generate().Int64(),
+		ConversationID:  conversation.ID,
+		Role:            knowledgebase.RoleUser,
+		Content:         question,
+		StandaloneQuery: standaloneQuery,
+		CitationsRaw:    "[]",
+	}
+	answerMessage := &Message{
+		ID:             s.snowflake.Generate().Int64(),
+		ConversationID: conversation.ID,
+		Role:           knowledgebase.RoleAssistant,
+		Content:        answer,
+		CitationsRaw:   string(citationsRaw),
+		Citations:      citations,
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(questionMessage).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(answerMessage).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"updated_at": time.Now()}
+		if conversation.Title == "" {
+			updates["title"] = title(question)
+		}
+		return tx.Model(conversation).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add messages to conversation: %v", err)
+	}
+
+	return answerMessage, nil
+}
+
+// maxTitleLength is the number of characters of the first question a conversation is named after
+const maxTitleLength = 80
+
+// title shortens a question to the title of a conversation
+func title(question string) string {
+	runes := []rune(strings.TrimSpace(question))
+	if len(runes) <= maxTitleLength {
+		return question
+	}
+	return string(runes[:maxTitleLength-1]) + "…"
+}
@@ -0,0 +1,176 @@
+package glossaryctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/snowflake"
+	"gorm.io/gorm"
+
+	"raggo/src/core/translationflow"
+)
+
+type Glossary struct {
+	ID             int64     `gorm:"primaryKey" json:"id"`
+	Name           string    `gorm:"not null" json:"name"`
+	Description    string    `json:"description"`
+	SourceLanguage string    `gorm:"not null" json:"source_language"`
+	TargetLanguage string    `gorm:"not null" json:"target_language"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type GlossaryTerm struct {
+	ID             int64     `gorm:"primaryKey" json:"id"`
+	GlossaryID     int64     `gorm:"not null" json:"glossary_id"`
+	SourceTerm     string    `gorm:"not null" json:"source_term"`
+	TargetTerm     string    `json:"target_term"`
+	DoNotTranslate bool      `gorm:"not null;default:false" json:"do_not_translate"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type GlossaryService struct {
+	db        *gorm.DB
+	snowflake *snowflake.Node
+}
+
+func NewGlossaryService(db *gorm.DB) (*GlossaryService, error) {
+	// Initialize snowflake node
+	node, err := snowflake.NewNode(5) // Node number 5 for glossaries
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snowflake node: %v", err)
+	}
+
+	return &GlossaryService{
+		db:        db,
+		snowflake: node,
+	}, nil
+}
+
+func (s *GlossaryService) Create(ctx context.Context, name, description, sourceLang, targetLang string) (*Glossary, error) {
+	glossary := &Glossary{
+		ID:             s.snowflake.Generate().Int64(),
+		Name:           name,
+		Description:    description,
+		SourceLanguage: sourceLang,
+		TargetLanguage: targetLang,
+	}
+
+	result := s.db.WithContext(ctx).Create(glossary)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to create glossary: %v", result.Error)
+	}
+
+	return glossary, nil
+}
+
+func (s *GlossaryService) List(ctx context.Context, limit, offset int) ([]Glossary, error) {
+	var glossaries []Glossary
+	result := s.db.WithContext(ctx).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&glossaries)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list glossaries: %v", result.Error)
+	}
+	return glossaries, nil
+}
+
+func (s *GlossaryService) GetByID(ctx context.Context, id int64) (*Glossary, error) {
+	var glossary Glossary
+	result := s.db.WithContext(ctx).First(&glossary, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get glossary: %v", result.Error)
+	}
+	return &glossary, nil
+}
+
+// Delete removes the glossary and its terms
+func (s *GlossaryService) Delete(ctx context.Context, id int64) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("glossary_id = ?", id).Delete(&GlossaryTerm{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Glossary{}, id).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete glossary: %v", err)
+	}
+	return nil
+}
+
+// AddTerm adds a term to the glossary or replaces the existing entry of the same source term
+func (s *GlossaryService) AddTerm(ctx context.Context, glossaryID int64, sourceTerm, targetTerm string, doNotTranslate bool) (*GlossaryTerm, error) {
+	var term GlossaryTerm
+	result := s.db.WithContext(ctx).
+		Where("glossary_id = ? AND source_term = ?", glossaryID, sourceTerm).
+		First(&term)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get glossary term: %v", result.Error)
+	}
+	if result.Error != nil {
+		term = GlossaryTerm{
+			ID:         s.snowflake.Generate().Int64(),
+			GlossaryID: glossaryID,
+			SourceTerm: sourceTerm,
+		}
+	}
+
+	term.TargetTerm = targetTerm
+	term.DoNotTranslate = doNotTranslate
+	if err := s.db.WithContext(ctx).Save(&term).Error; err != nil {
+		return nil, fmt.Errorf("failed to save glossary term: %v", err)
+	}
+
+	return &term, nil
+}
+
+func (s *GlossaryService) ListTerms(ctx context.Context, glossaryID int64) ([]GlossaryTerm, error) {
+	var terms []GlossaryTerm
+	result := s.db.WithContext(ctx).
+		Where("glossary_id = ?", glossaryID).
+		Order("source_term ASC").
+		Find(&terms)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list glossary terms: %v", result.Error)
+	}
+	return terms, nil
+}
+
+func (s *GlossaryService) DeleteTerm(ctx context.Context, glossaryID, termID int64) error {
+	result := s.db.WithContext(ctx).
+		Where("glossary_id = ? AND id = ?", glossaryID, termID).
+		Delete(&GlossaryTerm{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete glossary term: %v", result.Error)
+	}
+	return nil
+}
+
+// Load returns the terms of the glossary in the form used by the translation flow
+func (s *GlossaryService) Load(ctx context.Context, glossaryID int64) (*translationflow.Glossary, error) {
+	terms, err := s.ListTerms(ctx, glossaryID)
+	if err != nil {
+		return nil, err
+	}
+
+	glossary := &translationflow.Glossary{}
+	for _, term := range terms {
+		if term.DoNotTranslate {
+			glossary.DoNotTranslate = append(glossary.DoNotTranslate, term.SourceTerm)
+			continue
+		}
+		glossary.Terms = append(glossary.Terms, translationflow.GlossaryTerm{
+			SourceTerm: term.SourceTerm,
+			TargetTerm: term.TargetTerm,
+		})
+	}
+	return glossary, nil
+}
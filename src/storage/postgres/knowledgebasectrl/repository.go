@@ -30,6 +30,9 @@ type KnowledgeBaseResource struct {
 	ResourceID         int64  `gorm:"not null"`
 	Title              string `gorm:"not null"`
 	ContextDescription string
+	ContentHash        string
+	TranslatedChunkID  *int64
+	Language           string `gorm:"not null"`
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
 }
@@ -87,21 +90,7 @@ func (r *Repository) ListKnowledgeBaseResources(ctx context.Context, knowledgeBa
 		return nil, fmt.Errorf("failed to list knowledge base resources: %v", result.Error)
 	}
 
-	// Convert to domain model
-	var domainResources []kb.KnowledgeBaseResource
-	for _, resource := range resources {
-		domainResources = append(domainResources, kb.KnowledgeBaseResource{
-			ID:                 resource.ID,
-			KnowledgeBaseID:    resource.KnowledgeBaseID,
-			ChunkID:            resource.ChunkID,
-			Title:              resource.Title,
-			ContextDescription: resource.ContextDescription,
-			CreatedAt:          resource.CreatedAt,
-			UpdatedAt:          resource.UpdatedAt,
-		})
-	}
-
-	return domainResources, nil
+	return toDomainResources(resources), nil
 }
 
 func (r *Repository) CreateKnowledgeBase(ctx context.Context, kb *kb.KnowledgeBase) error {
@@ -128,6 +117,9 @@ func (r *Repository) AddResource(ctx context.Context, resource *kb.KnowledgeBase
 		ChunkID:            resource.ChunkID,
 		Title:              resource.Title,
 		ContextDescription: resource.ContextDescription,
+		ContentHash:        resource.ContentHash,
+		TranslatedChunkID:  resource.TranslatedChunkID,
+		Language:           resource.Language,
 	}
 
 	result := r.db.WithContext(ctx).Create(&dbResource)
@@ -157,3 +149,188 @@ func (r *Repository) GetKnowledgeBase(ctx context.Context, id int64) (*kb.Knowle
 		UpdatedAt:      base.UpdatedAt,
 	}, nil
 }
+
+// HasContentHash reports whether the knowledge base already indexes a chunk with the given content hash
+func (r *Repository) HasContentHash(ctx context.Context, knowledgeBaseID int64, contentHash string) (bool, error) {
+	var count int64
+	result := r.db.WithContext(ctx).
+		Model(&KnowledgeBaseResource{}).
+		Where("knowledge_base_id = ? AND content_hash = ?", knowledgeBaseID, contentHash).
+		Count(&count)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to check content hash: %v", result.Error)
+	}
+
+	return count > 0, nil
+}
+
+// HasChunk reports whether the knowledge base already indexes the original of the chunk
+func (r *Repository) HasChunk(ctx context.Context, knowledgeBaseID int64, chunkID int64) (bool, error) {
+	var count int64
+	result := r.db.WithContext(ctx).
+		Model(&KnowledgeBaseResource{}).
+		Where("knowledge_base_id = ? AND chunk_id = ? AND translated_chunk_id IS NULL", knowledgeBaseID, chunkID).
+		Count(&count)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to check chunk: %v", result.Error)
+	}
+
+	return count > 0, nil
+}
+
+// GetResourceByContentHash returns the earliest indexed original chunk with the content hash that is not one of
+// the excluded chunks, or nil if there is none
+func (r *Repository) GetResourceByContentHash(ctx context.Context, knowledgeBaseID int64, contentHash string, excludedChunkIDs []int64) (*kb.KnowledgeBaseResource, error) {
+	query := r.db.WithContext(ctx).
+		Where("knowledge_base_id = ? AND content_hash = ? AND translated_chunk_id IS NULL", knowledgeBaseID, contentHash)
+	if len(excludedChunkIDs) > 0 {
+		query = query.Where("chunk_id NOT IN ?", excludedChunkIDs)
+	}
+
+	var resources []KnowledgeBaseResource
+	if err := query.Order("created_at ASC").Limit(1).Find(&resources).Error; err != nil {
+		return nil, fmt.Errorf("failed to get knowledge base resource by content hash: %v", err)
+	}
+	if len(resources) == 0 {
+		return nil, nil
+	}
+
+	resource := toDomainResources(resources)[0]
+	return &resource, nil
+}
+
+// ListResourcesByChunkIDs returns the knowledge base rows of the given chunks
+func (r *Repository) ListResourcesByChunkIDs(ctx context.Context, knowledgeBaseID int64, chunkIDs []int64) ([]kb.KnowledgeBaseResource, error) {
+	if len(chunkIDs) == 0 {
+		return nil, nil
+	}
+
+	var resources []KnowledgeBaseResource
+	result := r.db.WithContext(ctx).
+		Where("knowledge_base_id = ? AND chunk_id IN ?", knowledgeBaseID, chunkIDs).
+		Find(&resources)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list knowledge base resources: %v", result.Error)
+	}
+
+	return toDomainResources(resources), nil
+}
+
+// ListResourcesByResourceID returns every knowledge base row of a resource
+func (r *Repository) ListResourcesByResourceID(ctx context.Context, knowledgeBaseID int64, resourceID int64) ([]kb.KnowledgeBaseResource, error) {
+	var resources []KnowledgeBaseResource
+	result := r.db.WithContext(ctx).
+		Where("knowledge_base_id = ? AND resource_id = ?", knowledgeBaseID, resourceID).
+		Find(&resources)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list knowledge base resources: %v", result.Error)
+	}
+
+	return toDomainResources(resources), nil
+}
+
+// HasTranslatedChunk reports whether the knowledge base already indexes the translated chunk
+func (r *Repository) HasTranslatedChunk(ctx context.Context, knowledgeBaseID int64, translatedChunkID int64) (bool, error) {
+	var count int64
+	result := r.db.WithContext(ctx).
+		Model(&KnowledgeBaseResource{}).
+		Where("knowledge_base_id = ? AND translated_chunk_id = ?", knowledgeBaseID, translatedChunkID).
+		Count(&count)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to check translated chunk: %v", result.Error)
+	}
+
+	return count > 0, nil
+}
+
+// ListLanguages returns the languages of the chunks indexed by the knowledge base
+func (r *Repository) ListLanguages(ctx context.Context, knowledgeBaseID int64) ([]string, error) {
+	var languages []string
+	result := r.db.WithContext(ctx).
+		Model(&KnowledgeBaseResource{}).
+		Where("knowledge_base_id = ? AND language <> ''", knowledgeBaseID).
+		Distinct().
+		Order("language").
+		Pluck("language", &languages)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list knowledge base languages: %v", result.Error)
+	}
+
+	return languages, nil
+}
+
+// ListKnowledgeBaseIDsByResourceID returns the IDs of every knowledge base that includes the resource
+func (r *Repository) ListKnowledgeBaseIDsByResourceID(ctx context.Context, resourceID int64) ([]int64, error) {
+	var ids []int64
+	result := r.db.WithContext(ctx).
+		Model(&KnowledgeBaseResource{}).
+		Where("resource_id = ?", resourceID).
+		Distinct().
+		Pluck("knowledge_base_id", &ids)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list knowledge bases of resource: %v", result.Error)
+	}
+
+	return ids, nil
+}
+
+// DeleteResourcesByChunkIDs removes the knowledge base rows of the given chunks
+func (r *Repository) DeleteResourcesByChunkIDs(ctx context.Context, knowledgeBaseID int64, chunkIDs []int64) error {
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+
+	result := r.db.WithContext(ctx).
+		Where("knowledge_base_id = ? AND chunk_id IN ?", knowledgeBaseID, chunkIDs).
+		Delete(&KnowledgeBaseResource{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete knowledge base resources: %v", result.Error)
+	}
+
+	return nil
+}
+
+// DeleteResourcesByResourceID removes every knowledge base row of a resource
+func (r *Repository) DeleteResourcesByResourceID(ctx context.Context, knowledgeBaseID int64, resourceID int64) error {
+	result := r.db.WithContext(ctx).
+		Where("knowledge_base_id = ? AND resource_id = ?", knowledgeBaseID, resourceID).
+		Delete(&KnowledgeBaseResource{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete knowledge base resources: %v", result.Error)
+	}
+
+	return nil
+}
+
+// DeleteResources removes every resource row of a knowledge base
+func (r *Repository) DeleteResources(ctx context.Context, knowledgeBaseID int64) error {
+	result := r.db.WithContext(ctx).
+		Where("knowledge_base_id = ?", knowledgeBaseID).
+		Delete(&KnowledgeBaseResource{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete knowledge base resources: %v", result.Error)
+	}
+
+	return nil
+}
+
+func toDomainResources(resources []KnowledgeBaseResource) []kb.KnowledgeBaseResource {
+	// Convert to domain model
+	var domainResources []kb.KnowledgeBaseResource
+	for _, resource := range resources {
+		domainResources = append(domainResources, kb.KnowledgeBaseResource{
+			ID:                 resource.ID,
+			KnowledgeBaseID:    resource.KnowledgeBaseID,
+			ResourceID:         resource.ResourceID,
+			ChunkID:            resource.ChunkID,
+			Title:              resource.Title,
+			ContextDescription: resource.ContextDescription,
+			ContentHash:        resource.ContentHash,
+			TranslatedChunkID:  resource.TranslatedChunkID,
+			Language:           resource.Language,
+			CreatedAt:          resource.CreatedAt,
+			UpdatedAt:          resource.UpdatedAt,
+		})
+	}
+	return domainResources
+}
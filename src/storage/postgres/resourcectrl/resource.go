@@ -7,14 +7,33 @@ import (
 
 	"github.com/bwmarrin/snowflake"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Resource struct {
-	ID        int64     `gorm:"primaryKey" json:"id"`
-	Filename  string    `gorm:"not null" json:"filename"`
-	MinioURL  string    `gorm:"not null;column:minio_url" json:"minio_url"` // bucket name + object name
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          int64     `gorm:"primaryKey" json:"id"`
+	Filename    string    `gorm:"not null" json:"filename"`
+	MinioURL    string    `gorm:"not null;column:minio_url" json:"minio_url"` // bucket name + object name
+	ContentHash string    `gorm:"column:content_hash" json:"content_hash"`    // SHA-256 of the original file
+	Version     int       `gorm:"not null;default:1" json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Full document reassembled from the chunks of the last conversion
+	TextMinioURL     string `gorm:"column:text_minio_url" json:"text_minio_url,omitempty"`
+	MarkdownMinioURL string `gorm:"column:markdown_minio_url" json:"markdown_minio_url,omitempty"`
+}
+
+// ResourceVersion keeps the original file of every uploaded version of a resource
+type ResourceVersion struct {
+	ID          int64     `gorm:"primaryKey" json:"id"`
+	ResourceID  int64     `gorm:"not null" json:"resource_id"`
+	Version     int       `gorm:"not null" json:"version"`
+	Filename    string    `gorm:"not null" json:"filename"`
+	MinioURL    string    `gorm:"not null;column:minio_url" json:"minio_url"`
+	ContentHash string    `gorm:"column:content_hash" json:"content_hash"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 type ResourceService struct {
@@ -64,17 +83,115 @@ func (s *ResourceService) List(ctx context.Context, limit int, offset int) ([]Re
 	return resources, nil
 }
 
-func (s *ResourceService) Create(ctx context.Context, filename, minioURL string) (*Resource, error) {
+// GetByContentHash returns the resource with the given content hash, or nil if none exists
+func (s *ResourceService) GetByContentHash(ctx context.Context, contentHash string) (*Resource, error) {
+	var resource Resource
+	result := s.db.WithContext(ctx).Where("content_hash = ?", contentHash).Order("created_at ASC").First(&resource)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get resource by content hash: %v", result.Error)
+	}
+	return &resource, nil
+}
+
+func (s *ResourceService) Create(ctx context.Context, filename, minioURL, contentHash string) (*Resource, error) {
 	resource := &Resource{
-		ID:       s.snowflake.Generate().Int64(),
-		Filename: filename,
-		MinioURL: minioURL,
+		ID:          s.snowflake.Generate().Int64(),
+		Filename:    filename,
+		MinioURL:    minioURL,
+		ContentHash: contentHash,
+		Version:     1,
 	}
 
-	result := s.db.WithContext(ctx).Create(resource)
-	if result.Error != nil {
-		return nil, fmt.Errorf("failed to create resource: %v", result.Error)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(resource).Error; err != nil {
+			return err
+		}
+		return tx.Create(s.newVersion(resource)).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %v", err)
 	}
 
 	return resource, nil
 }
+
+// AddVersion points the resource at a newly uploaded file and records it as the next version
+func (s *ResourceService) AddVersion(ctx context.Context, id int64, filename, minioURL, contentHash string) (*Resource, error) {
+	var resource Resource
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&resource, id).Error; err != nil {
+			return err
+		}
+
+		resource.Filename = filename
+		resource.MinioURL = minioURL
+		resource.ContentHash = contentHash
+		resource.Version++
+		if err := tx.Save(&resource).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(s.newVersion(&resource)).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add resource version: %v", err)
+	}
+
+	return &resource, nil
+}
+
+// ListVersions returns every version of a resource, newest first
+func (s *ResourceService) ListVersions(ctx context.Context, id int64) ([]ResourceVersion, error) {
+	var versions []ResourceVersion
+	result := s.db.WithContext(ctx).
+		Where("resource_id = ?", id).
+		Order("version DESC").
+		Find(&versions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list resource versions: %v", result.Error)
+	}
+	return versions, nil
+}
+
+// SetDocumentURLs records where the reassembled full text and Markdown of the resource are stored
+func (s *ResourceService) SetDocumentURLs(ctx context.Context, id int64, textURL, markdownURL string) error {
+	result := s.db.WithContext(ctx).
+		Model(&Resource{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"text_minio_url":     textURL,
+			"markdown_minio_url": markdownURL,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update resource document urls: %v", result.Error)
+	}
+	return nil
+}
+
+// Delete removes the resource together with its version history
+func (s *ResourceService) Delete(ctx context.Context, id int64) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("resource_id = ?", id).Delete(&ResourceVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Resource{}, id).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete resource: %v", err)
+	}
+	return nil
+}
+
+func (s *ResourceService) newVersion(resource *Resource) *ResourceVersion {
+	return &ResourceVersion{
+		ID:          s.snowflake.Generate().Int64(),
+		ResourceID:  resource.ID,
+		Version:     resource.Version,
+		Filename:    resource.Filename,
+		MinioURL:    resource.MinioURL,
+		ContentHash: resource.ContentHash,
+	}
+}
@@ -0,0 +1,127 @@
+package styleguidectrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/snowflake"
+	"gorm.io/gorm"
+
+	"raggo/src/core/translationflow"
+)
+
+// StyleGuide holds the translation style of a target locale.
+// An empty country makes it the default for every country of the target language.
+type StyleGuide struct {
+	ID             int64     `gorm:"primaryKey" json:"id"`
+	TargetLanguage string    `gorm:"not null" json:"target_language"`
+	Country        string    `gorm:"not null" json:"country"`
+	Formality      string    `gorm:"not null" json:"formality"`
+	Tone           string    `gorm:"not null" json:"tone"`
+	Instructions   string    `gorm:"not null" json:"instructions"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type StyleGuideService struct {
+	db        *gorm.DB
+	snowflake *snowflake.Node
+}
+
+func NewStyleGuideService(db *gorm.DB) (*StyleGuideService, error) {
+	// Initialize snowflake node
+	node, err := snowflake.NewNode(7) // Node number 7 for style guides
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snowflake node: %v", err)
+	}
+
+	return &StyleGuideService{
+		db:        db,
+		snowflake: node,
+	}, nil
+}
+
+// Save creates the style guide of the locale or replaces its rules if it already exists
+func (s *StyleGuideService) Save(ctx context.Context, targetLanguage, country, formality, tone, instructions string) (*StyleGuide, error) {
+	var styleGuide StyleGuide
+	result := s.db.WithContext(ctx).
+		Where("target_language = ? AND country = ?", targetLanguage, country).
+		First(&styleGuide)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get style guide: %v", result.Error)
+	}
+	if result.Error != nil {
+		styleGuide = StyleGuide{
+			ID:             s.snowflake.Generate().Int64(),
+			TargetLanguage: targetLanguage,
+			Country:        country,
+		}
+	}
+
+	styleGuide.Formality = formality
+	styleGuide.Tone = tone
+	styleGuide.Instructions = instructions
+	if err := s.db.WithContext(ctx).Save(&styleGuide).Error; err != nil {
+		return nil, fmt.Errorf("failed to save style guide: %v", err)
+	}
+
+	return &styleGuide, nil
+}
+
+func (s *StyleGuideService) List(ctx context.Context, limit, offset int) ([]StyleGuide, error) {
+	var styleGuides []StyleGuide
+	result := s.db.WithContext(ctx).
+		Order("target_language ASC, country ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&styleGuides)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list style guides: %v", result.Error)
+	}
+	return styleGuides, nil
+}
+
+func (s *StyleGuideService) GetByID(ctx context.Context, id int64) (*StyleGuide, error) {
+	var styleGuide StyleGuide
+	result := s.db.WithContext(ctx).First(&styleGuide, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get style guide: %v", result.Error)
+	}
+	return &styleGuide, nil
+}
+
+func (s *StyleGuideService) Delete(ctx context.Context, id int64) error {
+	if err := s.db.WithContext(ctx).Delete(&StyleGuide{}, id).Error; err != nil {
+		return fmt.Errorf("failed to delete style guide: %v", err)
+	}
+	return nil
+}
+
+// Load returns the style guide of the country, falling back to the default of the target language.
+// It returns nil if neither exists.
+func (s *StyleGuideService) Load(ctx context.Context, targetLanguage, country string) (*translationflow.StyleGuide, error) {
+	var styleGuides []StyleGuide
+	result := s.db.WithContext(ctx).
+		Where("target_language = ? AND country IN ?", targetLanguage, []string{country, ""}).
+		Order("country DESC").
+		Find(&styleGuides)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get style guide: %v", result.Error)
+	}
+	if len(styleGuides) == 0 {
+		return nil, nil
+	}
+
+	// A country specific style guide sorts before the language default
+	styleGuide := styleGuides[0]
+	return &translationflow.StyleGuide{
+		Formality:    styleGuide.Formality,
+		Tone:         styleGuide.Tone,
+		Instructions: styleGuide.Instructions,
+	}, nil
+}
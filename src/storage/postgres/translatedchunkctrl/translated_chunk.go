@@ -2,21 +2,69 @@ package translatedchunkctrl
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/bwmarrin/snowflake"
 	"gorm.io/gorm"
+
+	"raggo/src/core/translationflow"
+)
+
+// Review statuses of a translated chunk
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected" // translated again by the next run of the translation job
 )
 
 type TranslatedChunk struct {
-	ID                   int64     `gorm:"primaryKey" json:"id"`
-	TranslatedResourceID int64     `gorm:"not null" json:"translated_resource_id"`
-	OriginalChunkID      int64     `gorm:"not null" json:"original_chunk_id"`
-	ChunkID              string    `gorm:"not null" json:"chunk_id"`
-	MinioURL             string    `gorm:"not null;column:minio_url" json:"minio_url"`
-	CreatedAt            time.Time `json:"created_at"`
-	UpdatedAt            time.Time `json:"updated_at"`
+	ID                   int64  `gorm:"primaryKey" json:"id"`
+	TranslatedResourceID int64  `gorm:"not null" json:"translated_resource_id"`
+	OriginalChunkID      int64  `gorm:"not null" json:"original_chunk_id"`
+	ChunkID              string `gorm:"not null" json:"chunk_id"`
+	MinioURL             string `gorm:"not null;column:minio_url" json:"minio_url"`
+	// SourceLanguage is the language the chunk was translated from, part of its translation memory key
+	SourceLanguage string `gorm:"column:source_language;not null;default:''" json:"source_language,omitempty"`
+	// Quality estimation of the machine translation, nil if it was not estimated
+	QualityScore              *float64 `gorm:"column:quality_score" json:"quality_score,omitempty"`
+	QualityAccuracy           *int     `gorm:"column:quality_accuracy" json:"quality_accuracy,omitempty"`
+	QualityFluency            *int     `gorm:"column:quality_fluency" json:"quality_fluency,omitempty"`
+	QualityTerminology        *int     `gorm:"column:quality_terminology" json:"quality_terminology,omitempty"`
+	BackTranslationSimilarity *float64 `gorm:"column:back_translation_similarity" json:"back_translation_similarity,omitempty"`
+	QualityComment            string   `gorm:"column:quality_comment;not null;default:''" json:"quality_comment,omitempty"`
+	FlaggedForReview          bool     `gorm:"column:flagged_for_review;not null;default:false" json:"flagged_for_review"`
+	// Human review; MinioURL keeps the machine draft and RevisionMinioURL the latest human revision
+	ReviewStatus     string     `gorm:"column:review_status;not null;default:pending" json:"review_status"`
+	RevisionMinioURL string     `gorm:"column:revision_minio_url;not null;default:''" json:"revision_minio_url,omitempty"`
+	RevisedBy        string     `gorm:"column:revised_by;not null;default:''" json:"revised_by,omitempty"`
+	RevisedAt        *time.Time `gorm:"column:revised_at" json:"revised_at,omitempty"`
+	ReviewedBy       string     `gorm:"column:reviewed_by;not null;default:''" json:"reviewed_by,omitempty"`
+	ReviewedAt       *time.Time `gorm:"column:reviewed_at" json:"reviewed_at,omitempty"`
+	ReviewComment    string     `gorm:"column:review_comment;not null;default:''" json:"review_comment,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// FinalMinioURL returns the text that goes into the translated resource:
+// the human revision once it is approved, the machine draft otherwise
+func (c *TranslatedChunk) FinalMinioURL() string {
+	if c.ReviewStatus == ReviewStatusApproved && c.RevisionMinioURL != "" {
+		return c.RevisionMinioURL
+	}
+	return c.MinioURL
+}
+
+// IsRejected reports whether a reviewer rejected the translation
+func (c *TranslatedChunk) IsRejected() bool {
+	return c.ReviewStatus == ReviewStatusRejected
+}
+
+// ListFilter narrows the translated chunks returned by ListByTranslatedResourceID
+type ListFilter struct {
+	FlaggedOnly  bool   // only chunks flagged for human review
+	ReviewStatus string // only chunks with this review status if not empty
 }
 
 type TranslatedChunkService struct {
@@ -37,13 +85,14 @@ func NewTranslatedChunkService(db *gorm.DB) (*TranslatedChunkService, error) {
 	}, nil
 }
 
-func (s *TranslatedChunkService) Create(ctx context.Context, translatedResourceID, originalChunkID int64, chunkID, minioURL string) (*TranslatedChunk, error) {
+func (s *TranslatedChunkService) Create(ctx context.Context, translatedResourceID, originalChunkID int64, chunkID, minioURL, sourceLanguage string) (*TranslatedChunk, error) {
 	chunk := &TranslatedChunk{
 		ID:                   s.snowflake.Generate().Int64(),
 		TranslatedResourceID: translatedResourceID,
 		OriginalChunkID:      originalChunkID,
 		ChunkID:              chunkID,
 		MinioURL:             minioURL,
+		SourceLanguage:       sourceLanguage,
 	}
 
 	result := s.db.WithContext(ctx).Create(chunk)
@@ -63,6 +112,151 @@ func (s *TranslatedChunkService) GetByTranslatedResourceID(ctx context.Context,
 	return chunks, nil
 }
 
+// GetByID returns the translated chunk, or nil if it does not exist
+func (s *TranslatedChunkService) GetByID(ctx context.Context, id int64) (*TranslatedChunk, error) {
+	var chunk TranslatedChunk
+	result := s.db.WithContext(ctx).First(&chunk, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get translated chunk: %v", result.Error)
+	}
+	return &chunk, nil
+}
+
+// ListByTranslatedResourceID returns a page of the translated chunks matching the filter in the order of their source chunks
+func (s *TranslatedChunkService) ListByTranslatedResourceID(ctx context.Context, translatedResourceID int64, filter ListFilter, limit, offset int) ([]TranslatedChunk, int64, error) {
+	// A fresh query for the count and the page, since a gorm chain must not be reused
+	query := func() *gorm.DB {
+		q := s.db.WithContext(ctx).Model(&TranslatedChunk{}).Where("translated_chunks.translated_resource_id = ?", translatedResourceID)
+		if filter.FlaggedOnly {
+			q = q.Where("translated_chunks.flagged_for_review")
+		}
+		if filter.ReviewStatus != "" {
+			q = q.Where("translated_chunks.review_status = ?", filter.ReviewStatus)
+		}
+		return q
+	}
+
+	var total int64
+	if err := query().Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count translated chunks: %v", err)
+	}
+
+	var chunks []TranslatedChunk
+	result := query().
+		Select("translated_chunks.*").
+		Joins("JOIN chunks ON chunks.id = translated_chunks.original_chunk_id").
+		Order("chunks.chunk_order ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&chunks)
+	if result.Error != nil {
+		return nil, 0, fmt.Errorf("failed to list translated chunks: %v", result.Error)
+	}
+	return chunks, total, nil
+}
+
+// UpdateQuality stores the quality estimation of a translated chunk and whether it needs a human review
+func (s *TranslatedChunkService) UpdateQuality(ctx context.Context, id int64, estimate *translationflow.QualityEstimate, flagged bool) error {
+	result := s.db.WithContext(ctx).Model(&TranslatedChunk{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"quality_score":               estimate.Score,
+		"quality_accuracy":            estimate.Accuracy,
+		"quality_fluency":             estimate.Fluency,
+		"quality_terminology":         estimate.Terminology,
+		"back_translation_similarity": estimate.BackTranslationSimilarity,
+		"quality_comment":             estimate.Comment,
+		"flagged_for_review":          flagged,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update translated chunk quality: %v", result.Error)
+	}
+	return nil
+}
+
+// SetFlagged flags a translated chunk for human review or clears the flag
+func (s *TranslatedChunkService) SetFlagged(ctx context.Context, id int64, flagged bool) error {
+	result := s.db.WithContext(ctx).Model(&TranslatedChunk{}).Where("id = ?", id).Update("flagged_for_review", flagged)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update translated chunk flag: %v", result.Error)
+	}
+	return nil
+}
+
+// SaveRevision records a human revision of the translation, which waits for approval again
+func (s *TranslatedChunkService) SaveRevision(ctx context.Context, id int64, revisionMinioURL, author string) error {
+	result := s.db.WithContext(ctx).Model(&TranslatedChunk{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"revision_minio_url": revisionMinioURL,
+		"revised_by":         author,
+		"revised_at":         time.Now(),
+		"review_status":      ReviewStatusPending,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to save translated chunk revision: %v", result.Error)
+	}
+	return nil
+}
+
+// SetReview records the decision of a reviewer. A reviewed chunk is no longer flagged for review.
+func (s *TranslatedChunkService) SetReview(ctx context.Context, id int64, status, reviewer, comment string) error {
+	result := s.db.WithContext(ctx).Model(&TranslatedChunk{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"review_status":      status,
+		"reviewed_by":        reviewer,
+		"reviewed_at":        time.Now(),
+		"review_comment":     comment,
+		"flagged_for_review": false,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to save translated chunk review: %v", result.Error)
+	}
+	return nil
+}
+
+func (s *TranslatedChunkService) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).Delete(&TranslatedChunk{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete translated chunk: %v", result.Error)
+	}
+	return nil
+}
+
+func (s *TranslatedChunkService) GetByOriginalChunkIDs(ctx context.Context, originalChunkIDs []int64) ([]TranslatedChunk, error) {
+	if len(originalChunkIDs) == 0 {
+		return nil, nil
+	}
+
+	var chunks []TranslatedChunk
+	result := s.db.WithContext(ctx).Where("original_chunk_id IN ?", originalChunkIDs).Find(&chunks)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get translated chunks: %v", result.Error)
+	}
+	return chunks, nil
+}
+
+// CountByTranslatedResourceIDs returns the number of translated chunks of each translated resource
+func (s *TranslatedChunkService) CountByTranslatedResourceIDs(ctx context.Context, translatedResourceIDs []int64) (map[int64]int64, error) {
+	var rows []struct {
+		TranslatedResourceID int64
+		Count                int64
+	}
+	result := s.db.WithContext(ctx).
+		Model(&TranslatedChunk{}).
+		Select("translated_resource_id, COUNT(*) AS count").
+		Where("translated_resource_id IN ?", translatedResourceIDs).
+		Group("translated_resource_id").
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to count translated chunks: %v", result.Error)
+	}
+
+	counts := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		counts[row.TranslatedResourceID] = row.Count
+	}
+	return counts, nil
+}
+
 func (s *TranslatedChunkService) DeleteByTranslatedResourceID(ctx context.Context, translatedResourceID int64) error {
 	result := s.db.WithContext(ctx).Where("translated_resource_id = ?", translatedResourceID).Delete(&TranslatedChunk{})
 	if result.Error != nil {
@@ -2,23 +2,45 @@ package translatedresourcectrl
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/bwmarrin/snowflake"
 	"gorm.io/gorm"
+
+	"raggo/src/core/translationmemory"
 )
 
 type TranslatedResource struct {
-	ID                 int64     `gorm:"primaryKey" json:"id"`
-	OriginalResourceID int64     `gorm:"not null" json:"original_resource_id"`
-	Filename           string    `gorm:"not null" json:"filename"`
-	MinioURL           string    `gorm:"not null;column:minio_url" json:"minio_url"`
-	SourceLanguage     string    `gorm:"not null" json:"source_language"`
-	TargetLanguage     string    `gorm:"not null" json:"target_language"`
-	Country            string    `gorm:"not null" json:"country"`
-	CreatedAt          time.Time `json:"created_at"`
-	UpdatedAt          time.Time `json:"updated_at"`
+	ID                 int64  `gorm:"primaryKey" json:"id"`
+	OriginalResourceID int64  `gorm:"not null" json:"original_resource_id"`
+	Filename           string `gorm:"not null" json:"filename"`
+	MinioURL           string `gorm:"not null;column:minio_url" json:"minio_url"`
+	SourceLanguage     string `gorm:"not null" json:"source_language"`
+	TargetLanguage     string `gorm:"not null" json:"target_language"`
+	Country            string `gorm:"not null" json:"country"`
+	Model              string `gorm:"not null" json:"model"`
+	// Signatures of the pipeline, glossary and style guide of the last translation run, empty for none
+	PipelineSignature   string    `gorm:"column:pipeline_signature;not null;default:''" json:"pipeline_signature,omitempty"`
+	GlossarySignature   string    `gorm:"column:glossary_signature;not null;default:''" json:"glossary_signature,omitempty"`
+	StyleGuideSignature string    `gorm:"column:style_guide_signature;not null;default:''" json:"style_guide_signature,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// MemoryKey returns the translation memory key of the chunks of the translation that were translated from the
+// source language. The translation job stores its translations under this key and reviews update them under it.
+func (r *TranslatedResource) MemoryKey(sourceLanguage string) translationmemory.Key {
+	return translationmemory.Key{
+		SourceLanguage: sourceLanguage,
+		TargetLanguage: r.TargetLanguage,
+		Country:        r.Country,
+		Model:          r.Model,
+		Pipeline:       r.PipelineSignature,
+		Glossary:       r.GlossarySignature,
+		StyleGuide:     r.StyleGuideSignature,
+	}
 }
 
 type TranslatedResourceService struct {
@@ -39,7 +61,7 @@ func NewTranslatedResourceService(db *gorm.DB) (*TranslatedResourceService, erro
 	}, nil
 }
 
-func (s *TranslatedResourceService) Create(ctx context.Context, originalResourceID int64, filename, minioURL, sourceLang, targetLang, country string) (*TranslatedResource, error) {
+func (s *TranslatedResourceService) Create(ctx context.Context, originalResourceID int64, filename, minioURL, sourceLang, targetLang, country, model string) (*TranslatedResource, error) {
 	resource := &TranslatedResource{
 		ID:                 s.snowflake.Generate().Int64(),
 		OriginalResourceID: originalResourceID,
@@ -48,6 +70,7 @@ func (s *TranslatedResourceService) Create(ctx context.Context, originalResource
 		SourceLanguage:     sourceLang,
 		TargetLanguage:     targetLang,
 		Country:            country,
+		Model:              model,
 	}
 
 	result := s.db.WithContext(ctx).Create(resource)
@@ -60,13 +83,73 @@ func (s *TranslatedResourceService) Create(ctx context.Context, originalResource
 
 func (s *TranslatedResourceService) GetByOriginalID(ctx context.Context, originalID int64) ([]TranslatedResource, error) {
 	var resources []TranslatedResource
-	result := s.db.WithContext(ctx).Where("original_resource_id = ?", originalID).Find(&resources)
+	result := s.db.WithContext(ctx).
+		Where("original_resource_id = ?", originalID).
+		Order("target_language ASC, country ASC, model ASC").
+		Find(&resources)
 	if result.Error != nil {
 		return nil, fmt.Errorf("failed to get translated resources: %v", result.Error)
 	}
 	return resources, nil
 }
 
+// FindVariant returns the translation of the resource into the language and country by the model, or nil if there is none
+func (s *TranslatedResourceService) FindVariant(ctx context.Context, originalID int64, targetLang, country, model string) (*TranslatedResource, error) {
+	var resource TranslatedResource
+	result := s.db.WithContext(ctx).
+		Where("original_resource_id = ? AND target_language = ? AND country = ? AND model = ?", originalID, targetLang, country, model).
+		First(&resource)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get translated resource: %v", result.Error)
+	}
+	return &resource, nil
+}
+
+func (s *TranslatedResourceService) GetByID(ctx context.Context, id int64) (*TranslatedResource, error) {
+	var resource TranslatedResource
+	result := s.db.WithContext(ctx).First(&resource, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get translated resource: %v", result.Error)
+	}
+	return &resource, nil
+}
+
+// UpdateSourceLanguage records the source language of a translation whose source language was detected
+func (s *TranslatedResourceService) UpdateSourceLanguage(ctx context.Context, id int64, sourceLang string) error {
+	result := s.db.WithContext(ctx).Model(&TranslatedResource{}).Where("id = ?", id).Update("source_language", sourceLang)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update translated resource source language: %v", result.Error)
+	}
+	return nil
+}
+
+// UpdateSignatures records the signatures of the pipeline, glossary and style guide a translation run uses
+func (s *TranslatedResourceService) UpdateSignatures(ctx context.Context, id int64, pipeline, glossary, styleGuide string) error {
+	result := s.db.WithContext(ctx).Model(&TranslatedResource{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"pipeline_signature":    pipeline,
+		"glossary_signature":    glossary,
+		"style_guide_signature": styleGuide,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update translated resource signatures: %v", result.Error)
+	}
+	return nil
+}
+
+func (s *TranslatedResourceService) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).Delete(&TranslatedResource{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete translated resource: %v", result.Error)
+	}
+	return nil
+}
+
 func (s *TranslatedResourceService) DeleteByOriginalID(ctx context.Context, originalID int64) error {
 	result := s.db.WithContext(ctx).Where("original_resource_id = ?", originalID).Delete(&TranslatedResource{})
 	if result.Error != nil {
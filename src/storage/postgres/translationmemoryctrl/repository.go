@@ -0,0 +1,181 @@
+package translationmemoryctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	tm "raggo/src/core/translationmemory"
+)
+
+type TranslationMemoryEntry struct {
+	ID             int64  `gorm:"primaryKey"`
+	SourceHash     string `gorm:"not null"`
+	SourceLanguage string `gorm:"not null"`
+	TargetLanguage string `gorm:"not null"`
+	Country        string `gorm:"not null"`
+	Model          string `gorm:"not null"`
+	Pipeline       string `gorm:"not null"`
+	Glossary       string `gorm:"not null"`
+	StyleGuide     string `gorm:"not null"`
+	SourceText     string `gorm:"not null"`
+	TargetText     string `gorm:"not null"`
+	SourceLength   int    `gorm:"not null"` // length of the source text in characters, used to narrow fuzzy match candidates
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (TranslationMemoryEntry) TableName() string {
+	return "translation_memory"
+}
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Get(ctx context.Context, sourceHash string, key tm.Key) (*tm.Entry, error) {
+	var entry TranslationMemoryEntry
+	result := r.db.WithContext(ctx).
+		Scopes(withKey(key)).
+		Where("source_hash = ?", sourceHash).
+		First(&entry)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get translation memory entry: %v", result.Error)
+	}
+
+	domainEntry := toDomain(entry)
+	return &domainEntry, nil
+}
+
+func (r *Repository) Save(ctx context.Context, entry *tm.Entry) error {
+	var existing TranslationMemoryEntry
+	result := r.db.WithContext(ctx).
+		Scopes(withKey(entry.Key())).
+		Where("source_hash = ?", entry.SourceHash).
+		First(&existing)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to get translation memory entry: %v", result.Error)
+	}
+	if result.Error != nil {
+		existing = TranslationMemoryEntry{
+			ID:             entry.ID,
+			SourceHash:     entry.SourceHash,
+			SourceLanguage: entry.SourceLanguage,
+			TargetLanguage: entry.TargetLanguage,
+			Country:        entry.Country,
+			Model:          entry.Model,
+			Pipeline:       entry.Pipeline,
+			Glossary:       entry.Glossary,
+			StyleGuide:     entry.StyleGuide,
+			SourceText:     entry.SourceText,
+			SourceLength:   len([]rune(entry.SourceText)),
+		}
+	}
+
+	existing.TargetText = entry.TargetText
+	if err := r.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to save translation memory entry: %v", err)
+	}
+
+	entry.ID = existing.ID
+	return nil
+}
+
+func (r *Repository) Delete(ctx context.Context, id int64) error {
+	if err := r.db.WithContext(ctx).Delete(&TranslationMemoryEntry{}, id).Error; err != nil {
+		return fmt.Errorf("failed to delete translation memory entry: %v", err)
+	}
+	return nil
+}
+
+func (r *Repository) ListCandidates(ctx context.Context, sourceLanguage, targetLanguage, country string, minLength, maxLength, limit int) ([]tm.Entry, error) {
+	var entries []TranslationMemoryEntry
+	result := r.db.WithContext(ctx).
+		Where("source_language = ? AND target_language = ? AND country = ?", sourceLanguage, targetLanguage, country).
+		Where("source_length BETWEEN ? AND ?", minLength, maxLength).
+		Order("updated_at DESC").
+		Limit(limit).
+		Find(&entries)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list translation memory candidates: %v", result.Error)
+	}
+
+	return toDomainList(entries), nil
+}
+
+func (r *Repository) List(ctx context.Context, key tm.Key) ([]tm.Entry, error) {
+	query := r.db.WithContext(ctx)
+	if key.SourceLanguage != "" {
+		query = query.Where("source_language = ?", key.SourceLanguage)
+	}
+	if key.TargetLanguage != "" {
+		query = query.Where("target_language = ?", key.TargetLanguage)
+	}
+	if key.Country != "" {
+		query = query.Where("country = ?", key.Country)
+	}
+	if key.Model != "" {
+		query = query.Where("model = ?", key.Model)
+	}
+	if key.Pipeline != "" {
+		query = query.Where("pipeline = ?", key.Pipeline)
+	}
+	if key.Glossary != "" {
+		query = query.Where("glossary = ?", key.Glossary)
+	}
+	if key.StyleGuide != "" {
+		query = query.Where("style_guide = ?", key.StyleGuide)
+	}
+
+	var entries []TranslationMemoryEntry
+	if err := query.Order("created_at ASC").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list translation memory entries: %v", err)
+	}
+
+	return toDomainList(entries), nil
+}
+
+// withKey narrows a query to the entries stored under exactly the key
+func withKey(key tm.Key) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("source_language = ? AND target_language = ? AND country = ? AND model = ? AND pipeline = ? AND glossary = ? AND style_guide = ?",
+			key.SourceLanguage, key.TargetLanguage, key.Country, key.Model, key.Pipeline, key.Glossary, key.StyleGuide)
+	}
+}
+
+func toDomainList(entries []TranslationMemoryEntry) []tm.Entry {
+	// Convert to domain model
+	var domainEntries []tm.Entry
+	for _, entry := range entries {
+		domainEntries = append(domainEntries, toDomain(entry))
+	}
+	return domainEntries
+}
+
+func toDomain(entry TranslationMemoryEntry) tm.Entry {
+	return tm.Entry{
+		ID:             entry.ID,
+		SourceHash:     entry.SourceHash,
+		SourceLanguage: entry.SourceLanguage,
+		TargetLanguage: entry.TargetLanguage,
+		Country:        entry.Country,
+		Model:          entry.Model,
+		Pipeline:       entry.Pipeline,
+		Glossary:       entry.Glossary,
+		StyleGuide:     entry.StyleGuide,
+		SourceText:     entry.SourceText,
+		TargetText:     entry.TargetText,
+		CreatedAt:      entry.CreatedAt,
+		UpdatedAt:      entry.UpdatedAt,
+	}
+}
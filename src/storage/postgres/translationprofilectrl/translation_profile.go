@@ -0,0 +1,133 @@
+package translationprofilectrl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/snowflake"
+	"gorm.io/gorm"
+
+	"raggo/src/core/translationflow"
+)
+
+// TranslationProfile is a named translation pipeline that translation requests can refer to
+type TranslationProfile struct {
+	ID          int64                          `gorm:"primaryKey" json:"id"`
+	Name        string                         `gorm:"not null" json:"name"`
+	Description string                         `json:"description"`
+	PipelineRaw string                         `gorm:"column:pipeline;type:jsonb;not null" json:"-"`
+	Pipeline    translationflow.PipelineConfig `gorm:"-" json:"pipeline"`
+	CreatedAt   time.Time                      `json:"created_at"`
+	UpdatedAt   time.Time                      `json:"updated_at"`
+}
+
+type TranslationProfileService struct {
+	db        *gorm.DB
+	snowflake *snowflake.Node
+}
+
+func NewTranslationProfileService(db *gorm.DB) (*TranslationProfileService, error) {
+	// Initialize snowflake node
+	node, err := snowflake.NewNode(8) // Node number 8 for translation profiles
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snowflake node: %v", err)
+	}
+
+	return &TranslationProfileService{
+		db:        db,
+		snowflake: node,
+	}, nil
+}
+
+// Save creates the profile or replaces the pipeline of the profile with the same name.
+// The pipeline is validated first so that a stored profile can always be used.
+func (s *TranslationProfileService) Save(ctx context.Context, name, description string, pipeline translationflow.PipelineConfig) (*TranslationProfile, error) {
+	if err := pipeline.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid pipeline: %w", err)
+	}
+	pipelineRaw, err := json.Marshal(pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pipeline: %v", err)
+	}
+
+	var profile TranslationProfile
+	result := s.db.WithContext(ctx).Where("name = ?", name).First(&profile)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get translation profile: %v", result.Error)
+	}
+	if result.Error != nil {
+		profile = TranslationProfile{
+			ID:   s.snowflake.Generate().Int64(),
+			Name: name,
+		}
+	}
+
+	profile.Description = description
+	profile.PipelineRaw = string(pipelineRaw)
+	profile.Pipeline = pipeline
+	if err := s.db.WithContext(ctx).Save(&profile).Error; err != nil {
+		return nil, fmt.Errorf("failed to save translation profile: %v", err)
+	}
+
+	return &profile, nil
+}
+
+func (s *TranslationProfileService) List(ctx context.Context, limit, offset int) ([]TranslationProfile, error) {
+	var profiles []TranslationProfile
+	result := s.db.WithContext(ctx).
+		Order("name ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&profiles)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list translation profiles: %v", result.Error)
+	}
+
+	for i := range profiles {
+		if err := profiles[i].decodePipeline(); err != nil {
+			return nil, err
+		}
+	}
+	return profiles, nil
+}
+
+func (s *TranslationProfileService) GetByID(ctx context.Context, id int64) (*TranslationProfile, error) {
+	return s.get(ctx, "id = ?", id)
+}
+
+func (s *TranslationProfileService) GetByName(ctx context.Context, name string) (*TranslationProfile, error) {
+	return s.get(ctx, "name = ?", name)
+}
+
+func (s *TranslationProfileService) Delete(ctx context.Context, id int64) error {
+	if err := s.db.WithContext(ctx).Delete(&TranslationProfile{}, id).Error; err != nil {
+		return fmt.Errorf("failed to delete translation profile: %v", err)
+	}
+	return nil
+}
+
+func (s *TranslationProfileService) get(ctx context.Context, query string, arg any) (*TranslationProfile, error) {
+	var profile TranslationProfile
+	result := s.db.WithContext(ctx).Where(query, arg).First(&profile)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get translation profile: %v", result.Error)
+	}
+
+	if err := profile.decodePipeline(); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (p *TranslationProfile) decodePipeline() error {
+	if err := json.Unmarshal([]byte(p.PipelineRaw), &p.Pipeline); err != nil {
+		return fmt.Errorf("failed to unmarshal pipeline of translation profile %s: %v", p.Name, err)
+	}
+	return nil
+}
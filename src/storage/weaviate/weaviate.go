@@ -3,8 +3,10 @@ package weaviate
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
 	"github.com/weaviate/weaviate/entities/models"
 
@@ -48,6 +50,39 @@ func (w *SDK) CreateSchema(ctx context.Context, className string, properties []*
 	return nil
 }
 
+// AddMissingProperties adds the properties a class does not have yet, so that classes created before
+// a property was introduced can store and return it. Existing properties are left as they are.
+func (w *SDK) AddMissingProperties(ctx context.Context, className string, properties []*models.Property) error {
+	class, err := w.client.Schema().ClassGetter().WithClassName(className).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get Weaviate class: %v", err)
+	}
+
+	for _, property := range properties {
+		exists := false
+		for _, existing := range class.Properties {
+			if strings.EqualFold(existing.Name, property.Name) {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			continue
+		}
+
+		err := w.client.Schema().PropertyCreator().
+			WithClassName(className).
+			WithProperty(property).
+			Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to add property %s to Weaviate class: %v", property.Name, err)
+		}
+		log.Info("Added property to Weaviate class", "className", className, "property", property.Name)
+	}
+
+	return nil
+}
+
 // classExists checks if a class exists in the schema
 func (w *SDK) classExists(ctx context.Context, className string) (bool, error) {
 	schema, err := w.client.Schema().Getter().Do(ctx)
@@ -132,6 +167,9 @@ type QueryConfig struct {
 
 const DefaultQueryLimit = 20
 
+// findPageSize is the number of objects FindByNumberProperty fetches per request
+const findPageSize = 100
+
 // QueryResult represents a single result from vector similarity search
 type QueryResult struct {
 	ID         string
@@ -175,6 +213,9 @@ func (w *SDK) QueryVectors(ctx context.Context, className string, vector []float
 	if err != nil {
 		return nil, fmt.Errorf("failed to query vectors: %v", err)
 	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("failed to query vectors: %s", result.Errors[0].Message)
+	}
 
 	// Parse results
 	var queryResults []QueryResult
@@ -218,3 +259,102 @@ func (w *SDK) DeleteVector(ctx context.Context, className string, id string) err
 
 	return nil
 }
+
+// DeleteVectorsByNumberProperty deletes every object of a class whose numeric property matches one of the values
+func (w *SDK) DeleteVectorsByNumberProperty(ctx context.Context, className string, property string, values []int64) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	numbers := make([]float64, len(values))
+	for i, v := range values {
+		numbers[i] = float64(v)
+	}
+
+	where := filters.Where().
+		WithPath([]string{property}).
+		WithOperator(filters.ContainsAny).
+		WithValueNumber(numbers...)
+
+	_, err := w.client.Batch().ObjectsBatchDeleter().
+		WithClassName(className).
+		WithWhere(where).
+		Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete vectors by %s: %v", property, err)
+	}
+
+	return nil
+}
+
+// FindByNumberProperty returns every object of a class whose numeric property equals the value, with the given fields.
+// The objects are fetched page by page, so none is left out by the default limit of Weaviate.
+func (w *SDK) FindByNumberProperty(ctx context.Context, className string, property string, value int64, fields []string) ([]QueryResult, error) {
+	graphqlFields := make([]graphql.Field, len(fields))
+	for i, field := range fields {
+		graphqlFields[i] = graphql.Field{Name: field}
+	}
+	graphqlFields = append(graphqlFields, graphql.Field{Name: "_additional { id }"})
+
+	where := filters.Where().
+		WithPath([]string{property}).
+		WithOperator(filters.Equal).
+		WithValueNumber(float64(value))
+
+	var objects []QueryResult
+	for offset := 0; ; offset += findPageSize {
+		result, err := w.client.GraphQL().Get().
+			WithClassName(className).
+			WithFields(graphqlFields...).
+			WithWhere(where).
+			WithLimit(findPageSize).
+			WithOffset(offset).
+			Do(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find objects by %s: %v", property, err)
+		}
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("failed to find objects by %s: %s", property, result.Errors[0].Message)
+		}
+
+		var items []interface{}
+		if data, ok := result.Data["Get"].(map[string]interface{}); ok {
+			items, _ = data[className].([]interface{})
+		}
+		for _, item := range items {
+			objMap, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			additional, _ := objMap["_additional"].(map[string]interface{})
+			id, _ := additional["id"].(string)
+
+			properties := make(map[string]interface{})
+			for k, v := range objMap {
+				if k != "_additional" {
+					properties[k] = v
+				}
+			}
+			objects = append(objects, QueryResult{ID: id, Properties: properties})
+		}
+
+		if len(items) < findPageSize {
+			return objects, nil
+		}
+	}
+}
+
+// MergeProperties updates the given properties of an object, keeping its vector and other properties
+func (w *SDK) MergeProperties(ctx context.Context, className string, id string, properties map[string]interface{}) error {
+	err := w.client.Data().Updater().
+		WithMerge().
+		WithClassName(className).
+		WithID(id).
+		WithProperties(properties).
+		Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update object properties: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package weaviate_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	weaviateclient "github.com/weaviate/weaviate-go-client/v4/weaviate"
+
+	"raggo/src/storage/weaviate"
+)
+
+var (
+	limitArg  = regexp.MustCompile(`limit:\s*(\d+)`)
+	offsetArg = regexp.MustCompile(`offset:\s*(\d+)`)
+)
+
+// graphQLPages answers Get queries of the class with a page of its objects like Weaviate,
+// returning at most defaultLimit objects when the query sets no limit
+func graphQLPages(className string, total, defaultLimit int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/graphql" {
+			http.Error(w, "unexpected request "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		var body struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		limit, offset := defaultLimit, 0
+		if match := limitArg.FindStringSubmatch(body.Query); match != nil {
+			limit, _ = strconv.Atoi(match[1])
+		}
+		if match := offsetArg.FindStringSubmatch(body.Query); match != nil {
+			offset, _ = strconv.Atoi(match[1])
+		}
+
+		objects := []interface{}{}
+		for i := offset; i < total && i < offset+limit; i++ {
+			objects = append(objects, map[string]interface{}{
+				"chunkId":     7,
+				"_additional": map[string]interface{}{"id": fmt.Sprintf("object-%d", i)},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"Get": map[string]interface{}{className: objects}},
+		})
+	}
+}
+
+func TestFindByNumberProperty(t *testing.T) {
+	tests := []struct {
+		name  string
+		total int
+	}{
+		{name: "no objects", total: 0},
+		{name: "fewer objects than a page", total: 3},
+		{name: "exactly one page", total: 100},
+		{name: "objects beyond the default limit", total: 250},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(graphQLPages("Chunks", tt.total, 25))
+			defer server.Close()
+			client, err := weaviateclient.NewClient(weaviateclient.Config{Host: strings.TrimPrefix(server.URL, "http://"), Scheme: "http"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			objects, err := weaviate.NewSDK(client).FindByNumberProperty(context.Background(), "Chunks", "chunkId", 7, []string{"chunkId"})
+			if err != nil {
+				t.Fatalf("FindByNumberProperty() error = %v", err)
+			}
+			if len(objects) != tt.total {
+				t.Fatalf("FindByNumberProperty() returned %d objects, want %d", len(objects), tt.total)
+			}
+			seen := make(map[string]bool)
+			for _, object := range objects {
+				if seen[object.ID] {
+					t.Errorf("object %s returned twice", object.ID)
+				}
+				seen[object.ID] = true
+			}
+		})
+	}
+}